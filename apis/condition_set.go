@@ -91,6 +91,18 @@ type ConditionManager interface {
 	InitializeConditions()
 }
 
+// ConditionUpdater is implemented by the ConditionManagers returned by
+// ConditionSet.Manage, which may update a Condition without recording a
+// transition, e.g.
+//
+//	condSet.Manage(status).(apis.ConditionUpdater).UpdateCondition(cond)
+type ConditionUpdater interface {
+	// UpdateCondition is like SetCondition, except that the LastTransitionTime
+	// of an existing Condition is only bumped when its Status changes. Updates
+	// to only its Reason, Message or Severity leave it in place.
+	UpdateCondition(new Condition)
+}
+
 // NewLivingConditionSet returns a ConditionSet to hold the conditions for the
 // living resource. ConditionReady is used as the happy condition.
 // The set of condition types provided are those of the terminal subconditions.
@@ -132,8 +144,9 @@ func contains(ct []ConditionType, t ConditionType) bool {
 	return false
 }
 
-// Check that conditionsImpl implements ConditionManager.
+// Check that conditionsImpl implements ConditionManager and ConditionUpdater.
 var _ ConditionManager = (*conditionsImpl)(nil)
+var _ ConditionUpdater = (*conditionsImpl)(nil)
 
 // conditionsImpl implements the helper methods for evaluating Conditions.
 // +k8s:deepcopy-gen=false
@@ -148,7 +161,8 @@ func (r ConditionSet) GetTopLevelConditionType() ConditionType {
 }
 
 // Manage creates a ConditionManager from an accessor object using the original
-// ConditionSet as a reference. Status must be a pointer to a struct.
+// ConditionSet as a reference. Status must be a pointer to a struct. The
+// ConditionManager also implements ConditionUpdater.
 func (r ConditionSet) Manage(status ConditionsAccessor) ConditionManager {
 	return conditionsImpl{
 		accessor:     status,
@@ -185,11 +199,27 @@ func (r conditionsImpl) GetCondition(t ConditionType) *Condition {
 // SetCondition sets or updates the Condition on Conditions for Condition.Type.
 // If there is an update, Conditions are stored back sorted.
 func (r conditionsImpl) SetCondition(cond Condition) {
+	r.setCondition(cond, false /* transitions only */)
+}
+
+// UpdateCondition is like SetCondition, except that the LastTransitionTime
+// of an existing Condition is only bumped when its Status changes.
+func (r conditionsImpl) UpdateCondition(cond Condition) {
+	r.setCondition(cond, true /* transitions only */)
+}
+
+// setCondition sets or updates the Condition, bumping its LastTransitionTime
+// on any change, or only on a change of its Status if transitionsOnly.
+func (r conditionsImpl) setCondition(cond Condition, transitionsOnly bool) {
 	if r.accessor == nil {
 		return
 	}
 	t := cond.Type
-	var conditions Conditions
+	var (
+		conditions Conditions
+		existing   bool
+		status     corev1.ConditionStatus
+	)
 	for _, c := range r.accessor.GetConditions() {
 		if c.Type != t {
 			conditions = append(conditions, c)
@@ -199,9 +229,12 @@ func (r conditionsImpl) SetCondition(cond Condition) {
 			if reflect.DeepEqual(cond, c) {
 				return
 			}
+			existing, status = true, c.Status
 		}
 	}
-	cond.LastTransitionTime = VolatileTime{Inner: metav1.NewTime(time.Now())}
+	if !existing || !transitionsOnly || status != cond.Status {
+		cond.LastTransitionTime = VolatileTime{Inner: metav1.NewTime(time.Now())}
+	}
 	conditions = append(conditions, cond)
 	// Sorted for convenience of the consumer, i.e. kubectl.
 	sort.Slice(conditions, func(i, j int) bool { return conditions[i].Type < conditions[j].Type })
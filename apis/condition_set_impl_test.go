@@ -342,6 +342,98 @@ func TestUpdateLastTransitionTime(t *testing.T) {
 	}
 }
 
+func TestUpdateCondition(t *testing.T) {
+	condSet := NewLivingConditionSet()
+	then := VolatileTime{Inner: metav1.NewTime(time.Unix(1337, 0))}
+
+	cases := []struct {
+		name       string
+		conditions Conditions
+		condition  Condition
+		update     bool
+		bumped     bool
+	}{{
+		name: "no-op",
+		conditions: Conditions{{
+			Type:               ConditionReady,
+			Status:             corev1.ConditionFalse,
+			Reason:             "Reason",
+			LastTransitionTime: then,
+		}},
+		condition: Condition{
+			Type:   ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: "Reason",
+		},
+	}, {
+		name: "reason and message change",
+		conditions: Conditions{{
+			Type:               ConditionReady,
+			Status:             corev1.ConditionFalse,
+			Reason:             "Reason",
+			LastTransitionTime: then,
+		}},
+		condition: Condition{
+			Type:    ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "OtherReason",
+			Message: "Something else is wrong.",
+		},
+		update: true,
+	}, {
+		name: "status transition",
+		conditions: Conditions{{
+			Type:               ConditionReady,
+			Status:             corev1.ConditionFalse,
+			Reason:             "Reason",
+			LastTransitionTime: then,
+		}},
+		condition: Condition{
+			Type:   ConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+		update: true,
+		bumped: true,
+	}, {
+		name: "new condition",
+		condition: Condition{
+			Type:   ConditionReady,
+			Status: corev1.ConditionUnknown,
+		},
+		update: true,
+		bumped: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := &countingStatus{TestStatus: TestStatus{c: tc.conditions}}
+			condSet.Manage(status).(ConditionUpdater).UpdateCondition(tc.condition)
+
+			if got, want := status.sets > 0, tc.update; got != want {
+				t.Errorf("Updated the conditions = %v, wanted %v", got, want)
+			}
+			got := condSet.Manage(status).GetCondition(tc.condition.Type)
+			if diff := cmp.Diff(&tc.condition, got, ignoreFields); diff != "" {
+				t.Error("Condition (-want, +got) =", diff)
+			}
+			if bumped := got.LastTransitionTime != then; bumped != tc.bumped {
+				t.Errorf("Bumped LastTransitionTime = %v, wanted %v", bumped, tc.bumped)
+			}
+		})
+	}
+}
+
+// countingStatus counts the updates of its conditions.
+type countingStatus struct {
+	TestStatus
+	sets int
+}
+
+func (s *countingStatus) SetConditions(conditions Conditions) {
+	s.sets++
+	s.TestStatus.SetConditions(conditions)
+}
+
 func TestResourceConditions(t *testing.T) {
 	condSet := NewLivingConditionSet()
 
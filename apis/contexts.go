@@ -19,6 +19,7 @@ package apis
 import (
 	"context"
 	"net/http"
+	"sync"
 
 	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -253,3 +254,74 @@ func GetHTTPRequest(ctx context.Context) *http.Request {
 	}
 	return v.(*http.Request)
 }
+
+// This is attached to contexts passed to webhook interfaces when the
+// receiver is being deleted with explicit options.
+type deleteOptionsKey struct{}
+
+// WithDeleteOptions associates the options of the Delete operation the
+// webhook is called within with the context.
+func WithDeleteOptions(ctx context.Context, opts *metav1.DeleteOptions) context.Context {
+	return context.WithValue(ctx, deleteOptionsKey{}, opts)
+}
+
+// GetDeleteOptions fetches the options of the Delete operation the webhook
+// is called within, e.g. to inspect its propagation policy, or nil if there
+// are none.
+func GetDeleteOptions(ctx context.Context) *metav1.DeleteOptions {
+	v := ctx.Value(deleteOptionsKey{})
+	if v == nil {
+		return nil
+	}
+	return v.(*metav1.DeleteOptions)
+}
+
+// This is attached to contexts passed to webhook interfaces so that they
+// can surface audit annotations on the admission response.
+type auditAnnotationsKey struct{}
+
+type auditAnnotations struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+// WithAuditAnnotations is used to note that audit annotations set via
+// SetAuditAnnotation should be collected for the current request.
+func WithAuditAnnotations(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditAnnotationsKey{}, &auditAnnotations{})
+}
+
+// SetAuditAnnotation records an audit annotation to be returned with the
+// admission response. It is a no-op when the context was not set up with
+// WithAuditAnnotations.
+func SetAuditAnnotation(ctx context.Context, key, value string) {
+	aa, ok := ctx.Value(auditAnnotationsKey{}).(*auditAnnotations)
+	if !ok {
+		return
+	}
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+	if aa.m == nil {
+		aa.m = make(map[string]string, 1)
+	}
+	aa.m[key] = value
+}
+
+// GetAuditAnnotations returns a copy of the audit annotations recorded
+// on the context via SetAuditAnnotation.
+func GetAuditAnnotations(ctx context.Context) map[string]string {
+	aa, ok := ctx.Value(auditAnnotationsKey{}).(*auditAnnotations)
+	if !ok {
+		return nil
+	}
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+	if len(aa.m) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(aa.m))
+	for k, v := range aa.m {
+		m[k] = v
+	}
+	return m
+}
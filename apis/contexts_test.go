@@ -241,3 +241,48 @@ func TestGetHTTPRequest(t *testing.T) {
 		t.Errorf("GetHTTPRequest() = %v, wanted %v", got, want)
 	}
 }
+
+func TestGetDeleteOptions(t *testing.T) {
+	ctx := context.Background()
+
+	if got := GetDeleteOptions(ctx); got != nil {
+		t.Errorf("GetDeleteOptions() = %v, wanted %v", got, nil)
+	}
+
+	policy := metav1.DeletePropagationForeground
+	opts := &metav1.DeleteOptions{PropagationPolicy: &policy}
+	ctx = WithDeleteOptions(ctx, opts)
+
+	if want, got := opts, GetDeleteOptions(ctx); got != want {
+		t.Errorf("GetDeleteOptions() = %v, wanted %v", got, want)
+	}
+}
+
+func TestAuditAnnotations(t *testing.T) {
+	ctx := context.Background()
+
+	// Setting annotations without the context being set up is a no-op.
+	SetAuditAnnotation(ctx, "foo", "bar")
+	if got := GetAuditAnnotations(ctx); got != nil {
+		t.Errorf("GetAuditAnnotations() = %v, wanted nil", got)
+	}
+
+	ctx = WithAuditAnnotations(ctx)
+	if got := GetAuditAnnotations(ctx); got != nil {
+		t.Errorf("GetAuditAnnotations() = %v, wanted nil", got)
+	}
+
+	SetAuditAnnotation(ctx, "foo", "bar")
+	SetAuditAnnotation(ctx, "baz", "blah")
+	got := GetAuditAnnotations(ctx)
+	want := map[string]string{"foo": "bar", "baz": "blah"}
+	if !cmp.Equal(got, want) {
+		t.Errorf("GetAuditAnnotations() = %v, wanted %v", got, want)
+	}
+
+	// Mutating the result must not affect the recorded annotations.
+	got["foo"] = "mutated"
+	if got := GetAuditAnnotations(ctx); !cmp.Equal(got, want) {
+		t.Errorf("GetAuditAnnotations() = %v, wanted %v", got, want)
+	}
+}
@@ -180,6 +180,29 @@ func (fe *FieldError) Error() string {
 	return strings.Join(errs, "\n")
 }
 
+// TopLevelFields returns the sorted, distinct top-level fields of the paths
+// of the errors, without their index or key, e.g. "spec" for the path
+// "spec.containers[0].image". Errors on the current field contribute
+// CurrentField.
+func (fe *FieldError) TopLevelFields() []string {
+	seen := make(map[string]struct{})
+	for _, e := range fe.normalized() {
+		for _, path := range e.Paths {
+			field := strings.SplitN(path, ".", 2)[0]
+			if i := strings.Index(field, "["); i >= 0 {
+				field = field[:i]
+			}
+			seen[field] = struct{}{}
+		}
+	}
+	fields := make([]string, 0, len(seen))
+	for field := range seen {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
 // Helpers ---
 
 func asIndex(index int) string {
@@ -842,3 +842,41 @@ func makeFieldKey(fk string) (string, string) {
 	all := strings.Split(fk, ",")
 	return all[0], all[1]
 }
+
+func TestTopLevelFields(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *FieldError
+		want []string
+	}{{
+		name: "nil",
+		want: []string{},
+	}, {
+		name: "nested with index",
+		err:  ErrMissingField("image").ViaFieldIndex("containers", 0).ViaField("spec"),
+		want: []string{"spec"},
+	}, {
+		name: "key",
+		err:  ErrInvalidKeyName("foo", "annotations").ViaField("metadata"),
+		want: []string{"metadata"},
+	}, {
+		name: "current field",
+		err:  ErrGeneric("bad", CurrentField),
+		want: []string{CurrentField},
+	}, {
+		name: "several",
+		err: ErrMissingField("spec.foo").Also(
+			ErrInvalidValue("bar", "metadata.name"),
+			ErrDisallowedFields("spec.bar", "status"),
+		),
+		want: []string{"metadata", "spec", "status"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.err.TopLevelFields(); !cmp.Equal(got, test.want) {
+				t.Errorf("TopLevelFields() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
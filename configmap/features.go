@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configmap
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Flag is the state of a feature flag.
+type Flag string
+
+const (
+	// Enabled turns the feature on.
+	Enabled Flag = "Enabled"
+	// Disabled turns the feature off.
+	Disabled Flag = "Disabled"
+	// Allowed lets the feature be turned on, e.g. per resource through an
+	// annotation, while it is off otherwise.
+	Allowed Flag = "Allowed"
+)
+
+// AsFlag parses the value at key as a Flag into the target, if it exists.
+// The value is case insensitive.
+func AsFlag(key string, target *Flag) ParseFunc {
+	return func(data map[string]string) error {
+		if raw, ok := data[key]; ok {
+			for _, flag := range []Flag{Enabled, Disabled, Allowed} {
+				if strings.EqualFold(raw, string(flag)) {
+					*target = flag
+					return nil
+				}
+			}
+			return fmt.Errorf("failed to parse %q: %q is not one of %s, %s or %s", key, raw, Enabled, Disabled, Allowed)
+		}
+		return nil
+	}
+}
+
+// FeatureFlags holds the feature flags parsed from a ConfigMap, and keeps
+// them up to date as the ConfigMap changes once it watches it.
+//
+// Only the flags with a default are parsed, the other keys of the ConfigMap
+// are ignored. Flags absent from the ConfigMap have their default value.
+type FeatureFlags struct {
+	name     string
+	logger   Logger
+	defaults map[string]Flag
+	onChange []func(name string, flag Flag)
+
+	mu    sync.RWMutex
+	flags map[string]Flag
+}
+
+// NewFeatureFlags creates a FeatureFlags parsing the flags of defaults from
+// the ConfigMap with the given name. The Logger must not be nil.
+//
+// onChange is a variadic list of callbacks to run, in the argument order,
+// for every flag whose value changes when the ConfigMap changes.
+func NewFeatureFlags(name string, logger Logger, defaults map[string]Flag, onChange ...func(name string, flag Flag)) *FeatureFlags {
+	flags := make(map[string]Flag, len(defaults))
+	for name, flag := range defaults {
+		flags[name] = flag
+	}
+	return &FeatureFlags{
+		name:     name,
+		logger:   logger,
+		defaults: defaults,
+		onChange: onChange,
+		flags:    flags,
+	}
+}
+
+// WatchConfigs uses the provided configmap.Watcher to keep the flags up to
+// date with the ConfigMap.
+func (f *FeatureFlags) WatchConfigs(w Watcher) {
+	w.Watch(f.name, f.OnConfigChanged)
+}
+
+// OnConfigChanged parses the flags from the ConfigMap. If any fails to parse,
+// the error is logged and the flags are left as they were.
+func (f *FeatureFlags) OnConfigChanged(cm *corev1.ConfigMap) {
+	flags := make(map[string]Flag, len(f.defaults))
+	for name, flag := range f.defaults {
+		if err := AsFlag(name, &flag)(cm.Data); err != nil {
+			f.logger.Errorf("Error parsing the feature flags of config %q: %v", f.name, err)
+			return
+		}
+		flags[name] = flag
+	}
+
+	f.mu.Lock()
+	previous := f.flags
+	f.flags = flags
+	f.mu.Unlock()
+
+	for name, flag := range flags {
+		if flag == previous[name] {
+			continue
+		}
+		f.logger.Infof("Feature flag %q of config %q changed from %s to %s", name, f.name, previous[name], flag)
+		for _, o := range f.onChange {
+			o(name, flag)
+		}
+	}
+}
+
+// Get returns the state of the named flag, or Disabled for unknown flags.
+func (f *FeatureFlags) Get(name string) Flag {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if flag, ok := f.flags[name]; ok {
+		return flag
+	}
+	return Disabled
+}
+
+// IsEnabled returns whether the named flag is Enabled.
+func (f *FeatureFlags) IsEnabled(name string) bool {
+	return f.Get(name) == Enabled
+}
+
+// IsAllowed returns whether the named flag is Enabled or Allowed.
+func (f *FeatureFlags) IsAllowed(name string) bool {
+	flag := f.Get(name)
+	return flag == Enabled || flag == Allowed
+}
+
+// IsDisabled returns whether the named flag is Disabled.
+func (f *FeatureFlags) IsDisabled(name string) bool {
+	return f.Get(name) == Disabled
+}
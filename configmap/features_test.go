@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configmap
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "knative.dev/pkg/logging/testing"
+)
+
+const featuresConfig = "config-features"
+
+var featureDefaults = map[string]Flag{
+	"multi-container": Enabled,
+	"pod-spec-dryrun": Allowed,
+	"tag-header":      Disabled,
+}
+
+func featuresConfigMap(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      featuresConfig,
+		},
+		Data: data,
+	}
+}
+
+func TestFeatureFlagsDefaults(t *testing.T) {
+	flags := NewFeatureFlags(featuresConfig, TestLogger(t), featureDefaults)
+
+	check := func() {
+		t.Helper()
+		if !flags.IsEnabled("multi-container") {
+			t.Error("multi-container is not enabled")
+		}
+		if flags.IsEnabled("pod-spec-dryrun") || !flags.IsAllowed("pod-spec-dryrun") {
+			t.Errorf("pod-spec-dryrun = %s, wanted %s", flags.Get("pod-spec-dryrun"), Allowed)
+		}
+		if !flags.IsDisabled("tag-header") || flags.IsAllowed("tag-header") {
+			t.Errorf("tag-header = %s, wanted %s", flags.Get("tag-header"), Disabled)
+		}
+		if got := flags.Get("unknown"); got != Disabled {
+			t.Errorf("Get(unknown) = %s, wanted %s", got, Disabled)
+		}
+	}
+
+	// Before the ConfigMap is seen.
+	check()
+	// With a ConfigMap setting none of the flags.
+	flags.OnConfigChanged(featuresConfigMap(map[string]string{"unrelated": "foo"}))
+	check()
+}
+
+func TestFeatureFlagsChange(t *testing.T) {
+	type change struct {
+		name string
+		flag Flag
+	}
+	var changes []change
+	flags := NewFeatureFlags(featuresConfig, TestLogger(t), featureDefaults, func(name string, flag Flag) {
+		changes = append(changes, change{name, flag})
+	})
+
+	watcher := &ManualWatcher{Namespace: "default"}
+	flags.WatchConfigs(watcher)
+
+	// Flip tag-header from disabled to enabled, the values are case insensitive.
+	watcher.OnChange(featuresConfigMap(map[string]string{
+		"multi-container": "Enabled",
+		"tag-header":      "enabled",
+	}))
+	if !flags.IsEnabled("tag-header") {
+		t.Errorf("tag-header = %s, wanted %s", flags.Get("tag-header"), Enabled)
+	}
+	if want := []change{{"tag-header", Enabled}}; !cmp.Equal(changes, want, cmp.AllowUnexported(change{})) {
+		t.Error("Changes (-got, +want):", cmp.Diff(changes, want, cmp.AllowUnexported(change{})))
+	}
+
+	// Invalid values leave the flags as they were.
+	changes = nil
+	watcher.OnChange(featuresConfigMap(map[string]string{
+		"multi-container": "Disabled",
+		"tag-header":      "yes",
+	}))
+	if !flags.IsEnabled("tag-header") || !flags.IsEnabled("multi-container") {
+		t.Error("The flags changed along with an invalid value")
+	}
+	if len(changes) != 0 {
+		t.Error("Notified changes along with an invalid value:", changes)
+	}
+
+	// Removing a flag from the ConfigMap restores its default.
+	watcher.OnChange(featuresConfigMap(nil))
+	if !flags.IsDisabled("tag-header") {
+		t.Errorf("tag-header = %s, wanted %s", flags.Get("tag-header"), Disabled)
+	}
+	if want := []change{{"tag-header", Disabled}}; !cmp.Equal(changes, want, cmp.AllowUnexported(change{})) {
+		t.Error("Changes (-got, +want):", cmp.Diff(changes, want, cmp.AllowUnexported(change{})))
+	}
+}
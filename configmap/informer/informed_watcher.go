@@ -43,6 +43,7 @@ func NewInformedWatcherFromFactory(sif informers.SharedInformerFactory, namespac
 			Namespace: namespace,
 		},
 		defaults: make(map[string]*corev1.ConfigMap),
+		frozen:   make(map[string]struct{}),
 	}
 }
 
@@ -91,10 +92,18 @@ type InformedWatcher struct {
 	sif      informers.SharedInformerFactory
 	informer corev1informers.ConfigMapInformer
 	started  bool
+	// synced is set once Start has processed the initial state of all
+	// the watched ConfigMaps.
+	synced bool
 
 	// defaults are the default ConfigMaps to use if the real ones do not exist or are deleted.
 	defaults map[string]*corev1.ConfigMap
 
+	// frozen are the names of the ConfigMaps whose changes are ignored once Start has returned.
+	frozen map[string]struct{}
+	// logger reports the changes to frozen ConfigMaps that are ignored.
+	logger configmap.Logger
+
 	// Embedding this struct allows us to reuse the logic
 	// of registering and notifying observers. This simplifies the
 	// InformedWatcher to just setting up the Kubernetes informer.
@@ -127,6 +136,22 @@ func (i *InformedWatcher) WatchWithDefault(cm corev1.ConfigMap, o ...configmap.O
 	i.Watch(cm.Name, o...)
 }
 
+// Freeze marks the named ConfigMaps as frozen. Their observers are notified
+// of the state observed while the InformedWatcher starts, but later changes
+// to them are ignored and logged through the given logger, as they only take
+// effect after a restart. The logger must not be nil.
+func (i *InformedWatcher) Freeze(logger configmap.Logger, names ...string) {
+	i.Lock()
+	defer i.Unlock()
+	if i.started {
+		panic("cannot Freeze after the InformedWatcher has started")
+	}
+	i.logger = logger
+	for _, name := range names {
+		i.frozen[name] = struct{}{}
+	}
+}
+
 func (i *InformedWatcher) triggerAddEventForDefaultedConfigMaps(addConfigMapEvent func(obj interface{})) {
 	i.ForEach(func(k string, _ []configmap.Observer) error {
 		if def, ok := i.defaults[k]; ok {
@@ -176,7 +201,14 @@ func (i *InformedWatcher) Start(stopCh <-chan struct{}) error {
 	}
 
 	// Wait until all config maps have been at least initially processed
-	return s.WaitForAllKeys(stopCh)
+	if err := s.WaitForAllKeys(stopCh); err != nil {
+		return err
+	}
+
+	i.Lock()
+	defer i.Unlock()
+	i.synced = true
+	return nil
 }
 
 func (i *InformedWatcher) registerCallbackAndStartInformer(addConfigMapEvent func(obj interface{}), stopCh <-chan struct{}) error {
@@ -215,8 +247,23 @@ func (i *InformedWatcher) checkObservedResourcesExist() error {
 	})
 }
 
+// isFrozen reports whether changes to the named ConfigMap must be ignored,
+// logging that a restart is required for them to take effect.
+func (i *InformedWatcher) isFrozen(name string) bool {
+	i.RLock()
+	defer i.RUnlock()
+	if _, ok := i.frozen[name]; !ok || !i.synced {
+		return false
+	}
+	i.logger.Infof("Ignoring change to frozen config %q, a restart is required for it to take effect", name)
+	return true
+}
+
 func (i *InformedWatcher) addConfigMapEvent(obj interface{}) {
 	configMap := obj.(*corev1.ConfigMap)
+	if i.isFrozen(configMap.Name) {
+		return
+	}
 	i.OnChange(configMap)
 }
 
@@ -227,11 +274,17 @@ func (i *InformedWatcher) updateConfigMapEvent(o, n interface{}) {
 		return
 	}
 	configMap := n.(*corev1.ConfigMap)
+	if i.isFrozen(configMap.Name) {
+		return
+	}
 	i.OnChange(configMap)
 }
 
 func (i *InformedWatcher) deleteConfigMapEvent(obj interface{}) {
 	configMap := obj.(*corev1.ConfigMap)
+	if i.isFrozen(configMap.Name) {
+		return
+	}
 	if def, ok := i.defaults[configMap.Name]; ok {
 		i.OnChange(def)
 	}
@@ -28,6 +28,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/selection"
 	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+
+	logtesting "knative.dev/pkg/logging/testing"
 )
 
 type counter struct {
@@ -169,6 +171,82 @@ func TestInformedWatcher(t *testing.T) {
 	}
 }
 
+func TestFrozenConfigMap(t *testing.T) {
+	fooCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "foo",
+		},
+		Data: map[string]string{"key": "val"},
+	}
+	barCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "bar",
+		},
+		Data: map[string]string{"key2": "val3"},
+	}
+	kc := fakekubeclientset.NewSimpleClientset(fooCM, barCM)
+	cmw := NewInformedWatcher(kc, "default")
+
+	foo := &counter{name: "foo"}
+	bar := &counter{name: "bar"}
+	cmw.Watch("foo", foo.callback)
+	cmw.Watch("bar", bar.callback)
+	cmw.Freeze(logtesting.TestLogger(t), "foo")
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := cmw.Start(stopCh); err != nil {
+		t.Fatal("cm.Start() =", err)
+	}
+
+	// The frozen ConfigMap is observed at startup, like any other.
+	for _, obj := range []*counter{foo, bar} {
+		if got, want := obj.count(), 1; got != want {
+			t.Errorf("%v.count = %d, want %d", obj.name, got, want)
+		}
+	}
+
+	// Edits after startup only reach the observers of the ConfigMaps
+	// that are not frozen.
+	nfooCM := fooCM.DeepCopy()
+	nfooCM.Data["key"] = "edited"
+	cmw.updateConfigMapEvent(fooCM, nfooCM)
+	nbarCM := barCM.DeepCopy()
+	nbarCM.Data["key2"] = "edited"
+	cmw.updateConfigMapEvent(barCM, nbarCM)
+	cmw.deleteConfigMapEvent(nfooCM)
+
+	if got, want := foo.count(), 1; got != want {
+		t.Errorf("%v.count = %d, want %d", foo.name, got, want)
+	}
+	if got, want := foo.cfg[0].Data, fooCM.Data; !equality.Semantic.DeepEqual(want, got) {
+		t.Errorf("%v config seen should have been '%v', actually '%v'", foo.name, want, got)
+	}
+	if got, want := bar.count(), 2; got != want {
+		t.Errorf("%v.count = %d, want %d", bar.name, got, want)
+	}
+}
+
+func TestFreezeAfterStart(t *testing.T) {
+	kc := fakekubeclientset.NewSimpleClientset()
+	cmw := NewInformedWatcher(kc, "default")
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := cmw.Start(stopCh); err != nil {
+		t.Fatal("cm.Start() =", err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Freeze() after Start() did not panic")
+		}
+	}()
+	cmw.Freeze(logtesting.TestLogger(t), "foo")
+}
+
 func TestFilterConfigByLabelExists(t *testing.T) {
 	testCases := map[string]struct {
 		input        string
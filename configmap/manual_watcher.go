@@ -17,6 +17,7 @@ limitations under the License.
 package configmap
 
 import (
+	"log"
 	"sync"
 
 	corev1 "k8s.io/api/core/v1"
@@ -26,6 +27,10 @@ import (
 type ManualWatcher struct {
 	Namespace string
 
+	// Logger, when set, reports the observers that panic while handling
+	// a change. The standard logger is used otherwise.
+	Logger Logger
+
 	// Guards observers
 	sync.RWMutex
 	observers map[string][]Observer
@@ -69,6 +74,21 @@ func (w *ManualWatcher) OnChange(configMap *corev1.ConfigMap) {
 	defer w.RUnlock()
 	// Iterate over the observers and invoke their callbacks.
 	for _, o := range w.observers[configMap.Name] {
-		o(configMap)
+		w.notify(o, configMap)
 	}
 }
+
+// notify invokes the observer, recovering from its panics so that they don't
+// prevent the remaining observers from being notified of the change.
+func (w *ManualWatcher) notify(o Observer, configMap *corev1.ConfigMap) {
+	defer func() {
+		if r := recover(); r != nil {
+			if w.Logger != nil {
+				w.Logger.Errorf("Observer of ConfigMap %q panicked: %v", configMap.Name, r)
+			} else {
+				log.Printf("Observer of ConfigMap %q panicked: %v", configMap.Name, r)
+			}
+		}
+	}()
+	o(configMap)
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package configmap
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -122,3 +124,41 @@ func TestDifferentConfigName(t *testing.T) {
 		t.Error("Expected callback to be not be invoked - got invocations", observer.count())
 	}
 }
+
+type recordingLogger struct {
+	errors []string
+}
+
+func (l *recordingLogger) Debugf(string, ...interface{}) {}
+func (l *recordingLogger) Infof(string, ...interface{})  {}
+func (l *recordingLogger) Fatalf(string, ...interface{}) {}
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+func TestPanickingObserver(t *testing.T) {
+	logger := &recordingLogger{}
+	watcher := ManualWatcher{
+		Namespace: "default",
+		Logger:    logger,
+	}
+
+	observer := counter{}
+
+	watcher.Watch("foo", func(*corev1.ConfigMap) {
+		panic("boom")
+	}, observer.callback)
+	watcher.OnChange(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "foo",
+		},
+	})
+
+	if observer.count() != 1 {
+		t.Error("Expected callback to be invoked once - got invocations", observer.count())
+	}
+	if want := []string{`Observer of ConfigMap "foo" panicked: boom`}; !cmp.Equal(logger.errors, want) {
+		t.Errorf("Logged errors = %q, want: %q", logger.errors, want)
+	}
+}
@@ -24,6 +24,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"go.opencensus.io/trace"
+	"go.uber.org/atomic"
 	"golang.org/x/sync/errgroup"
 
 	"go.uber.org/zap"
@@ -37,6 +39,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/kmeta"
 	kle "knative.dev/pkg/leaderelection"
 	"knative.dev/pkg/logging"
@@ -90,6 +93,48 @@ func HandleAll(h func(interface{})) cache.ResourceEventHandler {
 	}
 }
 
+// HandleGenerationChanges wraps the provided handler function into a
+// cache.ResourceEventHandler for reconcilers that only care about changes
+// to the spec of the resources. Adds and deletes are sent to the handler,
+// but updates are only forwarded (passing the new object) when they bump
+// metadata.generation, when they start the deletion of the resource, or
+// when the status of a duckv1.KRShaped resource has not yet observed its
+// current generation, e.g. because a previous status update failed.
+// Periodic resyncs of the informer are forwarded as well.
+func HandleGenerationChanges(h func(interface{})) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: h,
+		UpdateFunc: func(first, second interface{}) {
+			if generationChanged(first, second) {
+				h(second)
+			}
+		},
+		DeleteFunc: h,
+	}
+}
+
+// generationChanged reports whether the update from old to new needs to be
+// reconciled by a reconciler that only cares about spec changes.
+func generationChanged(oldObj, newObj interface{}) bool {
+	o, ok := oldObj.(metav1.Object)
+	if !ok {
+		return true
+	}
+	n, ok := newObj.(metav1.Object)
+	if !ok {
+		return true
+	}
+	if o.GetResourceVersion() == n.GetResourceVersion() ||
+		o.GetGeneration() != n.GetGeneration() ||
+		(o.GetDeletionTimestamp() == nil) != (n.GetDeletionTimestamp() == nil) {
+		return true
+	}
+	if kr, ok := newObj.(duckv1.KRShaped); ok {
+		return kr.GetStatus().ObservedGeneration != n.GetGeneration()
+	}
+	return false
+}
+
 // Filter makes it simple to create FilterFunc's for use with
 // cache.FilteringResourceEventHandler that filter based on the
 // schema.GroupVersionKind of the controlling resources.
@@ -222,6 +267,13 @@ type Impl struct {
 	// Tracker allows reconcilers to associate a reference with particular key,
 	// such that when the reference changes the key is queued for reconciliation.
 	Tracker tracker.Interface
+
+	// started is set once the workers started, inFlight counts the keys
+	// being reconciled and drained latches once the work queue was observed
+	// empty with no key being reconciled after that, see InitialSyncDrained.
+	started  atomic.Bool
+	inFlight atomic.Int32
+	drained  atomic.Bool
 }
 
 // ControllerOptions encapsulates options for creating a new controller,
@@ -264,6 +316,34 @@ func NewContext(ctx context.Context, r Reconciler, options ControllerOptions) *I
 	return i
 }
 
+// QueueDepth returns the number of keys waiting in the work queue, across
+// both its lanes, excluding those being reconciled.
+func (c *Impl) QueueDepth() int {
+	return c.workQueue.Len()
+}
+
+// InitialSyncDrained reports whether the controller has worked through the
+// backlog of keys it had when it started, i.e. the work queue was observed
+// empty with no key being reconciled since the workers started. Once true,
+// it remains so, regardless of the keys enqueued later. Keys scheduled to be
+// retried after a delay do not hold it back. This is intended to
+// gate readiness, so that a new replica does not report ready while it is
+// still catching up with the informers' initial listing.
+func (c *Impl) InitialSyncDrained() bool {
+	return c.drained.Load()
+}
+
+// checkDrained latches drained if the work queue is empty and no key is
+// being reconciled since the workers started.
+func (c *Impl) checkDrained() {
+	if c.drained.Load() || !c.started.Load() {
+		return
+	}
+	if c.inFlight.Load() == 0 && c.workQueue.Len() == 0 && !c.drained.Swap(true) {
+		c.logger.Info("Drained the initial work queue")
+	}
+}
+
 // WorkQueue permits direct access to the work queue.
 func (c *Impl) WorkQueue() workqueue.RateLimitingInterface {
 	return c.workQueue
@@ -339,6 +419,28 @@ func (c *Impl) EnqueueControllerOf(obj interface{}) {
 	}
 }
 
+// EnqueueControllerOfCoalesced returns an Enqueue func that, like
+// EnqueueControllerOf, enqueues the controller resource of the resources it
+// is given. The enqueues of the same controller resource received within
+// window of the first one are coalesced into a single enqueue, which is added
+// to the work queue once window has elapsed. This avoids redundant reconciles
+// of resources controlling many others that change in bulk.
+func (c *Impl) EnqueueControllerOfCoalesced(window time.Duration) func(obj interface{}) {
+	return func(obj interface{}) {
+		object, err := kmeta.DeletionHandlingAccessor(obj)
+		if err != nil {
+			c.logger.Error(err)
+			return
+		}
+
+		if owner := metav1.GetControllerOf(object); owner != nil {
+			// The delaying queue only keeps the earliest of the pending
+			// additions of a key, so the burst yields a single one.
+			c.EnqueueKeyAfter(types.NamespacedName{Namespace: object.GetNamespace(), Name: owner.Name}, window)
+		}
+	}
+}
+
 // EnqueueLabelOfNamespaceScopedResource returns with an Enqueue func that
 // takes a resource, identifies its controller resource through given namespace
 // and name labels, converts it into a namespace/name string, and passes that
@@ -441,6 +543,42 @@ func (c *Impl) EnqueueKeyAfter(key types.NamespacedName, delay time.Duration) {
 	}
 }
 
+// enqueueBackoff is how long EnqueueKeysFrom waits for the work queue to
+// drain below its maximum depth before checking it again.
+const enqueueBackoff = 10 * time.Millisecond
+
+// EnqueueKeysFrom enqueues the keys received on ch, e.g. from an external
+// event source such as a message bus, until ch is closed, ctx is done or the
+// work queue shuts down. While maxDepth keys or more are waiting in the work
+// queue, it stops receiving from ch, so that the backpressure propagates to
+// the senders rather than the work queue growing without bounds. A
+// non-positive maxDepth disables the backpressure.
+// EnqueueKeysFrom blocks, so it is meant to be run in its own goroutine.
+func (c *Impl) EnqueueKeysFrom(ctx context.Context, ch <-chan types.NamespacedName, maxDepth int) {
+	for {
+		for maxDepth > 0 && c.workQueue.Len() >= maxDepth {
+			if c.workQueue.ShuttingDown() {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(enqueueBackoff):
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case key, ok := <-ch:
+			if !ok || c.workQueue.ShuttingDown() {
+				return
+			}
+			c.EnqueueKey(key)
+		}
+	}
+}
+
 // Run runs the controller with it's configured Concurrency
 func (c *Impl) Run(ctx context.Context) error {
 	return c.RunContext(ctx, c.Concurrency)
@@ -494,6 +632,8 @@ func (c *Impl) RunContext(ctx context.Context, threadiness int) error {
 	}
 
 	c.logger.Info("Started workers")
+	c.started.Store(true)
+	c.checkDrained()
 	<-ctx.Done()
 	c.logger.Info("Shutting down workers")
 
@@ -507,6 +647,7 @@ func (c *Impl) processNextWorkItem() bool {
 	if shutdown {
 		return false
 	}
+	c.inFlight.Inc()
 	key := obj.(types.NamespacedName)
 	keyStr := safeKey(key)
 
@@ -530,6 +671,8 @@ func (c *Impl) processNextWorkItem() bool {
 		// Forget and put the item back to the queue with an increased
 		// delay.
 		c.workQueue.Done(key)
+		c.inFlight.Dec()
+		c.checkDrained()
 	}()
 
 	// Embed the key into the logger and attach that to the context we pass
@@ -537,6 +680,17 @@ func (c *Impl) processNextWorkItem() bool {
 	logger := c.logger.With(zap.String(logkey.TraceID, uuid.NewString()), zap.String(logkey.Key, keyStr))
 	ctx := logging.WithLogger(context.Background(), logger)
 
+	// Trace the reconcile, so that the API calls the Reconciler makes with
+	// ctx are part of it.
+	ctx, span := trace.StartSpan(ctx, c.Name)
+	span.AddAttributes(trace.StringAttribute(logkey.Key, keyStr))
+	defer func() {
+		if err != nil && !IsSkipKey(err) {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+		}
+		span.End()
+	}()
+
 	// Run Reconcile, passing it the namespace/name string of the
 	// resource to be synced.
 	if err = c.Reconciler.Reconcile(ctx, keyStr); err != nil {
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/trace"
 	"go.uber.org/atomic"
 
 	coordinationv1 "k8s.io/api/coordination/v1"
@@ -37,6 +38,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/leaderelection"
 	"knative.dev/pkg/ptr"
 	"knative.dev/pkg/reconciler"
@@ -73,6 +75,94 @@ func TestHandleAll(t *testing.T) {
 	ha.OnDelete(newObj)
 }
 
+func TestHandleGenerationChanges(t *testing.T) {
+	resource := func(rv string, generation, observedGeneration int64, labels map[string]string) *duckv1.KResource {
+		return &duckv1.KResource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "test-name",
+				Namespace:       "test-namespace",
+				ResourceVersion: rv,
+				Generation:      generation,
+				Labels:          labels,
+			},
+			Status: duckv1.Status{
+				ObservedGeneration: observedGeneration,
+			},
+		}
+	}
+	deleting := resource("2", 1, 1, nil)
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	tests := []struct {
+		name     string
+		old, new interface{}
+		want     bool
+	}{{
+		name: "generation bumped",
+		old:  resource("1", 1, 1, nil),
+		new:  resource("2", 2, 1, nil),
+		want: true,
+	}, {
+		name: "label-only update",
+		old:  resource("1", 1, 1, nil),
+		new:  resource("2", 1, 1, map[string]string{"foo": "bar"}),
+	}, {
+		name: "status not caught up with the generation",
+		old:  resource("1", 2, 1, nil),
+		new:  resource("2", 2, 1, map[string]string{"foo": "bar"}),
+		want: true,
+	}, {
+		name: "deletion started",
+		old:  resource("1", 1, 1, nil),
+		new:  deleting,
+		want: true,
+	}, {
+		name: "resync",
+		old:  resource("1", 1, 1, nil),
+		new:  resource("1", 1, 1, nil),
+		want: true,
+	}, {
+		name: "label-only update of a resource without status",
+		old: &Resource{
+			ObjectMeta: metav1.ObjectMeta{ResourceVersion: "1", Generation: 1},
+		},
+		new: &Resource{
+			ObjectMeta: metav1.ObjectMeta{ResourceVersion: "2", Generation: 1, Labels: map[string]string{"foo": "bar"}},
+		},
+	}, {
+		name: "not a metav1.Object",
+		old:  oldObj,
+		new:  newObj,
+		want: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got bool
+			h := HandleGenerationChanges(func(obj interface{}) {
+				if obj != test.new {
+					t.Errorf("HandleGenerationChanges() = %v, wanted %v", obj, test.new)
+				}
+				got = true
+			})
+
+			h.OnUpdate(test.old, test.new)
+			if got != test.want {
+				t.Errorf("Handler called = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+
+	// Adds and deletes are always forwarded.
+	calls := 0
+	h := HandleGenerationChanges(func(interface{}) { calls++ })
+	h.OnAdd(newObj)
+	h.OnDelete(newObj)
+	if calls != 2 {
+		t.Errorf("Handler called %d times, wanted 2", calls)
+	}
+}
+
 var gvk = schema.GroupVersionKind{
 	Group:   "pkg.knative.dev",
 	Version: "v1meta1",
@@ -869,6 +959,67 @@ func TestEnqueueKeyAfter(t *testing.T) {
 	}
 }
 
+func TestEnqueueControllerOfCoalesced(t *testing.T) {
+	impl := NewContext(context.TODO(), &nopReconciler{}, ControllerOptions{
+		Logger:        TestLogger(t),
+		WorkQueueName: "Testing",
+		Reporter:      &FakeStatsReporter{},
+	})
+	t.Cleanup(func() {
+		impl.WorkQueue().ShutDown()
+	})
+
+	enqueue := impl.EnqueueControllerOfCoalesced(shortDelay)
+
+	// A burst of events for resources owned by the same parent.
+	enqueueTime := time.Now()
+	for i := 0; i < 10; i++ {
+		enqueue(&Resource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprint("child-", i),
+				Namespace: "bar",
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: gvk.GroupVersion().String(),
+					Kind:       gvk.Kind,
+					Name:       "parent",
+					Controller: ptr.Bool(true),
+				}},
+			},
+		})
+	}
+	if got := impl.WorkQueue().Len(); got != 0 {
+		t.Errorf("|Queue| = %d within the coalescing window, want: 0", got)
+	}
+
+	queuePopulated := make(chan int)
+	ctx, cancel := context.WithTimeout(context.Background(), queueCheckTimeout)
+	t.Cleanup(func() {
+		close(queuePopulated)
+		cancel()
+	})
+
+	go wait.PollImmediateUntil(5*time.Millisecond,
+		pollQ(impl.WorkQueue(), queuePopulated), ctx.Done())
+
+	select {
+	case <-queuePopulated:
+		if enqueueDelay := time.Since(enqueueTime); enqueueDelay < shortDelay {
+			t.Errorf("Item enqueued within %v, expected at least a %v delay", enqueueDelay, shortDelay)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for item to be put onto the workqueue")
+	}
+
+	// Give any redundant enqueues a chance to show up.
+	time.Sleep(shortDelay)
+	impl.WorkQueue().ShutDown()
+
+	got, want := drainWorkQueue(impl.WorkQueue()), []types.NamespacedName{{Namespace: "bar", Name: "parent"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected workqueue state (-:expect, +:got):\n%s", diff)
+	}
+}
+
 type CountingReconciler struct {
 	count atomic.Int32
 }
@@ -1126,6 +1277,80 @@ func TestStartAndShutdownWithWork(t *testing.T) {
 	checkStats(t, reporter, 1, 0, 1, trueString)
 }
 
+// blockingReconciler blocks each reconcile until it is released.
+type blockingReconciler struct {
+	release chan struct{}
+}
+
+func (br *blockingReconciler) Reconcile(context.Context, string) error {
+	<-br.release
+	return nil
+}
+
+func TestInitialSyncDrained(t *testing.T) {
+	r := &blockingReconciler{release: make(chan struct{})}
+	impl := NewContext(context.TODO(), r, ControllerOptions{
+		Logger:        TestLogger(t),
+		WorkQueueName: "Testing",
+		Reporter:      &FakeStatsReporter{},
+		Concurrency:   1,
+	})
+
+	// The backlog from the informers' initial listing.
+	for _, name := range []string{"bar", "baz", "qux"} {
+		impl.EnqueueKey(types.NamespacedName{Namespace: "foo", Name: name})
+	}
+	if impl.InitialSyncDrained() {
+		t.Error("InitialSyncDrained() = true before starting")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		StartAll(ctx, impl)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-doneCh
+	})
+
+	// One key is being reconciled, the others are waiting.
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return impl.QueueDepth() == 2, nil
+	}); err != nil {
+		t.Fatal("QueueDepth() never reached 2:", impl.QueueDepth())
+	}
+	if impl.InitialSyncDrained() {
+		t.Error("InitialSyncDrained() = true with keys waiting")
+	}
+
+	// Release all but the last reconcile.
+	r.release <- struct{}{}
+	r.release <- struct{}{}
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return impl.QueueDepth() == 0, nil
+	}); err != nil {
+		t.Fatal("QueueDepth() never reached 0:", impl.QueueDepth())
+	}
+	if impl.InitialSyncDrained() {
+		t.Error("InitialSyncDrained() = true while reconciling the last key")
+	}
+
+	close(r.release)
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return impl.InitialSyncDrained(), nil
+	}); err != nil {
+		t.Fatal("InitialSyncDrained() never became true")
+	}
+
+	// The signal is latched.
+	impl.EnqueueKey(types.NamespacedName{Namespace: "foo", Name: "bar"})
+	if !impl.InitialSyncDrained() {
+		t.Error("InitialSyncDrained() = false after enqueuing a new key")
+	}
+}
+
 type fakeError struct{}
 
 var _ error = (*fakeError)(nil)
@@ -1175,6 +1400,117 @@ func TestRequeueKey(t *testing.T) {
 	}
 }
 
+func TestEnqueueKeysFrom(t *testing.T) {
+	r := &CountingReconciler{}
+	impl := NewContext(context.TODO(), r, ControllerOptions{
+		Logger:        TestLogger(t),
+		WorkQueueName: "Testing",
+		Reporter:      &FakeStatsReporter{},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	keys := make(chan types.NamespacedName)
+	enqueuerDone := make(chan struct{})
+	go func() {
+		defer close(enqueuerDone)
+		impl.EnqueueKeysFrom(ctx, keys, 1)
+	}()
+
+	keys <- types.NamespacedName{Namespace: "foo", Name: "bar"}
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return impl.QueueDepth() == 1, nil
+	}); err != nil {
+		t.Fatal("The key was never enqueued")
+	}
+
+	// The work queue is full until the controller starts, so the next key
+	// is not received.
+	select {
+	case keys <- types.NamespacedName{Namespace: "foo", Name: "baz"}:
+		t.Fatal("Received a key beyond the maximum depth")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		StartAll(ctx, impl)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-doneCh
+		<-enqueuerDone
+	})
+
+	select {
+	case keys <- types.NamespacedName{Namespace: "foo", Name: "baz"}:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the key to be received")
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return r.count.Load() == 2, nil
+	}); err != nil {
+		t.Fatal("Reconciles =", r.count.Load(), "wanted 2")
+	}
+
+	// Closing the channel stops the enqueuing.
+	close(keys)
+	select {
+	case <-enqueuerDone:
+	case <-time.After(time.Second):
+		t.Error("EnqueueKeysFrom didn't return once the channel was closed")
+	}
+}
+
+// spanReconciler sends the span of the reconcile contexts on spans.
+type spanReconciler struct {
+	spans chan *trace.Span
+}
+
+func (sr *spanReconciler) Reconcile(ctx context.Context, _ string) error {
+	sr.spans <- trace.FromContext(ctx)
+	return nil
+}
+
+func TestReconcileSpan(t *testing.T) {
+	r := &spanReconciler{spans: make(chan *trace.Span, 2)}
+	impl := NewContext(context.TODO(), r, ControllerOptions{
+		Logger:        TestLogger(t),
+		WorkQueueName: "Testing",
+		Reporter:      &FakeStatsReporter{},
+	})
+	impl.EnqueueKey(types.NamespacedName{Namespace: "foo", Name: "bar"})
+	impl.EnqueueKey(types.NamespacedName{Namespace: "foo", Name: "baz"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		StartAll(ctx, impl)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-doneCh
+	})
+
+	var traces []trace.TraceID
+	for i := 0; i < 2; i++ {
+		select {
+		case span := <-r.spans:
+			if span == nil {
+				t.Fatal("The reconcile context carries no span")
+			}
+			traces = append(traces, span.SpanContext().TraceID)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the reconciles")
+		}
+	}
+	// Each reconcile is traced on its own.
+	if traces[0] == traces[1] {
+		t.Error("The reconciles share trace", traces[0])
+	}
+}
+
 type errorReconciler struct{}
 
 func (er *errorReconciler) Reconcile(context.Context, string) error {
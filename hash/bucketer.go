@@ -19,6 +19,7 @@ limitations under the License.
 package hash
 
 import (
+	"strconv"
 	"sync"
 
 	lru "github.com/hashicorp/golang-lru"
@@ -35,10 +36,18 @@ type BucketSet struct {
 	// Stores the cached lookups. cache is internally thread safe.
 	cache *lru.Cache
 
-	// mu guards buckets.
+	// mu guards buckets and the weighted universe.
 	mu sync.RWMutex
 	// All the bucket names. Needed for building hash universe.
 	buckets sets.String
+
+	// weights are the relative weights of the buckets, if set. See
+	// NewWeightedBucketSet.
+	weights map[string]uint32
+	// slots and slotOwners are the universe of weighted buckets, where
+	// each bucket has as many slots as its weight, and the bucket of each slot.
+	slots      sets.String
+	slotOwners map[string]string
 }
 
 // Bucket implements reconciler.Bucket and wraps around BuketSet
@@ -66,6 +75,51 @@ func NewBucketSet(bucketList sets.String) *BucketSet {
 	}
 }
 
+// NewWeightedBucketSet creates a new bucket set with the given universe of
+// bucket names, and their relative weights, so that each bucket owns a share
+// of the keys proportional to its weight, e.g. a bucket of weight 2 owns
+// about twice as many keys as a bucket of weight 1. The buckets missing from
+// weights, or of weight 0, have a weight of 1. The sum of the weights should
+// stay well below 2048, the size of the hash universe.
+func NewWeightedBucketSet(weights map[string]uint32) *BucketSet {
+	names := make(sets.String, len(weights))
+	for n := range weights {
+		names.Insert(n)
+	}
+	bs := &BucketSet{
+		cache:   newCache(),
+		buckets: names,
+		weights: weights,
+	}
+	bs.buildSlots()
+	return bs
+}
+
+// buildSlots builds the weighted universe of the buckets, when weighted.
+// The first slot of each bucket is named after it, so that buckets of
+// a weight of 1 own the same keys as unweighted ones.
+func (bs *BucketSet) buildSlots() {
+	if bs.weights == nil {
+		return
+	}
+	bs.slots = make(sets.String, len(bs.buckets))
+	bs.slotOwners = make(map[string]string, len(bs.buckets))
+	for n := range bs.buckets {
+		w := bs.weights[n]
+		if w == 0 {
+			w = 1
+		}
+		for i := uint32(0); i < w; i++ {
+			slot := n
+			if i > 0 {
+				slot = n + "#" + strconv.FormatUint(uint64(i), 10)
+			}
+			bs.slots.Insert(slot)
+			bs.slotOwners[slot] = n
+		}
+	}
+}
+
 // Name implements Bucket.
 func (b *Bucket) Name() string {
 	return b.name
@@ -98,7 +152,16 @@ func (bs *BucketSet) Owner(key string) string {
 	}
 	bs.mu.RLock()
 	defer bs.mu.RUnlock()
-	ret, ok := GetAny(ChooseSubset(bs.buckets, 1 /*single query wanted*/, key))
+	var (
+		ret string
+		ok  bool
+	)
+	if bs.slots != nil {
+		ret, ok = GetAny(ChooseSubset(bs.slots, 1 /*single query wanted*/, key))
+		ret = bs.slotOwners[ret]
+	} else {
+		ret, ok = GetAny(ChooseSubset(bs.buckets, 1 /*single query wanted*/, key))
+	}
 	if ok {
 		bs.cache.Add(key, ret)
 	}
@@ -127,7 +190,8 @@ func (bs *BucketSet) BucketList() []string {
 	return bs.buckets.List()
 }
 
-// Update updates the universe of buckets.
+// Update updates the universe of buckets. The weights of the buckets of a
+// weighted BucketSet are kept, the new buckets having a weight of 1.
 func (bs *BucketSet) Update(newB sets.String) {
 	bs.mu.Lock()
 	defer bs.mu.Unlock()
@@ -137,4 +201,5 @@ func (bs *BucketSet) Update(newB sets.String) {
 	// the cache as reconciliations happen.
 	bs.cache.Purge()
 	bs.buckets = newB
+	bs.buildSlots()
 }
@@ -17,7 +17,9 @@ limitations under the License.
 package hash
 
 import (
+	"math"
 	"reflect"
+	"strconv"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -155,3 +157,56 @@ func TestBucketName(t *testing.T) {
 		t.Errorf("Name = %q, want: %q", got, want)
 	}
 }
+
+func TestWeightedBucketSetOwner(t *testing.T) {
+	weights := map[string]uint32{
+		thisBucket:  4,
+		otherBucket: 2,
+		"aguacero":  1,
+		"chaparrón": 1,
+	}
+	const keys = 20000
+	b := NewWeightedBucketSet(weights)
+	owned := make(map[string]int, len(weights))
+	for i := 0; i < keys; i++ {
+		owned[b.Owner(types.NamespacedName{Namespace: "ns", Name: strconv.Itoa(i)}.String())]++
+	}
+
+	// The keys are distributed in proportion to the weights, within 3%, as
+	// the hashing of the keys is itself only about uniform.
+	const total = 8
+	for n, w := range weights {
+		got, want := float64(owned[n])/keys, float64(w)/total
+		if math.Abs(got-want) > 0.03 {
+			t.Errorf("Share of %s = %.3f, want: %.3f", n, got, want)
+		}
+	}
+}
+
+func TestWeightedBucketSetUnitWeights(t *testing.T) {
+	weights := make(map[string]uint32, len(buckets))
+	for n := range buckets {
+		weights[n] = 1
+	}
+	weighted, unweighted := NewWeightedBucketSet(weights), NewBucketSet(buckets)
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		if got, want := weighted.Owner(key), unweighted.Owner(key); got != want {
+			t.Errorf("Owner(%s) = %q, want: %q", key, got, want)
+		}
+	}
+}
+
+func TestWeightedBucketSetUpdate(t *testing.T) {
+	b := NewWeightedBucketSet(map[string]uint32{thisBucket: 3, otherBucket: 1})
+	b.Update(sets.NewString(thisBucket, otherBucket, "aguacero"))
+
+	owned := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		owned[b.Owner(strconv.Itoa(i))]++
+	}
+	// The weights are kept, the new bucket having a weight of 1.
+	if owned[thisBucket] < 2*owned[otherBucket] || owned[thisBucket] < 2*owned["aguacero"] {
+		t.Errorf("Owned keys = %v, want %s to own about three times as many as the others", owned, thisBucket)
+	}
+}
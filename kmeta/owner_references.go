@@ -36,3 +36,18 @@ type OwnerRefable interface {
 func NewControllerRef(obj OwnerRefable) *metav1.OwnerReference {
 	return metav1.NewControllerRef(obj.GetObjectMeta(), obj.GetGroupVersionKind())
 }
+
+// IsControlledBy returns whether child has a controller owner reference
+// pointing to owner, as determined by the owner's UID and GroupVersionKind.
+func IsControlledBy(child metav1.Object, owner OwnerRefable) bool {
+	ref := metav1.GetControllerOf(child)
+	if ref == nil {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return false
+	}
+	return ref.UID == owner.GetObjectMeta().GetUID() &&
+		gv.WithKind(ref.Kind) == owner.GetGroupVersionKind()
+}
@@ -62,3 +62,65 @@ func TestNewControllerRef(t *testing.T) {
 		t.Error("Unexpected OwnerReference (-want +got):", diff)
 	}
 }
+
+func TestIsControlledBy(t *testing.T) {
+	owner := &Frobber{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "foo",
+			UID:  "42",
+		},
+	}
+
+	tests := []struct {
+		name  string
+		child metav1.Object
+		want  bool
+	}{{
+		name: "controlled by owner",
+		child: &metav1.ObjectMeta{
+			OwnerReferences: []metav1.OwnerReference{*NewControllerRef(owner)},
+		},
+		want: true,
+	}, {
+		name:  "orphan",
+		child: &metav1.ObjectMeta{},
+	}, {
+		name: "non-controller owner reference",
+		child: &metav1.ObjectMeta{
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "example.knative.dev/v1alpha1",
+				Kind:       "Frobber",
+				Name:       "foo",
+				UID:        "42",
+			}},
+		},
+	}, {
+		name: "controlled by a different UID",
+		child: &metav1.ObjectMeta{
+			OwnerReferences: []metav1.OwnerReference{*NewControllerRef(&Frobber{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo",
+					UID:  "43",
+				},
+			})},
+		},
+	}, {
+		name: "controlled by a different kind",
+		child: &metav1.ObjectMeta{
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(owner,
+				schema.GroupVersionKind{
+					Group:   "example.knative.dev",
+					Version: "v1alpha1",
+					Kind:    "Gizmo",
+				})},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IsControlledBy(test.child, owner); got != test.want {
+				t.Errorf("IsControlledBy() = %v, wanted %v", got, test.want)
+			}
+		})
+	}
+}
@@ -56,6 +56,7 @@ func NewConfigFromMap(data map[string]string) (*Config, error) {
 		cm.AsDuration("retry-period", &config.RetryPeriod),
 
 		cm.AsUint32("buckets", &config.Buckets),
+		asBucketWeights("bucket-weights", &config.BucketWeights),
 
 		cm.CollectMapEntriesWithPrefix("map-lease-prefix", &config.LeaseNamesPrefixMapping),
 	); err != nil {
@@ -65,9 +66,36 @@ func NewConfigFromMap(data map[string]string) (*Config, error) {
 	if config.Buckets < 1 || config.Buckets > MaxBuckets {
 		return nil, fmt.Errorf("buckets: value must be between %d <= %d <= %d", 1, config.Buckets, MaxBuckets)
 	}
+	if len(config.BucketWeights) > 0 && len(config.BucketWeights) != int(config.Buckets) {
+		return nil, fmt.Errorf("bucket-weights: got %d weights, want one per bucket, i.e. %d", len(config.BucketWeights), config.Buckets)
+	}
 	return config, nil
 }
 
+// asBucketWeights parses the comma separated, positive, weights at key into
+// target, if it exists.
+func asBucketWeights(key string, target *[]uint32) cm.ParseFunc {
+	return func(data map[string]string) error {
+		raw, ok := data[key]
+		if !ok {
+			return nil
+		}
+		weights := strings.Split(raw, ",")
+		*target = make([]uint32, len(weights))
+		for i, w := range weights {
+			val, err := strconv.ParseUint(strings.TrimSpace(w), 10, 32)
+			if err != nil {
+				return fmt.Errorf("failed to parse %q: %w", key, err)
+			}
+			if val == 0 {
+				return fmt.Errorf("%s: weights must be positive", key)
+			}
+			(*target)[i] = uint32(val)
+		}
+		return nil
+	}
+}
+
 // NewConfigFromConfigMap returns a new Config from the given ConfigMap.
 func NewConfigFromConfigMap(configMap *corev1.ConfigMap) (*Config, error) {
 	if configMap == nil {
@@ -86,12 +114,18 @@ type Config struct {
 	RenewDeadline           time.Duration
 	RetryPeriod             time.Duration
 	LeaseNamesPrefixMapping map[string]string
+
+	// BucketWeights are the relative weights of the buckets, by ordinal, if
+	// set, so that the buckets of higher weights own proportionally more
+	// keys, e.g. when they are held by replicas of higher capacity.
+	BucketWeights []uint32
 }
 
 func (c *Config) GetComponentConfig(name string) ComponentConfig {
 	return ComponentConfig{
 		Component:               name,
 		Buckets:                 c.Buckets,
+		BucketWeights:           c.BucketWeights,
 		LeaseDuration:           c.LeaseDuration,
 		RenewDeadline:           c.RenewDeadline,
 		RetryPeriod:             c.RetryPeriod,
@@ -132,6 +166,10 @@ type ComponentConfig struct {
 	// from <component>.<package>.<reconciler_type_name> to the
 	// associated value when using standardBuilder.
 	LeaseNamesPrefixMapping map[string]string
+
+	// BucketWeights are the relative weights of the buckets, by ordinal, if
+	// set. See Config.BucketWeights.
+	BucketWeights []uint32
 }
 
 // statefulSetID is a envconfig Decodable controller ordinal and name.
@@ -113,6 +113,40 @@ func TestNewConfigMapFromData(t *testing.T) {
 			"buckets": strconv.Itoa(int(MaxBuckets + 1)),
 		}),
 		err: fmt.Sprintf("buckets: value must be between 1 <= %d <= %d", MaxBuckets+1, MaxBuckets),
+	}, {
+		name: "invalid bucket weights - not an int",
+		data: kmap.Union(okData(), map[string]string{
+			"buckets":        "2",
+			"bucket-weights": "2,heavy",
+		}),
+		err: `failed to parse "bucket-weights": strconv.ParseUint: parsing "heavy": invalid syntax`,
+	}, {
+		name: "invalid bucket weights - zero",
+		data: kmap.Union(okData(), map[string]string{
+			"buckets":        "2",
+			"bucket-weights": "2,0",
+		}),
+		err: "bucket-weights: weights must be positive",
+	}, {
+		name: "invalid bucket weights - not one per bucket",
+		data: kmap.Union(okData(), map[string]string{
+			"buckets":        "3",
+			"bucket-weights": "2,1",
+		}),
+		err: "bucket-weights: got 2 weights, want one per bucket, i.e. 3",
+	}, {
+		name: "bucket weights",
+		data: map[string]string{
+			"buckets":        "3",
+			"bucket-weights": "3, 1,1",
+		},
+		expected: &Config{
+			Buckets:       3,
+			BucketWeights: []uint32{3, 1, 1},
+			LeaseDuration: 60 * time.Second,
+			RenewDeadline: 40 * time.Second,
+			RetryPeriod:   10 * time.Second,
+		},
 	}, {
 		name: "legacy keys",
 		data: map[string]string{
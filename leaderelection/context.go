@@ -190,6 +190,17 @@ func newStandardBuckets(queueName string, cc ComponentConfig) []reconciler.Bucke
 			return standardBucketName(i, queueName, cc)
 		}
 	}
+	if len(cc.BucketWeights) > 0 {
+		weights := make(map[string]uint32, cc.Buckets)
+		for i := uint32(0); i < cc.Buckets; i++ {
+			if int(i) < len(cc.BucketWeights) {
+				weights[ln(i)] = cc.BucketWeights[i]
+			} else {
+				weights[ln(i)] = 1
+			}
+		}
+		return hash.NewWeightedBucketSet(weights).Buckets()
+	}
 	names := make(sets.String, cc.Buckets)
 	for i := uint32(0); i < cc.Buckets; i++ {
 		names.Insert(ln(i))
@@ -383,6 +383,28 @@ func TestStandardBucketName(t *testing.T) {
 	}
 }
 
+func TestStandardBucketsWeights(t *testing.T) {
+	bkts := newStandardBuckets("queue-queue", ComponentConfig{
+		Component:     "my-comp",
+		Buckets:       2,
+		BucketWeights: []uint32{3, 1},
+	})
+
+	owned := make([]int, len(bkts))
+	for i := 0; i < 4000; i++ {
+		key := types.NamespacedName{Namespace: "ns", Name: fmt.Sprint("name-", i)}
+		for j, b := range bkts {
+			if b.Has(key) {
+				owned[j]++
+			}
+		}
+	}
+	// The buckets are ordered by name, i.e. by ordinal.
+	if owned[0]+owned[1] != 4000 || owned[0] < 2*owned[1] {
+		t.Errorf("Owned keys = %v, want the first bucket to own about three times as many", owned)
+	}
+}
+
 func TestUnopposedElectorInitialBucket(t *testing.T) {
 	u := &unopposedElector{
 		bkt: reconciler.UniversalBucket(),
@@ -118,6 +118,9 @@ func (mc *metricsConfig) record(ctx context.Context, mss []stats.Measurement, ro
 		return err
 	}
 	ros = append(ros, opt)
+	if attachments := exemplarAttachments(ctx); attachments != nil {
+		ros = append(ros, stats.WithAttachments(attachments))
+	}
 
 	return stats.RecordWithOptions(ctx, append(ros, stats.WithMeasurements(mss...))...)
 }
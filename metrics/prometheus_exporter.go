@@ -22,6 +22,8 @@ import (
 	"sync"
 
 	prom "contrib.go.opencensus.io/exporter/prometheus"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opencensus.io/resource"
 	"go.opencensus.io/stats/view"
 	"go.uber.org/zap"
@@ -43,7 +45,8 @@ func (emptyPromExporter) ExportView(viewData *view.Data) {
 
 //nolint: unparam // False positive of flagging the second result of this function unused.
 func newPrometheusExporter(config *metricsConfig, logger *zap.SugaredLogger) (view.Exporter, ResourceExporterFactory, error) {
-	e, err := prom.NewExporter(prom.Options{Namespace: config.component})
+	registry := promclient.NewRegistry()
+	e, err := prom.NewExporter(prom.Options{Namespace: config.component, Registry: registry})
 	if err != nil {
 		logger.Errorw("Failed to create the Prometheus exporter.", zap.Error(err))
 		return nil, nil, err
@@ -51,7 +54,7 @@ func newPrometheusExporter(config *metricsConfig, logger *zap.SugaredLogger) (vi
 	logger.Infof("Created Prometheus exporter with config: %v. Start the server for Prometheus exporter.", config)
 	// Start the server for Prometheus scraping
 	go func() {
-		srv := startNewPromSrv(e, config.prometheusHost, config.prometheusPort)
+		srv := startNewPromSrv(registry, config.prometheusHost, config.prometheusPort)
 		srv.ListenAndServe()
 	}()
 	return e,
@@ -74,9 +77,11 @@ func resetCurPromSrv() {
 	}
 }
 
-func startNewPromSrv(e *prom.Exporter, host string, port int) *http.Server {
+// startNewPromSrv serves the metrics gathered by g, negotiating the
+// OpenMetrics format with scrapers that accept it so exemplars are exposed.
+func startNewPromSrv(g promclient.Gatherer, host string, port int) *http.Server {
 	sm := http.NewServeMux()
-	sm.Handle("/metrics", e)
+	sm.Handle("/metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{EnableOpenMetrics: true}))
 	curPromSrvMux.Lock()
 	defer curPromSrvMux.Unlock()
 	if curPromSrv != nil {
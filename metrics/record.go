@@ -19,23 +19,42 @@ package metrics
 import (
 	"context"
 
+	"go.opencensus.io/metric/metricdata"
 	"go.opencensus.io/stats"
+	"go.opencensus.io/trace"
 )
 
 // TODO should be properly refactored and pieces should move to eventing and serving, as appropriate.
 // 	See https://github.com/knative/pkg/issues/608
 
 // Record stores the given Measurement from `ms` in the current metrics backend.
+// When `ctx` carries a sampled trace span, the span is attached to the
+// recorded value as an exemplar, which links distributions to the traces.
 func Record(ctx context.Context, ms stats.Measurement, ros ...stats.Options) {
 	getCurMetricsConfig().record(ctx, []stats.Measurement{ms}, ros...)
 }
 
 // RecordBatch stores the given Measurements from `mss` in the current metrics backend.
-// All metrics should be reported using the same Resource.
+// All metrics should be reported using the same Resource. Like with Record,
+// the sampled trace span of `ctx`, if any, is attached as an exemplar.
 func RecordBatch(ctx context.Context, mss ...stats.Measurement) {
 	getCurMetricsConfig().record(ctx, mss)
 }
 
+// exemplarAttachments returns the exemplar attachments linking the values
+// recorded with ctx to its sampled trace span, if any.
+func exemplarAttachments(ctx context.Context) metricdata.Attachments {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return nil
+	}
+	sc := span.SpanContext()
+	if !sc.IsSampled() {
+		return nil
+	}
+	return metricdata.Attachments{metricdata.AttachmentKeySpanContext: sc}
+}
+
 // Buckets125 generates an array of buckets with approximate powers-of-two
 // buckets that also aligns with powers of 10 on every 3rd step. This can
 // be used to create a view.Distribution.
@@ -25,10 +25,12 @@ import (
 	"knative.dev/pkg/metrics/metricstest"
 
 	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/metric/metricdata"
 	"go.opencensus.io/resource"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
 )
 
 type cases struct {
@@ -60,6 +62,46 @@ func TestRecordBatch(t *testing.T) {
 	metricstest.CheckLastValueData(t, measurement2.Measure().Name(), map[string]string{}, 42)
 }
 
+func TestRecordExemplar(t *testing.T) {
+	measure := stats.Float64("latency", "A latency", stats.UnitMilliseconds)
+	v := &view.View{
+		Measure:     measure,
+		Aggregation: view.Distribution(Buckets125(1, 100)...),
+	}
+	view.Register(v)
+	t.Cleanup(func() { view.Unregister(v) })
+	setCurMetricsConfig(&metricsConfig{})
+
+	ctx, span := trace.StartSpan(context.Background(), "sampled", trace.WithSampler(trace.AlwaysSample()))
+	defer span.End()
+	Record(ctx, measure.M(3))
+	_, unsampled := trace.StartSpan(context.Background(), "unsampled", trace.WithSampler(trace.NeverSample()))
+	defer unsampled.End()
+	Record(trace.NewContext(context.Background(), unsampled), measure.M(30))
+	Record(context.Background(), measure.M(60))
+
+	rows, err := view.RetrieveData(v.Name)
+	if err != nil {
+		t.Fatal("RetrieveData() =", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("Rows = %d, want: 1", len(rows))
+	}
+	var exemplars []metricdata.Attachments
+	for _, e := range rows[0].Data.(*view.DistributionData).ExemplarsPerBucket {
+		if e != nil {
+			exemplars = append(exemplars, e.Attachments)
+		}
+	}
+	// Values recorded without a sampled span carry no exemplar.
+	want := []metricdata.Attachments{
+		{metricdata.AttachmentKeySpanContext: span.SpanContext()},
+	}
+	if !cmp.Equal(exemplars, want) {
+		t.Errorf("Exemplar attachments = %v, want: %v", exemplars, want)
+	}
+}
+
 func TestBucketsNBy10(t *testing.T) {
 	tests := []struct {
 		base float64
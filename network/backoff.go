@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// BackoffStrategy decides how long each attempt of a retried operation may
+// take before it is retried. For the backoff dialers, this is the timeout of
+// each dial attempt. Implementations must be safe for concurrent use, as a
+// strategy is shared by all the dials of a dialer.
+type BackoffStrategy interface {
+	// NextDelay returns the delay of the attempt-th attempt, counting from 1,
+	// or false if no such attempt should be made. The first attempt is
+	// always made.
+	NextDelay(attempt int) (time.Duration, bool)
+}
+
+// ConstantBackoff allows Attempts attempts, each taking Delay.
+type ConstantBackoff struct {
+	Delay    time.Duration
+	Attempts int
+}
+
+var _ BackoffStrategy = ConstantBackoff{}
+
+// NextDelay implements BackoffStrategy.
+func (b ConstantBackoff) NextDelay(attempt int) (time.Duration, bool) {
+	if attempt > 1 && attempt > b.Attempts {
+		return 0, false
+	}
+	return b.Delay, true
+}
+
+// ExponentialBackoff is the BackoffStrategy equivalent of a wait.Backoff,
+// which the backoff dialers use for the wait.Backoff they are given.
+// The first attempt takes Duration, and each of the up to Steps retries
+// takes as long as the corresponding wait.Backoff.Step, i.e. Factor times
+// longer than the previous one starting from Duration, with Jitter. As with
+// wait.Backoff, the retries stop once the delay would exceed Cap, if set.
+type ExponentialBackoff wait.Backoff
+
+var _ BackoffStrategy = ExponentialBackoff{}
+
+// NewExponentialBackoff returns the BackoffStrategy equivalent of bo.
+func NewExponentialBackoff(bo wait.Backoff) BackoffStrategy {
+	return ExponentialBackoff(bo)
+}
+
+// DefaultBackoffStrategy is the strategy of DialWithBackOff and
+// DialTLSWithBackOff.
+var DefaultBackoffStrategy = NewExponentialBackoff(backOffTemplate)
+
+// NextDelay implements BackoffStrategy.
+func (b ExponentialBackoff) NextDelay(attempt int) (time.Duration, bool) {
+	// Replay the steps of the wait.Backoff, without their jitter.
+	bo := wait.Backoff(b)
+	bo.Jitter = 0
+	d := bo.Duration
+	for i := 1; i < attempt; i++ {
+		if bo.Steps < 1 {
+			return 0, false
+		}
+		d = bo.Step()
+	}
+	if attempt > 1 && b.Jitter > 0 {
+		d = wait.Jitter(d, b.Jitter)
+	}
+	return d, true
+}
+
+// DecorrelatedJitterBackoff allows Attempts attempts, each taking a random
+// delay between Base and an upper bound that grows threefold with every
+// attempt, up to Cap, spreading out the retries of concurrent operations.
+// Unlike the original decorrelated jitter, the bound doesn't depend on the
+// previous delay, so that the strategy can be shared.
+type DecorrelatedJitterBackoff struct {
+	Base     time.Duration
+	Cap      time.Duration
+	Attempts int
+}
+
+var _ BackoffStrategy = DecorrelatedJitterBackoff{}
+
+// NextDelay implements BackoffStrategy.
+func (b DecorrelatedJitterBackoff) NextDelay(attempt int) (time.Duration, bool) {
+	if attempt > 1 && attempt > b.Attempts {
+		return 0, false
+	}
+	if attempt <= 1 {
+		return b.Base, true
+	}
+	bound := float64(b.Base) * math.Pow(3, float64(attempt-1))
+	if b.Cap > 0 && bound > float64(b.Cap) {
+		bound = float64(b.Cap)
+	}
+	if bound <= float64(b.Base) {
+		return time.Duration(bound), true
+	}
+	return b.Base + time.Duration(rand.Int63n(int64(bound)-int64(b.Base)+1)), true //nolint:gosec // No need for a secure RNG.
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// delays returns the delays of the attempts allowed by s.
+func delays(s BackoffStrategy) []time.Duration {
+	var ds []time.Duration
+	for attempt := 1; ; attempt++ {
+		d, ok := s.NextDelay(attempt)
+		if !ok {
+			return ds
+		}
+		ds = append(ds, d)
+	}
+}
+
+func TestConstantBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy ConstantBackoff
+		want     []time.Duration
+	}{{
+		name:     "three attempts",
+		strategy: ConstantBackoff{Delay: time.Second, Attempts: 3},
+		want:     []time.Duration{time.Second, time.Second, time.Second},
+	}, {
+		name:     "the first attempt is always made",
+		strategy: ConstantBackoff{Delay: time.Second},
+		want:     []time.Duration{time.Second},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := delays(test.strategy); !cmp.Equal(got, test.want) {
+				t.Errorf("Delays = %v, want: %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy BackoffStrategy
+		want     []time.Duration
+	}{{
+		name: "steps",
+		strategy: NewExponentialBackoff(wait.Backoff{
+			Duration: 10 * time.Millisecond,
+			Factor:   2,
+			Steps:    4,
+		}),
+		// The first retry takes as long as the first attempt, as with
+		// wait.Backoff.Step.
+		want: []time.Duration{
+			10 * time.Millisecond,
+			10 * time.Millisecond,
+			20 * time.Millisecond,
+			40 * time.Millisecond,
+			80 * time.Millisecond,
+		},
+	}, {
+		name: "capped",
+		strategy: NewExponentialBackoff(wait.Backoff{
+			Duration: 10 * time.Millisecond,
+			Factor:   3,
+			Steps:    3,
+			Cap:      50 * time.Millisecond,
+		}),
+		// The retries stop once the cap is reached, as with wait.Backoff.
+		want: []time.Duration{
+			10 * time.Millisecond,
+			10 * time.Millisecond,
+			30 * time.Millisecond,
+		},
+	}, {
+		name: "no factor",
+		strategy: NewExponentialBackoff(wait.Backoff{
+			Duration: 10 * time.Millisecond,
+			Steps:    1,
+		}),
+		want: []time.Duration{10 * time.Millisecond, 10 * time.Millisecond},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := delays(test.strategy); !cmp.Equal(got, test.want) {
+				t.Errorf("Delays = %v, want: %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoffMatchesWaitBackoff(t *testing.T) {
+	bo := backOffTemplate
+	bo.Jitter = 0
+	got := delays(NewExponentialBackoff(bo))
+
+	want := []time.Duration{bo.Duration}
+	for bo.Steps > 0 {
+		want = append(want, bo.Step())
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("Delays = %v, want: %v", got, want)
+	}
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	s := DecorrelatedJitterBackoff{
+		Base:     10 * time.Millisecond,
+		Cap:      200 * time.Millisecond,
+		Attempts: 5,
+	}
+	// The upper bounds of the delays of the attempts.
+	bounds := []time.Duration{
+		10 * time.Millisecond,
+		30 * time.Millisecond,
+		90 * time.Millisecond,
+		200 * time.Millisecond,
+		200 * time.Millisecond,
+	}
+
+	for i := 0; i < 100; i++ {
+		got := delays(s)
+		if len(got) != len(bounds) {
+			t.Fatalf("Delays = %v, want %d of them", got, len(bounds))
+		}
+		for i, d := range got {
+			if d < s.Base || d > bounds[i] {
+				t.Errorf("Delay of attempt %d = %v, want within [%v, %v]", i+1, d, s.Base, bounds[i])
+			}
+		}
+	}
+}
+
+func TestDialWithBackoffStrategy(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+
+	var attempts int
+	ctx := WithDialAttemptsReporter(context.Background(), func(n int) {
+		attempts = n
+	})
+
+	// The attempts time out, as they can't connect within a nanosecond, and
+	// the strategy only allows two of them.
+	dial := NewBackoffDialer(backOffTemplate, WithBackoffStrategy(ConstantBackoff{Delay: time.Nanosecond, Attempts: 2}))
+	c, err := dial(ctx, "tcp4", addr)
+	if err == nil {
+		c.Close()
+		t.Fatal("Unexpected success dialing")
+	}
+	if !errors.Is(err, ErrDialTimeout) {
+		t.Errorf("Dial error = %v, want: %v", err, ErrDialTimeout)
+	}
+	if attempts != 2 {
+		t.Errorf("Dial attempts = %d, want: 2", attempts)
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// DialOption configures the dialers returned by NewBackoffDialer and
+// NewTLSBackoffDialer.
+type DialOption func(*dialOptions)
+
+type dialOptions struct {
+	// sem bounds the number of dials in flight, if non-nil.
+	sem chan struct{}
+
+	// idleTimeout, when positive, closes dialed connections after being
+	// idle for that long.
+	idleTimeout time.Duration
+
+	// budget bounds the rate of retries, if non-nil.
+	budget *RetryBudget
+
+	// sessionCache, if non-nil, is used by the TLS dials whose config has
+	// no ClientSessionCache of its own.
+	sessionCache tls.ClientSessionCache
+
+	// dnsCache, if non-nil, resolves the hosts of the dialed addresses.
+	dnsCache *DNSCache
+
+	// strategy, if non-nil, replaces the wait.Backoff of the dialer.
+	strategy BackoffStrategy
+}
+
+func newDialOptions(opts []DialOption) *dialOptions {
+	o := &dialOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMaxConcurrentDials limits the number of dials in flight at any given
+// time across all the calls to the dialer to n. Further dials wait for one
+// of the in-flight dials to finish, or for their context to be done.
+// By default, the number of concurrent dials is unlimited.
+func WithMaxConcurrentDials(n int) DialOption {
+	return func(o *dialOptions) {
+		if n > 0 {
+			o.sem = make(chan struct{}, n)
+		}
+	}
+}
+
+// WithIdleTimeout makes the dialer wrap the connections it returns so that
+// they are closed once no reads or writes happened on them for d, guarding
+// against leaking connections that callers forgot to set deadlines on.
+// Note that the wrapped connections are no longer *tls.Conn.
+// By default, connections are returned as is.
+func WithIdleTimeout(d time.Duration) DialOption {
+	return func(o *dialOptions) {
+		o.idleTimeout = d
+	}
+}
+
+// RetryBudget is a token bucket bounding the aggregate rate at which the
+// dialers sharing it retry dials, to avoid retry storms when an endpoint is
+// unreachable. See WithRetryBudget.
+type RetryBudget struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRetryBudget returns a RetryBudget allowing retriesPerSecond retries on
+// average, with bursts of up to burst retries. The budget starts full.
+func NewRetryBudget(retriesPerSecond float64, burst int) *RetryBudget {
+	return &RetryBudget{
+		rate:   retriesPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// take consumes a token from the budget, if one is available.
+func (b *RetryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// WithRetryBudget makes the dialer draw from b for each retry of a timed out
+// dial attempt. Once b is exhausted, dials fail fast with an error matching
+// ErrRetryBudgetExhausted rather than retrying. The same budget may be shared
+// across several dialers. By default, retries are unlimited.
+func WithRetryBudget(b *RetryBudget) DialOption {
+	return func(o *dialOptions) {
+		o.budget = b
+	}
+}
+
+// WithTLSSessionCache makes the TLS dialer resume the sessions established
+// by its previous dials using cache, sparing full handshakes to the servers
+// it frequently dials. A nil cache uses a new LRU cache, shared by all the
+// dials of the dialer. The cache is only used when the tls.Config given to a
+// dial has no ClientSessionCache; one set by the caller is always preserved.
+// By default, sessions are only resumed if the config has a cache.
+func WithTLSSessionCache(cache tls.ClientSessionCache) DialOption {
+	return func(o *dialOptions) {
+		if cache == nil {
+			cache = tls.NewLRUClientSessionCache(0)
+		}
+		o.sessionCache = cache
+	}
+}
+
+// WithBackoffStrategy makes the dialer time out and retry its dial attempts
+// as decided by s, e.g. a DecorrelatedJitterBackoff, rather than by the
+// wait.Backoff it was created with.
+func WithBackoffStrategy(s BackoffStrategy) DialOption {
+	return func(o *dialOptions) {
+		o.strategy = s
+	}
+}
+
+// backoffStrategy returns the strategy of the dials, that of the options if
+// any, or else the equivalent of bo.
+func (o *dialOptions) backoffStrategy(bo wait.Backoff) BackoffStrategy {
+	if o == nil || o.strategy == nil {
+		return NewExponentialBackoff(bo)
+	}
+	return o.strategy
+}
+
+// tlsConfig applies the options to the config of a TLS dial, which may be
+// modified in place.
+func (o *dialOptions) tlsConfig(conf *tls.Config) *tls.Config {
+	if o != nil && o.sessionCache != nil && conf.ClientSessionCache == nil {
+		conf.ClientSessionCache = o.sessionCache
+	}
+	return conf
+}
+
+// resolve returns the addresses to dial for address.
+func (o *dialOptions) resolve(ctx context.Context, address string) ([]string, error) {
+	if o == nil || o.dnsCache == nil {
+		return []string{address}, nil
+	}
+	return o.dnsCache.resolve(ctx, address)
+}
+
+// allowRetry returns whether the dial may be retried, consuming a token
+// from the retry budget if so.
+func (o *dialOptions) allowRetry() bool {
+	if o == nil || o.budget == nil {
+		return true
+	}
+	return o.budget.take()
+}
+
+// wrap applies the options to a dialed connection.
+func (o *dialOptions) wrap(c net.Conn) net.Conn {
+	if o == nil {
+		return c
+	}
+	return NewIdleTimeoutConn(c, o.idleTimeout)
+}
+
+// acquire blocks until the dial may proceed, and returns the function
+// releasing the acquired dial slot.
+func (o *dialOptions) acquire(ctx context.Context) (func(), error) {
+	if o == nil || o.sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case o.sem <- struct{}{}:
+		return func() { <-o.sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
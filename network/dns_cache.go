@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// DNSCache caches the addresses that host names resolve to for a short TTL,
+// sparing the dialers sharing it from resolving the same host on every dial.
+// The expired entries are evicted as other hosts are looked up, so the cache
+// only holds the hosts looked up recently. See WithDNSCache.
+type DNSCache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	// lookupHost resolves a host name, net.DefaultResolver.LookupHost by default.
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+
+	mu      sync.Mutex
+	entries map[string]dnsCacheEntry
+	// nextSweep is when the expired entries are next evicted.
+	nextSweep time.Time
+}
+
+type dnsCacheEntry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// NewDNSCache returns a DNSCache keeping the addresses a host resolves to for
+// ttl. When negativeTTL is positive, the host names found not to exist are
+// also remembered as such for negativeTTL. Other resolution errors, e.g.
+// timeouts, are never cached.
+func NewDNSCache(ttl, negativeTTL time.Duration) *DNSCache {
+	return &DNSCache{
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		lookupHost:  net.DefaultResolver.LookupHost,
+		entries:     map[string]dnsCacheEntry{},
+	}
+}
+
+// WithDNSCache makes the dialer resolve the host of the addresses it dials
+// through c, and dial the resolved addresses in turn across the attempts of
+// a dial. The same cache may be shared across several dialers.
+// By default, the host is resolved by every dial attempt.
+func WithDNSCache(c *DNSCache) DialOption {
+	return func(o *dialOptions) {
+		o.dnsCache = c
+	}
+}
+
+// lookup returns the addresses host resolves to, from the cache if they were
+// resolved within the TTL.
+func (c *DNSCache) lookup(ctx context.Context, host string) ([]string, error) {
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.addrs, e.err
+	}
+
+	addrs, err := c.lookupHost(ctx, host)
+	var ttl time.Duration
+	var errDNS *net.DNSError
+	switch {
+	case err == nil:
+		ttl = c.ttl
+	case errors.As(err, &errDNS) && errDNS.IsNotFound:
+		ttl = c.negativeTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !now.Before(c.nextSweep) {
+		c.sweep(now)
+	}
+	if ttl > 0 {
+		c.entries[host] = dnsCacheEntry{addrs: addrs, err: err, expires: now.Add(ttl)}
+	} else {
+		delete(c.entries, host)
+	}
+	return addrs, err
+}
+
+// sweep evicts the expired entries, so that the hosts which are no longer
+// looked up don't accumulate in the cache. It is called with mu held, at most
+// once per TTL.
+func (c *DNSCache) sweep(now time.Time) {
+	for host, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, host)
+		}
+	}
+	c.nextSweep = now.Add(c.ttl)
+}
+
+// resolve returns the addresses to dial for address, with its host resolved
+// through the cache unless it is an IP address.
+func (c *DNSCache) resolve(ctx context.Context, address string) ([]string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		// Let the dialer handle it as usual.
+		return []string{address}, nil
+	}
+	addrs, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return []string{address}, nil
+	}
+	resolved := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		resolved = append(resolved, net.JoinHostPort(addr, port))
+	}
+	return resolved, nil
+}
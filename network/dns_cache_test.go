@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// countingDNSCache returns a DNSCache resolving every host to the given
+// addresses, or failing with err, and a pointer to its number of lookups.
+func countingDNSCache(ttl, negativeTTL time.Duration, addrs []string, err error) (*DNSCache, *int) {
+	var lookups int
+	c := NewDNSCache(ttl, negativeTTL)
+	c.lookupHost = func(context.Context, string) ([]string, error) {
+		lookups++
+		return addrs, err
+	}
+	return c, &lookups
+}
+
+func TestDialWithDNSCache(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+	_, port, err := net.SplitHostPort(s.Listener.Addr().String())
+	if err != nil {
+		t.Fatal("SplitHostPort() =", err)
+	}
+	addr := net.JoinHostPort("example.invalid", port)
+
+	cache, lookups := countingDNSCache(time.Minute, 0, []string{"127.0.0.1"}, nil)
+	dial := NewBackoffDialer(backOffTemplate, WithDNSCache(cache))
+	for i := 0; i < 2; i++ {
+		c, err := dial(context.Background(), "tcp4", addr)
+		if err != nil {
+			t.Fatal("Dial error =", err)
+		}
+		c.Close()
+	}
+	if *lookups != 1 {
+		t.Errorf("Lookups = %d, want: 1", *lookups)
+	}
+
+	// IP addresses are dialed as is.
+	if c, err := dial(context.Background(), "tcp4", s.Listener.Addr().String()); err != nil {
+		t.Fatal("Dial error =", err)
+	} else {
+		c.Close()
+	}
+	if *lookups != 1 {
+		t.Errorf("Lookups = %d, want: 1", *lookups)
+	}
+}
+
+func TestDNSCacheExpiry(t *testing.T) {
+	cache, lookups := countingDNSCache(time.Millisecond, 0, []string{"127.0.0.1"}, nil)
+	if _, err := cache.resolve(context.Background(), "example.invalid:80"); err != nil {
+		t.Fatal("resolve() =", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	got, err := cache.resolve(context.Background(), "example.invalid:80")
+	if err != nil {
+		t.Fatal("resolve() =", err)
+	}
+	if want := "127.0.0.1:80"; len(got) != 1 || got[0] != want {
+		t.Errorf("resolve() = %v, want: [%s]", got, want)
+	}
+	if *lookups != 2 {
+		t.Errorf("Lookups = %d, want: 2", *lookups)
+	}
+}
+
+func TestDNSCacheEviction(t *testing.T) {
+	cache, _ := countingDNSCache(time.Millisecond, 0, []string{"127.0.0.1"}, nil)
+	for i := 0; i < 10; i++ {
+		if _, err := cache.resolve(context.Background(), fmt.Sprintf("host-%d.invalid:80", i)); err != nil {
+			t.Fatal("resolve() =", err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// The expired entries of the hosts no longer looked up are evicted.
+	if _, err := cache.resolve(context.Background(), "example.invalid:80"); err != nil {
+		t.Fatal("resolve() =", err)
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if got := len(cache.entries); got != 1 {
+		t.Errorf("Entries = %d, want: 1", got)
+	}
+}
+
+func TestDNSCacheNegative(t *testing.T) {
+	notFound := &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}
+	temporary := &net.DNSError{Err: "server misbehaving", Name: "example.invalid", IsTemporary: true}
+
+	tests := []struct {
+		name        string
+		negativeTTL time.Duration
+		err         error
+		wantLookups int
+	}{{
+		name:        "not found, cached",
+		negativeTTL: time.Minute,
+		err:         notFound,
+		wantLookups: 1,
+	}, {
+		name:        "not found, no negative caching",
+		err:         notFound,
+		wantLookups: 2,
+	}, {
+		name:        "temporary error, never cached",
+		negativeTTL: time.Minute,
+		err:         temporary,
+		wantLookups: 2,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache, lookups := countingDNSCache(time.Minute, test.negativeTTL, nil, test.err)
+			for i := 0; i < 2; i++ {
+				if _, err := cache.resolve(context.Background(), "example.invalid:80"); !errors.Is(err, test.err) {
+					t.Errorf("resolve() = %v, want: %v", err, test.err)
+				}
+			}
+			if *lookups != test.wantLookups {
+				t.Errorf("Lookups = %d, want: %d", *lookups, test.wantLookups)
+			}
+		})
+	}
+}
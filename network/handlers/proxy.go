@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"knative.dev/pkg/network"
+)
+
+// ProxyFlushInterval is the interval at which DrainingProxy flushes the
+// responses it streams, so that chunked responses and server-sent events reach
+// the clients as they are produced. Responses are never buffered as a whole.
+const ProxyFlushInterval = 50 * time.Millisecond
+
+// DrainingProxy is a reverse proxy to a single target, using the network
+// package's auto transport, that can be drained on shutdown.
+// Until Drain is called it responds to kubelet probes with a "200 OK", and
+// afterwards it fails probes and rejects new requests with a
+// "503 shutting down", while the requests already in flight complete.
+type DrainingProxy struct {
+	proxy *httputil.ReverseProxy
+
+	// mu guards draining, so that no request is added to inflight once
+	// draining has started.
+	mu       sync.RWMutex
+	draining bool
+	inflight sync.WaitGroup
+}
+
+// Ensure DrainingProxy implements http.Handler
+var _ http.Handler = (*DrainingProxy)(nil)
+
+// NewDrainingProxy creates a DrainingProxy forwarding requests to target.
+func NewDrainingProxy(logger *zap.SugaredLogger, target *url.URL) *DrainingProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = network.NewProxyAutoTransport(1000, 100)
+	proxy.ErrorHandler = Error(logger)
+	proxy.FlushInterval = ProxyFlushInterval
+	return &DrainingProxy{proxy: proxy}
+}
+
+// ServeHTTP implements http.Handler
+func (p *DrainingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !p.track() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer p.inflight.Done()
+
+	if network.IsKubeletProbe(r) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	p.proxy.ServeHTTP(w, r)
+}
+
+// track registers a request as in flight, unless the proxy is draining.
+func (p *DrainingProxy) track() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.draining {
+		return false
+	}
+	p.inflight.Add(1)
+	return true
+}
+
+// Drain makes the proxy reject new requests and fail probes, and blocks
+// until the requests in flight have completed or ctx is done.
+func (p *DrainingProxy) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	p.draining = true
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
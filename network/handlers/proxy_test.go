@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	logtesting "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/network"
+)
+
+func TestDrainingProxy(t *testing.T) {
+	received, release := make(chan struct{}), make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	target, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal("Parse() =", err)
+	}
+	proxy := NewDrainingProxy(logtesting.TestLogger(t), target)
+
+	probe := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(network.UserAgentKey, network.KubeProbeUAPrefix)
+		resp := httptest.NewRecorder()
+		proxy.ServeHTTP(resp, req)
+		return resp.Code
+	}
+	if got, want := probe(), http.StatusOK; got != want {
+		t.Errorf("Probe status = %d, want: %d", got, want)
+	}
+
+	inflight := make(chan int)
+	go func() {
+		resp := httptest.NewRecorder()
+		proxy.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+		inflight <- resp.Code
+	}()
+	<-received
+
+	drained := make(chan error)
+	go func() {
+		drained <- proxy.Drain(context.Background())
+	}()
+
+	// Once draining, new requests and probes are rejected.
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return probe() == http.StatusServiceUnavailable, nil
+	}); err != nil {
+		t.Fatal("Probes never started failing")
+	}
+	resp := httptest.NewRecorder()
+	proxy.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got, want := resp.Code, http.StatusServiceUnavailable; got != want {
+		t.Errorf("New request status = %d, want: %d", got, want)
+	}
+
+	select {
+	case err := <-drained:
+		t.Fatal("Drain returned with a request in flight, err =", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The in-flight request completes, which unblocks Drain.
+	close(release)
+	if got, want := <-inflight, http.StatusOK; got != want {
+		t.Errorf("In-flight request status = %d, want: %d", got, want)
+	}
+	if err := <-drained; err != nil {
+		t.Error("Drain() =", err)
+	}
+}
+
+func TestDrainingProxyStreams(t *testing.T) {
+	const (
+		chunkSize = 1 << 20
+		chunks    = 16
+	)
+	// The backend only writes a chunk once the client received the previous
+	// one, which would deadlock if the proxy buffered the response.
+	received := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := bytes.Repeat([]byte("x"), chunkSize)
+		for i := 0; i < chunks; i++ {
+			if _, err := w.Write(chunk); err != nil {
+				t.Error("Write() =", err)
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				t.Errorf("Chunk %d never reached the client", i)
+				return
+			}
+		}
+	}))
+	defer backend.Close()
+
+	target, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal("Parse() =", err)
+	}
+	server := httptest.NewServer(NewDrainingProxy(logtesting.TestLogger(t), target))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal("Get() =", err)
+	}
+	defer resp.Body.Close()
+	if resp.ContentLength != -1 {
+		t.Errorf("ContentLength = %d, want a chunked response", resp.ContentLength)
+	}
+
+	buf := make([]byte, chunkSize)
+	for i := 0; i < chunks; i++ {
+		if _, err := io.ReadFull(resp.Body, buf); err != nil {
+			t.Fatalf("Reading chunk %d: %v", i, err)
+		}
+		received <- struct{}{}
+	}
+	if n, err := io.Copy(io.Discard, resp.Body); err != nil || n != 0 {
+		t.Errorf("Trailing body = %d bytes, %v, want: none", n, err)
+	}
+}
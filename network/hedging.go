@@ -0,0 +1,103 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// NewHedgingTransport returns a transport that hedges the idempotent,
+// body-less requests sent through rt, i.e. GETs and HEADs: if the response
+// to such a request hasn't arrived after delay, a second, backup, request is
+// sent, and whichever response arrives first is returned while the other
+// request is canceled. All other requests are sent once, as is.
+func NewHedgingTransport(rt http.RoundTripper, delay time.Duration) http.RoundTripper {
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if !isHedgeable(r) {
+			return rt.RoundTrip(r)
+		}
+		return hedge(rt, r, delay)
+	})
+}
+
+// isHedgeable returns whether r can safely be sent twice.
+func isHedgeable(r *http.Request) bool {
+	return (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+		(r.Body == nil || r.Body == http.NoBody)
+}
+
+type hedgeResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+}
+
+func hedge(rt http.RoundTripper, r *http.Request, delay time.Duration) (*http.Response, error) {
+	// Buffered so that the attempts never block, even once we're done.
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	send := func() {
+		ctx, cancel := context.WithCancel(r.Context())
+		attempt := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := rt.RoundTrip(r.Clone(ctx))
+			results <- hedgeResult{attempt: attempt, resp: resp, err: err}
+		}()
+	}
+
+	send()
+	pending := 1
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			send()
+			pending++
+
+		case res := <-results:
+			pending--
+			if res.err != nil {
+				cancels[res.attempt]()
+				if pending > 0 {
+					// The other attempt may still succeed.
+					continue
+				}
+				return nil, res.err
+			}
+			for i, cancel := range cancels {
+				if i != res.attempt {
+					cancel()
+				}
+			}
+			if pending > 0 {
+				// Release the response of the canceled attempt, should it
+				// arrive nonetheless.
+				go func() {
+					if loser := <-results; loser.resp != nil {
+						loser.resp.Body.Close()
+					}
+				}()
+			}
+			res.resp.Body = &cancelOnCloseBody{ReadCloser: res.resp.Body, cancel: cancels[res.attempt]}
+			return res.resp, nil
+		}
+	}
+}
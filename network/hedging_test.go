@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgingTransport(t *testing.T) {
+	var requests int32
+	canceled := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// The first request is slow.
+			select {
+			case <-r.Context().Done():
+				close(canceled)
+			case <-time.After(10 * time.Second):
+			}
+			w.Write([]byte("slow"))
+			return
+		}
+		w.Write([]byte("fast"))
+	}))
+	t.Cleanup(s.Close)
+
+	transport := NewAutoTransport(10, 10, WithHedging(50*time.Millisecond))
+
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		t.Fatal("NewRequest() =", err)
+	}
+	start := time.Now()
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal("RoundTrip() =", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal("ReadAll() =", err)
+	}
+	if got, want := string(body), "fast"; got != want {
+		t.Errorf("Body = %q, want: %q", got, want)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("RoundTrip() took %v, wanted the hedge to win", elapsed)
+	}
+
+	// The losing request is canceled.
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Error("The slow request was not canceled")
+	}
+}
+
+func TestHedgingTransportNonIdempotent(t *testing.T) {
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(100 * time.Millisecond)
+	}))
+	t.Cleanup(s.Close)
+
+	transport := NewHedgingTransport(http.DefaultTransport, 10*time.Millisecond)
+
+	for _, test := range []struct {
+		method string
+		body   string
+	}{{
+		method: http.MethodPost,
+	}, {
+		method: http.MethodGet,
+		body:   "body",
+	}} {
+		req, err := http.NewRequest(test.method, s.URL, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal("NewRequest() =", err)
+		}
+		atomic.StoreInt32(&requests, 0)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal("RoundTrip() =", err)
+		}
+		resp.Body.Close()
+		if got := atomic.LoadInt32(&requests); got != 1 {
+			t.Errorf("%s with body %q: got %d requests, wanted 1", test.method, test.body, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrHostNotAllowed is matched by the errors returned by the transports
+// restricted to an allow-list of hosts for the requests for other hosts, see
+// NewAllowedHostsTransport.
+var ErrHostNotAllowed = errors.New("host not allowed")
+
+// HostRoute routes the requests for the hosts matching Pattern through
+// Transport, see NewHostRoutingTransport.
+type HostRoute struct {
+	// Pattern is either a host name, which matches that host only, or a
+	// wildcard like "*.internal", which matches the subdomains of the given
+	// domain, at any depth, but not the domain itself. Patterns are case
+	// insensitive and never include a port.
+	Pattern string
+
+	// Transport is the transport of the requests for the matching hosts,
+	// e.g. one returned by NewH2CTransport for the hosts that only speak
+	// h2c, or by NewAutoTransport with WithHTTP2Disabled for those that
+	// only speak HTTP/1.1.
+	Transport http.RoundTripper
+}
+
+// matches returns whether the route applies to the requests for host.
+func (r HostRoute) matches(host string) bool {
+	return matchesHost(r.Pattern, host)
+}
+
+// matchesHost returns whether the lowercase host matches pattern, see
+// HostRoute.Pattern.
+func matchesHost(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	if suffix := strings.TrimPrefix(pattern, "*"); suffix != pattern {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return host == pattern
+}
+
+// NewHostRoutingTransport returns a transport that sends each request
+// through the Transport of the first of the routes whose Pattern matches
+// the host of the request's URL, or through fallback if none does.
+// A nil fallback is AutoTransport, which picks the protocol based on the
+// request's HTTP version.
+func NewHostRoutingTransport(fallback http.RoundTripper, routes ...HostRoute) http.RoundTripper {
+	if fallback == nil {
+		fallback = AutoTransport
+	}
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		host := strings.ToLower(r.URL.Hostname())
+		for _, route := range routes {
+			if route.matches(host) {
+				return route.Transport.RoundTrip(r)
+			}
+		}
+		return fallback.RoundTrip(r)
+	})
+}
+
+// NewAllowedHostsTransport returns a transport that only sends the requests
+// for the hosts matching one of the patterns through rt, and fails the others
+// with ErrHostNotAllowed before anything is dialed, e.g. to enforce an egress
+// policy. The patterns are those of HostRoute.Pattern. A nil rt is
+// AutoTransport. See also WithAllowedHosts.
+func NewAllowedHostsTransport(rt http.RoundTripper, patterns ...string) http.RoundTripper {
+	if rt == nil {
+		rt = AutoTransport
+	}
+	return newAllowedHostsTransport(rt, patterns)
+}
+
+func newAllowedHostsTransport(rt http.RoundTripper, patterns []string) http.RoundTripper {
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		host := strings.ToLower(r.URL.Hostname())
+		for _, pattern := range patterns {
+			if matchesHost(pattern, host) {
+				return rt.RoundTrip(r)
+			}
+		}
+		// RoundTrip must close the body, even on errors.
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %q", ErrHostNotAllowed, host)
+	})
+}
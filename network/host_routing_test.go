@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHostRoutingTransport(t *testing.T) {
+	// Each transport responds with its own status code.
+	transport := func(code int) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: code, Body: http.NoBody, Request: r}, nil
+		})
+	}
+	const (
+		internal = http.StatusAccepted
+		exact    = http.StatusCreated
+		fallback = http.StatusOK
+	)
+	rt := NewHostRoutingTransport(transport(fallback),
+		HostRoute{Pattern: "*.internal", Transport: transport(internal)},
+		HostRoute{Pattern: "Example.com", Transport: transport(exact)},
+		// Shadowed by the first route.
+		HostRoute{Pattern: "*.svc.internal", Transport: transport(http.StatusTeapot)},
+	)
+
+	tests := []struct {
+		url  string
+		want int
+	}{{
+		url:  "http://foo.internal",
+		want: internal,
+	}, {
+		url:  "http://foo.bar.internal:8080/path",
+		want: internal,
+	}, {
+		url:  "http://FOO.INTERNAL",
+		want: internal,
+	}, {
+		url:  "http://foo.svc.internal",
+		want: internal,
+	}, {
+		url:  "http://internal",
+		want: fallback,
+	}, {
+		url:  "http://notinternal",
+		want: fallback,
+	}, {
+		url:  "http://example.com",
+		want: exact,
+	}, {
+		url:  "http://www.example.com",
+		want: fallback,
+	}, {
+		url:  "http://foo.internal.com",
+		want: fallback,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, test.url, nil)
+			if err != nil {
+				t.Fatal("NewRequest() =", err)
+			}
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatal("RoundTrip() =", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != test.want {
+				t.Errorf("Status = %d, want: %d", resp.StatusCode, test.want)
+			}
+		})
+	}
+}
+
+func TestAllowedHostsTransport(t *testing.T) {
+	var sent int
+	rt := NewAllowedHostsTransport(RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		sent++
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	}), "*.internal", "Example.com")
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{{
+		url:     "http://foo.internal",
+		allowed: true,
+	}, {
+		url:     "http://foo.bar.internal:8080/path",
+		allowed: true,
+	}, {
+		url:     "http://example.com",
+		allowed: true,
+	}, {
+		url: "http://internal",
+	}, {
+		url: "http://www.example.com",
+	}, {
+		url: "http://evil.com/foo.internal",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			sent = 0
+			req, err := http.NewRequest(http.MethodGet, test.url, nil)
+			if err != nil {
+				t.Fatal("NewRequest() =", err)
+			}
+			resp, err := rt.RoundTrip(req)
+			if test.allowed {
+				if err != nil {
+					t.Fatal("RoundTrip() =", err)
+				}
+				resp.Body.Close()
+			} else {
+				if !errors.Is(err, ErrHostNotAllowed) {
+					t.Fatalf("RoundTrip() = %v, want: %v", err, ErrHostNotAllowed)
+				}
+				if host := req.URL.Hostname(); !strings.Contains(err.Error(), host) {
+					t.Errorf("RoundTrip() = %v, wanted it to name the host %q", err, host)
+				}
+			}
+			want := 0
+			if test.allowed {
+				want = 1
+			}
+			if sent != want {
+				t.Errorf("Requests sent = %d, want: %d", sent, want)
+			}
+		})
+	}
+}
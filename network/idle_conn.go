@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// idleTimeoutConn is a net.Conn that closes itself once no reads or writes
+// happened on it for the idle timeout.
+type idleTimeoutConn struct {
+	net.Conn
+
+	timeout time.Duration
+	// lastActive is the UnixNano timestamp of the latest read or write.
+	lastActive int64
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+// NewIdleTimeoutConn wraps c so that it is closed after timeout elapses
+// without any reads or writes on it. A non-positive timeout returns c as is.
+func NewIdleTimeoutConn(c net.Conn, timeout time.Duration) net.Conn {
+	if timeout <= 0 {
+		return c
+	}
+	ic := &idleTimeoutConn{
+		Conn:       c,
+		timeout:    timeout,
+		lastActive: time.Now().UnixNano(),
+	}
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.timer = time.AfterFunc(timeout, ic.checkIdle)
+	return ic
+}
+
+// checkIdle closes the connection if it has been idle for the timeout and
+// otherwise checks again once the timeout could have elapsed.
+func (c *idleTimeoutConn) checkIdle() {
+	idle := time.Since(time.Unix(0, atomic.LoadInt64(&c.lastActive)))
+	if idle >= c.timeout {
+		c.Conn.Close()
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Reset(c.timeout - idle)
+	}
+}
+
+func (c *idleTimeoutConn) touch() {
+	atomic.StoreInt64(&c.lastActive, time.Now().UnixNano())
+}
+
+// Read implements net.Conn
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	c.touch()
+	n, err := c.Conn.Read(b)
+	c.touch()
+	return n, err
+}
+
+// Write implements net.Conn
+func (c *idleTimeoutConn) Write(b []byte) (int, error) {
+	c.touch()
+	n, err := c.Conn.Write(b)
+	c.touch()
+	return n, err
+}
+
+// Close implements net.Conn
+func (c *idleTimeoutConn) Close() error {
+	c.mu.Lock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.mu.Unlock()
+	return c.Conn.Close()
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+const idleTimeout = 50 * time.Millisecond
+
+func TestIdleTimeoutConnClosesIdle(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Listen() =", err)
+	}
+	defer l.Close()
+
+	dial := NewBackoffDialer(backOffTemplate, WithIdleTimeout(idleTimeout))
+	c, err := dial(context.Background(), "tcp4", l.Addr().String())
+	if err != nil {
+		t.Fatal("Dial error =", err)
+	}
+	defer c.Close()
+
+	server, err := l.Accept()
+	if err != nil {
+		t.Fatal("Accept() =", err)
+	}
+	defer server.Close()
+
+	// The server observes the connection being closed by the client.
+	server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	start := time.Now()
+	if _, err := server.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Fatalf("Read() = %v, wanted EOF", err)
+	}
+	if elapsed := time.Since(start); elapsed < idleTimeout {
+		t.Errorf("Connection closed after %v, before the idle timeout %v", elapsed, idleTimeout)
+	}
+}
+
+func TestIdleTimeoutConnStaysOpenWhenActive(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	c := NewIdleTimeoutConn(client, idleTimeout)
+	defer c.Close()
+
+	go io.Copy(io.Discard, server)
+
+	// Keep writing for several idle timeouts.
+	for deadline := time.Now().Add(4 * idleTimeout); time.Now().Before(deadline); {
+		if _, err := c.Write([]byte("ping")); err != nil {
+			t.Fatal("Write() =", err)
+		}
+		time.Sleep(idleTimeout / 5)
+	}
+
+	// Once idle, it gets closed.
+	time.Sleep(2 * idleTimeout)
+	if _, err := c.Write([]byte("ping")); err == nil {
+		t.Error("Expected the idle connection to be closed")
+	}
+}
+
+func TestIdleTimeoutConnDisabled(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	if got := NewIdleTimeoutConn(client, 0); got != client {
+		t.Errorf("NewIdleTimeoutConn() = %T, wanted the connection as is", got)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Call is a request sent through a FakeTransport.
+type Call struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// FakeTransport is an http.RoundTripper responding to the requests matching
+// its expectations with canned responses, without any network access, and
+// recording the requests it is sent. Requests matching no expectation fail.
+type FakeTransport struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+}
+
+// Expectation is a method and URL a FakeTransport responds to, see
+// FakeTransport.Expect.
+type Expectation struct {
+	method, url string
+
+	status int
+	body   string
+}
+
+// NewFakeTransport returns a FakeTransport without any expectations.
+func NewFakeTransport() *FakeTransport {
+	return &FakeTransport{}
+}
+
+// Expect registers an expectation for the requests with the given method
+// and URL, which are responded to as set by Respond on the returned
+// Expectation, or with 200 and no body by default. The URL is matched
+// against the full URL of the requests, e.g. "http://foo.bar/baz?q=1".
+// The earliest matching expectation is used.
+func (t *FakeTransport) Expect(method, url string) *Expectation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e := &Expectation{method: method, url: url, status: http.StatusOK}
+	t.expectations = append(t.expectations, e)
+	return e
+}
+
+// Respond sets the status code and body of the responses to the requests
+// matching the expectation. It must be called before sending them.
+func (e *Expectation) Respond(status int, body string) {
+	e.status, e.body = status, body
+}
+
+// Calls returns the requests sent through the transport so far, in order,
+// whether they matched an expectation or not.
+func (t *FakeTransport) Calls() []Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Call(nil), t.calls...)
+}
+
+var _ http.RoundTripper = (*FakeTransport)(nil)
+
+// RoundTrip implements http.RoundTripper.
+func (t *FakeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	call := Call{
+		Method: r.Method,
+		URL:    r.URL.String(),
+		Header: r.Header.Clone(),
+	}
+	if r.Body != nil {
+		body, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		call.Body = body
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = append(t.calls, call)
+	for _, e := range t.expectations {
+		if e.method == call.Method && e.url == call.URL {
+			return &http.Response{
+				Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+				StatusCode:    e.status,
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        make(http.Header),
+				Body:          ioutil.NopCloser(strings.NewReader(e.body)),
+				ContentLength: int64(len(e.body)),
+				Request:       r,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected request: %s %s", call.Method, call.URL)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFakeTransport(t *testing.T) {
+	transport := NewFakeTransport()
+	transport.Expect(http.MethodGet, "http://foo.bar/baz").Respond(http.StatusOK, "hello")
+	transport.Expect(http.MethodPost, "http://foo.bar/baz").Respond(http.StatusCreated, "created")
+	transport.Expect(http.MethodGet, "http://foo.bar/empty")
+	client := &http.Client{Transport: transport}
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		wantErr    bool
+		wantStatus int
+		wantBody   string
+	}{{
+		name:       "matched get",
+		method:     http.MethodGet,
+		url:        "http://foo.bar/baz",
+		wantStatus: http.StatusOK,
+		wantBody:   "hello",
+	}, {
+		name:       "matched post",
+		method:     http.MethodPost,
+		url:        "http://foo.bar/baz",
+		body:       "payload",
+		wantStatus: http.StatusCreated,
+		wantBody:   "created",
+	}, {
+		name:       "default response",
+		method:     http.MethodGet,
+		url:        "http://foo.bar/empty",
+		wantStatus: http.StatusOK,
+	}, {
+		name:    "unmatched method",
+		method:  http.MethodDelete,
+		url:     "http://foo.bar/baz",
+		wantErr: true,
+	}, {
+		name:    "unmatched url",
+		method:  http.MethodGet,
+		url:     "http://foo.bar/baz?q=1",
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal("NewRequest() =", err)
+			}
+			resp, err := client.Do(req)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Do() = %v, wanted error %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != test.wantStatus {
+				t.Errorf("StatusCode = %d, wanted %d", resp.StatusCode, test.wantStatus)
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal("ReadAll() =", err)
+			}
+			if got := string(body); got != test.wantBody {
+				t.Errorf("Body = %q, wanted %q", got, test.wantBody)
+			}
+		})
+	}
+
+	var got []string
+	for _, call := range transport.Calls() {
+		got = append(got, call.Method+" "+call.URL+" "+string(call.Body))
+	}
+	want := []string{
+		"GET http://foo.bar/baz ",
+		"POST http://foo.bar/baz payload",
+		"GET http://foo.bar/empty ",
+		"DELETE http://foo.bar/baz ",
+		"GET http://foo.bar/baz?q=1 ",
+	}
+	if !cmp.Equal(got, want) {
+		t.Error("Calls() (-want, +got) =", cmp.Diff(want, got))
+	}
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// defaultHTTP2ReadIdleTimeout is the default period after which idle HTTP/2
+// connections are health checked, so that half-open ones, e.g. after a
+// network partition, are detected and recycled rather than hanging requests.
+const defaultHTTP2ReadIdleTimeout = 30 * time.Second
+
+// TransportOption configures the transports returned by NewAutoTransport,
+// NewProxyAutoTransport and NewProxyAutoTLSTransport.
+type TransportOption func(*transportOptions)
+
+type transportOptions struct {
+	// disableHTTP2 forces all requests to be sent over HTTP/1.1.
+	disableHTTP2 bool
+
+	// strictMaxConcurrentStreams, readIdleTimeout and pingTimeout are
+	// applied to the HTTP/2 transport, see http2.Transport.
+	strictMaxConcurrentStreams bool
+	readIdleTimeout            time.Duration
+	pingTimeout                time.Duration
+
+	// totalTimeout bounds each request, from dialing to reading the
+	// response body.
+	totalTimeout time.Duration
+
+	// hedgeDelay, when positive, is how long to wait before hedging the
+	// idempotent requests.
+	hedgeDelay time.Duration
+
+	// decompress makes the transport decode the gzipped responses.
+	decompress bool
+
+	// allowedHosts, when non-nil, are the patterns of the only hosts the
+	// transport sends requests for.
+	allowedHosts []string
+}
+
+func newTransportOptions(opts []TransportOption) *transportOptions {
+	o := &transportOptions{
+		readIdleTimeout: defaultHTTP2ReadIdleTimeout,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithHTTP2Disabled makes the transport send all requests over HTTP/1.1,
+// never upgrading to HTTP/2 (or h2c), regardless of the protocol of the
+// request or what the server advertises. By default, the transport picks
+// the protocol based on the request's HTTP version.
+func WithHTTP2Disabled() TransportOption {
+	return func(o *transportOptions) {
+		o.disableHTTP2 = true
+	}
+}
+
+// WithHTTP2StrictMaxConcurrentStreams makes the HTTP/2 transport treat the
+// maximum number of concurrent streams advertised by the server as a limit
+// across all its connections to it. Requests beyond the limit wait for one
+// of the streams to complete. By default, the transport opens additional
+// connections to the server instead.
+func WithHTTP2StrictMaxConcurrentStreams() TransportOption {
+	return func(o *transportOptions) {
+		o.strictMaxConcurrentStreams = true
+	}
+}
+
+// WithHTTP2ReadIdleTimeout makes the HTTP/2 transport health check its
+// connections with pings once no frames were received on them for d.
+// A zero d disables the health checks. By default, connections are health
+// checked after being idle for 30 seconds.
+func WithHTTP2ReadIdleTimeout(d time.Duration) TransportOption {
+	return func(o *transportOptions) {
+		o.readIdleTimeout = d
+	}
+}
+
+// WithHTTP2PingTimeout sets how long the HTTP/2 transport waits for the
+// response to a health check ping before closing the connection. It only
+// matters if health checks are enabled with WithHTTP2ReadIdleTimeout.
+// By default, the timeout is 15 seconds.
+func WithHTTP2PingTimeout(d time.Duration) TransportOption {
+	return func(o *transportOptions) {
+		o.pingTimeout = d
+	}
+}
+
+// WithTotalTimeout bounds the whole of each request sent through the
+// transport to d, including the dial retries of the backoff dialer, the
+// exchange and reading the response body. The request is canceled once d
+// elapses, or the deadline of its context passes if that is earlier.
+// By default, requests are only bounded by their context.
+func WithTotalTimeout(d time.Duration) TransportOption {
+	return func(o *transportOptions) {
+		o.totalTimeout = d
+	}
+}
+
+// WithHedging makes the transport hedge GET and HEAD requests without a
+// body: a backup request is sent if no response arrived after d, and the
+// first response wins (see NewHedgingTransport). Other requests are never
+// duplicated. By default, requests are not hedged.
+func WithHedging(d time.Duration) TransportOption {
+	return func(o *transportOptions) {
+		o.hedgeDelay = d
+	}
+}
+
+// WithDecompression makes the transport transparently decode the responses
+// with a gzip Content-Encoding, dropping their Content-Encoding and
+// Content-Length headers, and ask for gzip when the request doesn't set an
+// Accept-Encoding itself. Go's transports only decode the responses when
+// they asked for gzip themselves, i.e. when the request doesn't set an
+// Accept-Encoding and DisableCompression is false. DisableCompression is
+// set on the transports returned by NewProxyAutoTransport and
+// NewProxyAutoTLSTransport, which therefore pass the responses through as
+// encoded by the upstream by default. This option has all the transports
+// decode them regardless.
+func WithDecompression() TransportOption {
+	return func(o *transportOptions) {
+		o.decompress = true
+	}
+}
+
+// WithAllowedHosts restricts the transport to the requests for the hosts
+// matching one of the patterns, failing the others with ErrHostNotAllowed
+// before anything is dialed, e.g. to enforce an egress policy (see
+// NewAllowedHostsTransport). By default, requests for any host are sent.
+func WithAllowedHosts(patterns ...string) TransportOption {
+	return func(o *transportOptions) {
+		o.allowedHosts = append([]string{}, patterns...)
+	}
+}
+
+// h2Transport applies the HTTP/2 options to the given transport.
+func (o *transportOptions) h2Transport(rt http.RoundTripper) http.RoundTripper {
+	if t, ok := rt.(*http2.Transport); ok {
+		t.StrictMaxConcurrentStreams = o.strictMaxConcurrentStreams
+		t.ReadIdleTimeout = o.readIdleTimeout
+		t.PingTimeout = o.pingTimeout
+	}
+	return rt
+}
+
+// autoTransport returns the transport for the options, see protocolTransport.
+func (o *transportOptions) autoTransport(v1 http.RoundTripper, v2 func() http.RoundTripper) http.RoundTripper {
+	rt := o.protocolTransport(v1, v2)
+	if o.decompress {
+		rt = newDecompressingTransport(rt)
+	}
+	if o.hedgeDelay > 0 {
+		rt = NewHedgingTransport(rt, o.hedgeDelay)
+	}
+	if o.totalTimeout > 0 {
+		// The timeout bounds the hedged requests altogether.
+		rt = newTotalTimeoutTransport(rt, o.totalTimeout)
+	}
+	if o.allowedHosts != nil {
+		// Fail the disallowed requests before any of the above kicks in.
+		rt = newAllowedHostsTransport(rt, o.allowedHosts)
+	}
+	return rt
+}
+
+// protocolTransport returns the transport that uses v1 or v2 based on the
+// request's HTTP version, or v1 only if HTTP/2 is disabled.
+func (o *transportOptions) protocolTransport(v1 http.RoundTripper, v2 func() http.RoundTripper) http.RoundTripper {
+	if !o.disableHTTP2 {
+		return newAutoTransport(v1, o.h2Transport(v2()))
+	}
+	if t, ok := v1.(*http.Transport); ok {
+		t.ForceAttemptHTTP2 = false
+		// A non-nil, empty map disables HTTP/2 negotiation over TLS.
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		if t.TLSClientConfig != nil {
+			// Don't offer h2 during the TLS handshake either, we could not speak it.
+			tlsConf := t.TLSClientConfig.Clone()
+			tlsConf.NextProtos = nil
+			for _, proto := range t.TLSClientConfig.NextProtos {
+				if proto != http2.NextProtoTLS {
+					tlsConf.NextProtos = append(tlsConf.NextProtos, proto)
+				}
+			}
+			t.TLSClientConfig = tlsConf
+		}
+	}
+	return v1
+}
+
+// newTotalTimeoutTransport returns a transport that cancels the requests
+// sent through rt once d elapses, until their response body is closed.
+func newTotalTimeoutTransport(rt http.RoundTripper, d time.Duration) http.RoundTripper {
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		resp, err := rt.RoundTrip(r.WithContext(ctx))
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	})
+}
+
+// cancelOnCloseBody releases the context of a request once its response
+// body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements io.Closer.
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// newDecompressingTransport returns a transport that decodes the gzipped
+// responses of rt, see WithDecompression.
+func newDecompressingTransport(rt http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		// As Go's transports do, don't ask for gzip with range requests, whose
+		// ranges would apply to the encoded body.
+		if r.Header.Get("Accept-Encoding") == "" && r.Header.Get("Range") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Accept-Encoding", "gzip")
+		}
+		resp, err := rt.RoundTrip(r)
+		if err != nil || resp.Uncompressed || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			return resp, err
+		}
+		resp.Body = &gzipBody{body: resp.Body}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+		return resp, nil
+	})
+}
+
+// gzipBody decodes a gzipped response body, lazily so that the empty bodies
+// of e.g. the responses to HEAD requests aren't read.
+type gzipBody struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+	err  error
+}
+
+// Read implements io.Reader.
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.zr == nil && b.err == nil {
+		b.zr, b.err = gzip.NewReader(b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.zr.Read(p)
+}
+
+// Close implements io.Closer.
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"syscall"
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -62,9 +63,10 @@ var DialWithBackOff = NewBackoffDialer(backOffTemplate)
 // NewBackoffDialer returns a dialer that executes `net.Dialer.DialContext()` with
 // exponentially increasing dial timeouts. In addition it sleeps with random jitter
 // between tries.
-func NewBackoffDialer(backoffConfig wait.Backoff) func(context.Context, string, string) (net.Conn, error) {
+func NewBackoffDialer(backoffConfig wait.Backoff, opts ...DialOption) func(context.Context, string, string) (net.Conn, error) {
+	o := newDialOptions(opts)
 	return func(ctx context.Context, network, address string) (net.Conn, error) {
-		return dialBackOffHelper(ctx, network, address, backoffConfig, nil)
+		return dialBackOffHelper(ctx, network, address, backoffConfig, nil, o)
 	}
 }
 
@@ -72,45 +74,174 @@ func NewBackoffDialer(backoffConfig wait.Backoff) func(context.Context, string,
 var DialTLSWithBackOff = NewTLSBackoffDialer(backOffTemplate)
 
 // NewTLSBackoffDialer is same with NewBackoffDialer but takes tls config.
-func NewTLSBackoffDialer(backoffConfig wait.Backoff) func(context.Context, string, string, *tls.Config) (net.Conn, error) {
+func NewTLSBackoffDialer(backoffConfig wait.Backoff, opts ...DialOption) func(context.Context, string, string, *tls.Config) (net.Conn, error) {
+	o := newDialOptions(opts)
 	return func(ctx context.Context, network, address string, tlsConf *tls.Config) (net.Conn, error) {
-		return dialBackOffHelper(ctx, network, address, backoffConfig, tlsConf)
+		return dialBackOffHelper(ctx, network, address, backoffConfig, tlsConf, o)
 	}
 }
 
-func dialBackOffHelper(ctx context.Context, network, address string, bo wait.Backoff, tlsConf *tls.Config) (net.Conn, error) {
+type serverNameKey struct{}
+
+// WithServerName returns a context instructing the TLS dialers, i.e.
+// DialTLSWithBackOff and those returned by NewTLSBackoffDialer, to use
+// serverName for SNI and certificate verification of the dials made with it,
+// rather than the ServerName of the tls.Config they are given. This allows
+// dialing a single address for several logical services with a shared config.
+func WithServerName(ctx context.Context, serverName string) context.Context {
+	return context.WithValue(ctx, serverNameKey{}, serverName)
+}
+
+type dialAttemptsReporterKey struct{}
+
+// WithDialAttemptsReporter returns a context instructing the backoff dialers
+// to report the number of attempts each of the dials made with it took,
+// whether it succeeded or not, e.g. to log dials that only connected after
+// several retries.
+func WithDialAttemptsReporter(ctx context.Context, report func(attempts int)) context.Context {
+	return context.WithValue(ctx, dialAttemptsReporterKey{}, report)
+}
+
+// tlsConfigForDial returns a copy of tlsConf with the derived fields set
+// for dialing address with ctx.
+func tlsConfigForDial(ctx context.Context, address string, tlsConf *tls.Config) *tls.Config {
+	conf := tlsConf.Clone()
+	if serverName, ok := ctx.Value(serverNameKey{}).(string); ok && serverName != "" {
+		conf.ServerName = serverName
+	} else if conf.ServerName == "" {
+		// Same as tls.Dial, infer the server name from the address.
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		conf.ServerName = host
+	}
+	return conf
+}
+
+var (
+	// ErrDialTimeout is matched by the errors returned by the backoff dialers
+	// when all the dial attempts timed out.
+	ErrDialTimeout = errors.New("timed out dialing")
+
+	// ErrConnectionRefused is matched by the errors returned by the backoff
+	// dialers when the connection was refused.
+	ErrConnectionRefused = errors.New("connection refused")
+
+	// ErrRetryBudgetExhausted is matched by the errors returned by the
+	// backoff dialers when a dial attempt timed out, but the retry budget
+	// did not allow retrying it (see WithRetryBudget).
+	ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+)
+
+// dialError is returned by the backoff dialers. It matches one of the
+// sentinel errors above with errors.Is, while still wrapping the underlying
+// error, typically a *net.OpError, for errors.As.
+type dialError struct {
+	sentinel error
+	msg      string
+	err      error
+}
+
+// Error implements error.
+func (e *dialError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the underlying dial error.
+func (e *dialError) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is the sentinel error describing this error.
+func (e *dialError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+func dialBackOffHelper(ctx context.Context, network, address string, bo wait.Backoff, tlsConf *tls.Config, opts *dialOptions) (net.Conn, error) {
+	release, err := opts.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if tlsConf != nil {
+		// Never mutate the caller's config, which may be shared across dials.
+		// The copy shares its ClientSessionCache, so sessions are resumed.
+		tlsConf = opts.tlsConfig(tlsConfigForDial(ctx, address, tlsConf))
+	}
+	addrs, err := opts.resolve(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy := opts.backoffStrategy(bo)
+	timeout, _ := strategy.NextDelay(1)
 	dialer := &net.Dialer{
-		Timeout:   bo.Duration, // Initial duration.
+		Timeout:   timeout,
 		KeepAlive: 5 * time.Second,
 		DualStack: true,
 	}
+	attempts := 0
+	if report, ok := ctx.Value(dialAttemptsReporterKey{}).(func(int)); ok {
+		defer func() { report(attempts) }()
+	}
+
 	start := time.Now()
+	var lastErr error
 	for {
 		var (
 			c   net.Conn
 			err error
 		)
+		// Rotate through the resolved addresses across the attempts.
+		addr := addrs[attempts%len(addrs)]
+		attempts++
 		if tlsConf == nil {
-			c, err = dialer.DialContext(ctx, network, address)
+			c, err = dialer.DialContext(ctx, network, addr)
 		} else {
-			c, err = tls.DialWithDialer(dialer, network, address, tlsConf)
+			c, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConf}).DialContext(ctx, network, addr)
 		}
 		if err != nil {
+			if ctx.Err() != nil {
+				// The caller gave up, don't retry.
+				return nil, err
+			}
 			var errNet net.Error
 			if errors.As(err, &errNet) && errNet.Timeout() {
-				if bo.Steps < 1 {
+				lastErr = err
+				timeout, ok := strategy.NextDelay(attempts + 1)
+				if !ok {
 					break
 				}
-				dialer.Timeout = bo.Step()
-				time.Sleep(wait.Jitter(sleep, 1.0)) // Sleep with jitter.
+				if !opts.allowRetry() {
+					return nil, &dialError{
+						sentinel: ErrRetryBudgetExhausted,
+						msg:      fmt.Sprintf("retry budget exhausted dialing after %d attempt(s): %v", attempts, err),
+						err:      err,
+					}
+				}
+				dialer.Timeout = timeout
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(wait.Jitter(sleep, 1.0)): // Sleep with jitter.
+				}
 				continue
 			}
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				return nil, &dialError{sentinel: ErrConnectionRefused, msg: err.Error(), err: err}
+			}
 			return nil, err
 		}
-		return c, nil
+		return opts.wrap(c), nil
 	}
 	elapsed := time.Since(start)
-	return nil, fmt.Errorf("timed out dialing after %.2fs", elapsed.Seconds())
+	return nil, &dialError{
+		sentinel: ErrDialTimeout,
+		msg:      fmt.Sprintf("timed out dialing after %.2fs", elapsed.Seconds()),
+		err:      lastErr,
+	}
 }
 
 func newHTTPTransport(disableKeepAlives, disableCompression bool, maxIdle, maxIdlePerHost int) http.RoundTripper {
@@ -146,27 +277,29 @@ func NewProberTransport() http.RoundTripper {
 }
 
 // NewProxyAutoTLSTransport is same with NewProxyAutoTransport but it has tls.Config to create HTTPS request.
-func NewProxyAutoTLSTransport(maxIdle, maxIdlePerHost int, tlsConf *tls.Config) http.RoundTripper {
-	return newAutoTransport(
+func NewProxyAutoTLSTransport(maxIdle, maxIdlePerHost int, tlsConf *tls.Config, opts ...TransportOption) http.RoundTripper {
+	return newTransportOptions(opts).autoTransport(
 		newHTTPSTransport(false /*disable keep-alives*/, true /*disable auto-compression*/, maxIdle, maxIdlePerHost, tlsConf),
-		newH2Transport(true /*disable auto-compression*/, tlsConf))
+		func() http.RoundTripper { return newH2Transport(true /*disable auto-compression*/, tlsConf) })
 }
 
 // NewAutoTransport creates a RoundTripper that can use appropriate transport
 // based on the request's HTTP version.
-func NewAutoTransport(maxIdle, maxIdlePerHost int) http.RoundTripper {
-	return newAutoTransport(
+func NewAutoTransport(maxIdle, maxIdlePerHost int, opts ...TransportOption) http.RoundTripper {
+	return newTransportOptions(opts).autoTransport(
 		newHTTPTransport(false /*disable keep-alives*/, false /*disable auto-compression*/, maxIdle, maxIdlePerHost),
-		newH2CTransport(false /*disable auto-compression*/))
+		func() http.RoundTripper { return newH2CTransport(false /*disable auto-compression*/) })
 }
 
 // NewProxyAutoTransport creates a RoundTripper suitable for use by a reverse
 // proxy.  The returned transport uses HTTP or H2C based on the request's HTTP
-// version. The transport has DisableCompression set to true.
-func NewProxyAutoTransport(maxIdle, maxIdlePerHost int) http.RoundTripper {
-	return newAutoTransport(
+// version. The transport has DisableCompression set to true, so the responses
+// are passed through as encoded by the upstream unless WithDecompression is
+// given.
+func NewProxyAutoTransport(maxIdle, maxIdlePerHost int, opts ...TransportOption) http.RoundTripper {
+	return newTransportOptions(opts).autoTransport(
 		newHTTPTransport(false /*disable keep-alives*/, true /*disable auto-compression*/, maxIdle, maxIdlePerHost),
-		newH2CTransport(true /*disable auto-compression*/))
+		func() http.RoundTripper { return newH2CTransport(true /*disable auto-compression*/) })
 }
 
 // AutoTransport uses h2c for HTTP2 requests and falls back to `http.DefaultTransport` for all others
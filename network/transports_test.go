@@ -17,16 +17,27 @@ limitations under the License.
 package network
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 const (
@@ -76,17 +87,470 @@ func TestHTTPRoundTripper(t *testing.T) {
 	}
 }
 
+func TestTransportWithHTTP2Disabled(t *testing.T) {
+	protoHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	h2cServer := httptest.NewServer(h2c.NewHandler(protoHandler, &http2.Server{}))
+	t.Cleanup(h2cServer.Close)
+
+	h2Server := httptest.NewUnstartedServer(protoHandler)
+	h2Server.EnableHTTP2 = true
+	h2Server.StartTLS()
+	t.Cleanup(h2Server.Close)
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(h2Server.Certificate())
+	tlsConf := &tls.Config{RootCAs: rootCAs, NextProtos: []string{"h2", "http/1.1"}}
+
+	tests := []struct {
+		name      string
+		transport http.RoundTripper
+		url       string
+		want      string
+	}{{
+		name:      "h2c by default",
+		transport: NewAutoTransport(10, 10),
+		url:       h2cServer.URL,
+		want:      "HTTP/2.0",
+	}, {
+		name:      "h2c disabled",
+		transport: NewAutoTransport(10, 10, WithHTTP2Disabled()),
+		url:       h2cServer.URL,
+		want:      "HTTP/1.1",
+	}, {
+		name:      "proxy h2c disabled",
+		transport: NewProxyAutoTransport(10, 10, WithHTTP2Disabled()),
+		url:       h2cServer.URL,
+		want:      "HTTP/1.1",
+	}, {
+		name:      "h2 by default",
+		transport: NewProxyAutoTLSTransport(10, 10, tlsConf),
+		url:       h2Server.URL,
+		want:      "HTTP/2.0",
+	}, {
+		name:      "h2 disabled",
+		transport: NewProxyAutoTLSTransport(10, 10, tlsConf, WithHTTP2Disabled()),
+		url:       h2Server.URL,
+		want:      "HTTP/1.1",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, test.url, nil)
+			if err != nil {
+				t.Fatal("NewRequest() =", err)
+			}
+			// Requests forwarded by a proxy carry the protocol they were received with.
+			req.ProtoMajor, req.ProtoMinor = 2, 0
+
+			resp, err := test.transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal("RoundTrip() =", err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal("ReadAll() =", err)
+			}
+			if got := string(body); got != test.want {
+				t.Errorf("Server saw protocol %q, wanted %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTransportWithTotalTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	t.Cleanup(slow.Close)
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(fast.Close)
+
+	// Each dial attempt times out, as it can't connect within a nanosecond,
+	// and is retried for much longer than the total timeout.
+	retrying := http.DefaultTransport.(*http.Transport).Clone()
+	retrying.DialContext = NewBackoffDialer(wait.Backoff{Duration: time.Nanosecond, Factor: 1, Steps: 1000})
+
+	const timeout = 200 * time.Millisecond
+	tests := []struct {
+		name      string
+		transport http.RoundTripper
+		url       string
+	}{{
+		name:      "mid-retry",
+		transport: newTotalTimeoutTransport(retrying, timeout),
+		url:       fast.URL,
+	}, {
+		name:      "slow response",
+		transport: NewAutoTransport(10, 10, WithTotalTimeout(timeout)),
+		url:       slow.URL,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, test.url, nil)
+			if err != nil {
+				t.Fatal("NewRequest() =", err)
+			}
+			start := time.Now()
+			resp, err := test.transport.RoundTrip(req)
+			if err == nil {
+				resp.Body.Close()
+				t.Fatal("RoundTrip() succeeded, wanted it to time out")
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("RoundTrip() = %v, want: %v", err, context.DeadlineExceeded)
+			}
+			if elapsed := time.Since(start); elapsed > 5*timeout {
+				t.Errorf("RoundTrip() took %v, wanted it aborted after %v", elapsed, timeout)
+			}
+		})
+	}
+
+	t.Run("within the timeout", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, fast.URL, nil)
+		if err != nil {
+			t.Fatal("NewRequest() =", err)
+		}
+		resp, err := NewAutoTransport(10, 10, WithTotalTimeout(timeout)).RoundTrip(req)
+		if err != nil {
+			t.Fatal("RoundTrip() =", err)
+		}
+		defer resp.Body.Close()
+		// The body is still readable once RoundTrip returned.
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal("ReadAll() =", err)
+		}
+		if got, want := string(body), "ok"; got != want {
+			t.Errorf("Body = %q, want: %q", got, want)
+		}
+	})
+}
+
+func TestTransportWithAllowedHosts(t *testing.T) {
+	var served int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}))
+	t.Cleanup(s.Close)
+
+	tests := []struct {
+		name    string
+		allowed []string
+		wantErr error
+	}{{
+		name:    "allowed",
+		allowed: []string{"*.example.com", "127.0.0.1"},
+	}, {
+		name:    "disallowed",
+		allowed: []string{"*.example.com"},
+		wantErr: ErrHostNotAllowed,
+	}, {
+		name:    "nothing allowed",
+		wantErr: ErrHostNotAllowed,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			served = 0
+			rt := NewAutoTransport(10, 10, WithAllowedHosts(test.allowed...))
+			req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+			if err != nil {
+				t.Fatal("NewRequest() =", err)
+			}
+			resp, err := rt.RoundTrip(req)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("RoundTrip() = %v, want: %v", err, test.wantErr)
+			}
+			if err == nil {
+				resp.Body.Close()
+			}
+			want := 0
+			if test.wantErr == nil {
+				want = 1
+			}
+			if served != want {
+				t.Errorf("Requests served = %d, want: %d", served, want)
+			}
+		})
+	}
+}
+
+func TestTransportWithDecompression(t *testing.T) {
+	const want = "a gzipped response"
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write([]byte(want))
+	zw.Close()
+
+	// The server gzips its responses whatever the request accepts.
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gzipped.Bytes())
+	}))
+	t.Cleanup(s.Close)
+
+	tests := []struct {
+		name           string
+		transport      http.RoundTripper
+		acceptEncoding string
+	}{{
+		name:      "auto transport",
+		transport: NewAutoTransport(10, 10, WithDecompression()),
+	}, {
+		name:           "auto transport, explicit Accept-Encoding",
+		transport:      NewAutoTransport(10, 10, WithDecompression()),
+		acceptEncoding: "gzip",
+	}, {
+		name:      "proxy transport, compression disabled",
+		transport: NewProxyAutoTransport(10, 10, WithDecompression()),
+	}, {
+		name:           "proxy transport, explicit Accept-Encoding",
+		transport:      NewProxyAutoTransport(10, 10, WithDecompression()),
+		acceptEncoding: "gzip, deflate",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+			if err != nil {
+				t.Fatal("NewRequest() =", err)
+			}
+			if test.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", test.acceptEncoding)
+			}
+			resp, err := test.transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal("RoundTrip() =", err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal("ReadAll() =", err)
+			}
+			if got := string(body); got != want {
+				t.Errorf("Body = %q, want: %q", got, want)
+			}
+			if got := resp.Header.Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding = %q, wanted it dropped", got)
+			}
+			if !resp.Uncompressed {
+				t.Error("Uncompressed = false, want: true")
+			}
+		})
+	}
+
+	t.Run("proxy transport, no decompression", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+		if err != nil {
+			t.Fatal("NewRequest() =", err)
+		}
+		resp, err := NewProxyAutoTransport(10, 10).RoundTrip(req)
+		if err != nil {
+			t.Fatal("RoundTrip() =", err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal("ReadAll() =", err)
+		}
+		if !bytes.Equal(body, gzipped.Bytes()) {
+			t.Errorf("Body = %q, wanted the gzipped response as is", body)
+		}
+	})
+}
+
+func TestTransportWithHTTP2Settings(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []TransportOption
+		want *http2.Transport
+	}{{
+		name: "defaults",
+		want: &http2.Transport{
+			ReadIdleTimeout: defaultHTTP2ReadIdleTimeout,
+		},
+	}, {
+		name: "health checks disabled",
+		opts: []TransportOption{WithHTTP2ReadIdleTimeout(0)},
+		want: &http2.Transport{},
+	}, {
+		name: "configured",
+		opts: []TransportOption{
+			WithHTTP2StrictMaxConcurrentStreams(),
+			WithHTTP2ReadIdleTimeout(30 * time.Second),
+			WithHTTP2PingTimeout(5 * time.Second),
+		},
+		want: &http2.Transport{
+			StrictMaxConcurrentStreams: true,
+			ReadIdleTimeout:            30 * time.Second,
+			PingTimeout:                5 * time.Second,
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h2 := newH2CTransport(false).(*http2.Transport)
+			newTransportOptions(test.opts).autoTransport(
+				newHTTPTransport(false, false, 10, 10),
+				func() http.RoundTripper { return h2 })
+
+			if got, want := h2.StrictMaxConcurrentStreams, test.want.StrictMaxConcurrentStreams; got != want {
+				t.Errorf("StrictMaxConcurrentStreams = %v, want: %v", got, want)
+			}
+			if got, want := h2.ReadIdleTimeout, test.want.ReadIdleTimeout; got != want {
+				t.Errorf("ReadIdleTimeout = %v, want: %v", got, want)
+			}
+			if got, want := h2.PingTimeout, test.want.PingTimeout; got != want {
+				t.Errorf("PingTimeout = %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
+// droppingProxy forwards connections to target, until dropAll silently
+// drops its current connections: data sent on them is discarded, but they
+// are not closed, as happens on a network partition.
+type droppingProxy struct {
+	net.Listener
+	target string
+
+	mu      sync.Mutex
+	dropped []*int32
+	accepts int
+	closed  chan struct{}
+}
+
+func newDroppingProxy(t *testing.T, target string) *droppingProxy {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Listen() =", err)
+	}
+	p := &droppingProxy{Listener: l, target: target, closed: make(chan struct{}, 10)}
+	t.Cleanup(func() { l.Close() })
+	go p.serve()
+	return p
+}
+
+func (p *droppingProxy) serve() {
+	for {
+		c, err := p.Accept()
+		if err != nil {
+			return
+		}
+		upstream, err := net.Dial("tcp4", p.target)
+		if err != nil {
+			c.Close()
+			continue
+		}
+		dropped := new(int32)
+		p.mu.Lock()
+		p.accepts++
+		p.dropped = append(p.dropped, dropped)
+		p.mu.Unlock()
+
+		go func() {
+			p.forward(upstream, c, dropped)
+			p.closed <- struct{}{}
+		}()
+		go p.forward(c, upstream, dropped)
+	}
+}
+
+func (p *droppingProxy) forward(dst, src net.Conn, dropped *int32) {
+	defer dst.Close()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if err != nil {
+			return
+		}
+		if atomic.LoadInt32(dropped) == 0 {
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (p *droppingProxy) dropAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, dropped := range p.dropped {
+		atomic.StoreInt32(dropped, 1)
+	}
+}
+
+func (p *droppingProxy) acceptCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.accepts
+}
+
+func TestTransportDetectsDroppedConnection(t *testing.T) {
+	s := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}), &http2.Server{}))
+	t.Cleanup(s.Close)
+	proxy := newDroppingProxy(t, strings.TrimPrefix(s.URL, "http://"))
+
+	transport := NewAutoTransport(10, 10,
+		WithHTTP2ReadIdleTimeout(50*time.Millisecond),
+		WithHTTP2PingTimeout(50*time.Millisecond))
+	get := func() {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "http://"+proxy.Addr().String(), nil)
+		if err != nil {
+			t.Fatal("NewRequest() =", err)
+		}
+		req.ProtoMajor, req.ProtoMinor = 2, 0
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal("RoundTrip() =", err)
+		}
+		defer resp.Body.Close()
+		if _, err := ioutil.ReadAll(resp.Body); err != nil {
+			t.Fatal("ReadAll() =", err)
+		}
+	}
+
+	get()
+	proxy.dropAll()
+
+	// The health check fails and the transport closes the connection.
+	select {
+	case <-proxy.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The dropped connection was never closed")
+	}
+
+	// The next request redials rather than hanging on the dropped connection.
+	get()
+	if got, want := proxy.acceptCount(), 2; got != want {
+		t.Errorf("Connections = %d, want: %d", got, want)
+	}
+}
+
 func TestDialWithBackoff(t *testing.T) {
 	// Make the test short.
 	bo := backOffTemplate
 	bo.Steps = 2
 
 	// Nobody's listening on a random port. Usually.
-	c, err := dialBackOffHelper(context.Background(), "tcp4", "127.0.0.1:41482", bo, nil)
+	c, err := dialBackOffHelper(context.Background(), "tcp4", "127.0.0.1:41482", bo, nil, nil)
 	verifyFailedConnection(t, c, err, connectionRefusedErr)
 
 	// Timeout. Use special testing IP address.
-	c, err = dialBackOffHelper(context.Background(), "tcp4", "198.18.0.254:8888", bo, nil)
+	c, err = dialBackOffHelper(context.Background(), "tcp4", "198.18.0.254:8888", bo, nil, nil)
 	verifyFailedConnection(t, c, err, timeoutErr)
 
 	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
@@ -111,11 +575,11 @@ func TestDialTLSWithBackoff(t *testing.T) {
 	}
 
 	// Nobody's listening on a random port. Usually.
-	c, err := dialBackOffHelper(context.Background(), "tcp4", "127.0.0.1:41482", bo, tlsConf)
+	c, err := dialBackOffHelper(context.Background(), "tcp4", "127.0.0.1:41482", bo, tlsConf, nil)
 	verifyFailedConnection(t, c, err, connectionRefusedErr)
 
 	// Timeout. Use special testing IP address.
-	c, err = dialBackOffHelper(context.Background(), "tcp4", "198.18.0.254:8888", bo, tlsConf)
+	c, err = dialBackOffHelper(context.Background(), "tcp4", "198.18.0.254:8888", bo, tlsConf, nil)
 	verifyFailedConnection(t, c, err, timeoutErr)
 
 	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
@@ -132,6 +596,269 @@ func TestDialTLSWithBackoff(t *testing.T) {
 	c.Close()
 }
 
+func TestDialTLSWithServerName(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		serverNames []string
+	)
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	s.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			serverNames = append(serverNames, hello.ServerName)
+			return nil, nil
+		},
+	}
+	s.StartTLS()
+	defer s.Close()
+
+	// The config is shared by the dials to the different logical services.
+	tlsConf := &tls.Config{
+		InsecureSkipVerify: true, //nolint:gosec // The test server's certificate doesn't cover the names.
+		MinVersion:         tls.VersionTLS12,
+	}
+	address := strings.TrimPrefix(s.URL, "https://")
+	for _, serverName := range []string{"foo.example.com", "bar.example.com"} {
+		c, err := DialTLSWithBackOff(WithServerName(context.Background(), serverName), "tcp4", address, tlsConf)
+		if err != nil {
+			t.Fatal("Dial error =", err)
+		}
+		if got := c.(*tls.Conn).ConnectionState().ServerName; got != serverName {
+			t.Errorf("ServerName = %q, wanted %q", got, serverName)
+		}
+		c.Close()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"foo.example.com", "bar.example.com"}; !cmp.Equal(serverNames, want) {
+		t.Errorf("Server saw SNI %v, wanted %v", serverNames, want)
+	}
+	if tlsConf.ServerName != "" {
+		t.Errorf("The shared config was mutated, ServerName = %q", tlsConf.ServerName)
+	}
+}
+
+func TestDialTLSWithSessionResumption(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(s.Certificate())
+	address := strings.TrimPrefix(s.URL, "https://")
+
+	tests := []struct {
+		name  string
+		dial  func(context.Context, string, string, *tls.Config) (net.Conn, error)
+		cache tls.ClientSessionCache
+	}{{
+		name:  "cache of the config",
+		dial:  DialTLSWithBackOff,
+		cache: tls.NewLRUClientSessionCache(0),
+	}, {
+		name: "cache of the dialer",
+		dial: NewTLSBackoffDialer(backOffTemplate, WithTLSSessionCache(nil)),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tlsConf := &tls.Config{
+				RootCAs:            rootCAs,
+				ServerName:         "example.com",
+				ClientSessionCache: test.cache,
+				MinVersion:         tls.VersionTLS12,
+				// TLS 1.3 tickets are only received when reading from the
+				// connection, TLS 1.2 ones are part of the handshake.
+				MaxVersion: tls.VersionTLS12,
+			}
+			for i, wantResumed := range []bool{false, true} {
+				c, err := test.dial(context.Background(), "tcp4", address, tlsConf)
+				if err != nil {
+					t.Fatal("Dial error =", err)
+				}
+				if got := c.(*tls.Conn).ConnectionState().DidResume; got != wantResumed {
+					t.Errorf("Dial #%d DidResume = %v, wanted %v", i+1, got, wantResumed)
+				}
+				c.Close()
+			}
+			if tlsConf.ClientSessionCache != test.cache {
+				t.Error("The caller's config was mutated")
+			}
+		})
+	}
+}
+
+func TestDialErrors(t *testing.T) {
+	// Grab a port that nobody listens on.
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Listen() =", err)
+	}
+	refusedAddr := l.Addr().String()
+	l.Close()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		bo      wait.Backoff
+		address string
+		want    error
+	}{{
+		name:    "connection refused",
+		bo:      backOffTemplate,
+		address: refusedAddr,
+		want:    ErrConnectionRefused,
+	}, {
+		name: "timeout",
+		// Dial attempts can't succeed within a nanosecond.
+		bo:      wait.Backoff{Duration: time.Nanosecond, Steps: 2},
+		address: strings.TrimPrefix(s.URL, "http://"),
+		want:    ErrDialTimeout,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := dialBackOffHelper(context.Background(), "tcp4", test.address, test.bo, nil, nil)
+			if err == nil {
+				c.Close()
+				t.Fatal("Unexpected success dialing")
+			}
+			if !errors.Is(err, test.want) {
+				t.Errorf("Dial error = %v, want: %v", err, test.want)
+			}
+			if !strings.Contains(err.Error(), test.want.Error()) {
+				t.Errorf("Dial error = %q, want it to contain %q", err, test.want)
+			}
+			var opErr *net.OpError
+			if !errors.As(err, &opErr) {
+				t.Errorf("Dial error = %v, want it to wrap a *net.OpError", err)
+			}
+		})
+	}
+}
+
+func TestDialAttemptsReporter(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	var attempts []int
+	ctx := WithDialAttemptsReporter(context.Background(), func(n int) {
+		attempts = append(attempts, n)
+	})
+
+	// The first two attempts time out, as they can't connect within a
+	// nanosecond, and the third is given plenty of time.
+	bo := wait.Backoff{Duration: time.Nanosecond, Factor: float64(time.Second), Steps: 3}
+	c, err := dialBackOffHelper(ctx, "tcp4", strings.TrimPrefix(s.URL, "http://"), bo, nil, nil)
+	if err != nil {
+		t.Fatal("Dial error =", err)
+	}
+	c.Close()
+
+	// With the default backoff, the first attempt connects.
+	c, err = DialWithBackOff(ctx, "tcp4", strings.TrimPrefix(s.URL, "http://"))
+	if err != nil {
+		t.Fatal("Dial error =", err)
+	}
+	c.Close()
+
+	if want := []int{3, 1}; !cmp.Equal(attempts, want) {
+		t.Errorf("Reported attempts = %v, want: %v", attempts, want)
+	}
+}
+
+func TestDialWithMaxConcurrentDials(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+
+	opts := newDialOptions([]DialOption{WithMaxConcurrentDials(1)})
+
+	// Occupy the only dial slot, as an in-flight dial would.
+	release, err := opts.acquire(context.Background())
+	if err != nil {
+		t.Fatal("acquire() =", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		c, err := dialBackOffHelper(context.Background(), "tcp4", addr, backOffTemplate, nil, opts)
+		if err == nil {
+			c.Close()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatal("Dial completed while another dial was in flight, err =", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal("Dial error =", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Dial did not complete after the in-flight dial finished")
+	}
+
+	// Dials waiting for a slot give up when their context is done.
+	release, err = opts.acquire(context.Background())
+	if err != nil {
+		t.Fatal("acquire() =", err)
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := dialBackOffHelper(ctx, "tcp4", addr, backOffTemplate, nil, opts); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Dial error = %v, want: %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDialWithRetryBudget(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+	addr := strings.TrimPrefix(s.URL, "http://")
+
+	var attempts int
+	ctx := WithDialAttemptsReporter(context.Background(), func(n int) {
+		attempts = n
+	})
+
+	// The first attempt times out, as it can't connect within a nanosecond,
+	// and the depleted budget doesn't allow retrying it.
+	bo := wait.Backoff{Duration: time.Nanosecond, Factor: float64(time.Second), Steps: 3}
+	opts := newDialOptions([]DialOption{WithRetryBudget(NewRetryBudget(0, 0))})
+	c, err := dialBackOffHelper(ctx, "tcp4", addr, bo, nil, opts)
+	if err == nil {
+		c.Close()
+		t.Fatal("Unexpected success dialing")
+	}
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Errorf("Dial error = %v, want: %v", err, ErrRetryBudgetExhausted)
+	}
+	if attempts != 1 {
+		t.Errorf("Dial attempts = %d, want: 1", attempts)
+	}
+
+	// The budget is shared across dials: the first two attempts of the first
+	// dial time out, and it spends the budget connecting on the third.
+	opts = newDialOptions([]DialOption{WithRetryBudget(NewRetryBudget(0, 2))})
+	c, err = dialBackOffHelper(ctx, "tcp4", addr, bo, nil, opts)
+	if err != nil {
+		t.Fatal("Dial error =", err)
+	}
+	c.Close()
+	if _, err := dialBackOffHelper(ctx, "tcp4", addr, bo, nil, opts); !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Errorf("Dial error = %v, want: %v", err, ErrRetryBudgetExhausted)
+	}
+}
+
 func verifyFailedConnection(t *testing.T, c net.Conn, err error, prefix string) {
 	if err == nil {
 		c.Close()
@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"sync"
+	"time"
+)
+
+// RequeueBackoff tracks the attempts to reconcile each key, so that the keys
+// failing to reconcile can be requeued after exponentially growing delays,
+// e.g. with controller.NewRequeueAfter(b.Next(key)), until they reconcile
+// successfully, when they should be forgotten. Unlike the rate limiter of
+// the work queue, its state can be inspected. It is safe for concurrent use.
+type RequeueBackoff struct {
+	base time.Duration
+	max  time.Duration
+
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+// NewRequeueBackoff returns a RequeueBackoff whose delays start at base and
+// double with every attempt, up to max.
+func NewRequeueBackoff(base, max time.Duration) *RequeueBackoff {
+	return &RequeueBackoff{
+		base:     base,
+		max:      max,
+		attempts: map[string]int{},
+	}
+}
+
+// Next counts an attempt to reconcile key and returns the delay to requeue
+// it after, base for the first attempt since key was last forgotten, and
+// twice the previous delay for the next ones, up to max.
+func (b *RequeueBackoff) Next(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	attempt := b.attempts[key]
+	b.attempts[key] = attempt + 1
+
+	d := b.base
+	for i := 0; i < attempt && d < b.max; i++ {
+		d *= 2
+	}
+	if d > b.max {
+		d = b.max
+	}
+	return d
+}
+
+// Attempts returns the number of attempts to reconcile key counted since it
+// was last forgotten.
+func (b *RequeueBackoff) Attempts(key string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.attempts[key]
+}
+
+// Forget resets the attempts to reconcile key, e.g. once it reconciled
+// successfully, so that the delays start over from base.
+func (b *RequeueBackoff) Forget(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.attempts, key)
+}
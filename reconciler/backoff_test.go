@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// nextDelays returns the next n delays of key.
+func nextDelays(b *RequeueBackoff, key string, n int) []time.Duration {
+	ds := make([]time.Duration, 0, n)
+	for i := 0; i < n; i++ {
+		ds = append(ds, b.Next(key))
+	}
+	return ds
+}
+
+func TestRequeueBackoff(t *testing.T) {
+	tests := []struct {
+		name string
+		base time.Duration
+		max  time.Duration
+		want []time.Duration
+	}{{
+		name: "growth",
+		base: time.Second,
+		max:  time.Hour,
+		want: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+	}, {
+		name: "cap",
+		base: time.Second,
+		max:  5 * time.Second,
+		want: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+	}, {
+		name: "base over the cap",
+		base: time.Minute,
+		max:  time.Second,
+		want: []time.Duration{time.Second, time.Second},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := NewRequeueBackoff(test.base, test.max)
+			if got := nextDelays(b, "ns/name", len(test.want)); !cmp.Equal(got, test.want) {
+				t.Errorf("Next() = %v, want: %v", got, test.want)
+			}
+			if got, want := b.Attempts("ns/name"), len(test.want); got != want {
+				t.Errorf("Attempts() = %d, want: %d", got, want)
+			}
+		})
+	}
+}
+
+func TestRequeueBackoffCapDoesNotOverflow(t *testing.T) {
+	b := NewRequeueBackoff(time.Second, time.Hour)
+	nextDelays(b, "ns/name", 100)
+	if got, want := b.Next("ns/name"), time.Hour; got != want {
+		t.Errorf("Next() = %v, want: %v", got, want)
+	}
+}
+
+func TestRequeueBackoffForget(t *testing.T) {
+	b := NewRequeueBackoff(time.Second, time.Hour)
+	nextDelays(b, "ns/failing", 3)
+	nextDelays(b, "ns/other", 2)
+
+	b.Forget("ns/failing")
+	if got := b.Attempts("ns/failing"); got != 0 {
+		t.Errorf("Attempts() = %d, want: 0", got)
+	}
+	if got, want := b.Next("ns/failing"), time.Second; got != want {
+		t.Errorf("Next() = %v, want: %v", got, want)
+	}
+
+	// The other keys are tracked independently.
+	if got, want := b.Next("ns/other"), 4*time.Second; got != want {
+		t.Errorf("Next() = %v, want: %v", got, want)
+	}
+}
@@ -67,6 +67,8 @@ func PreProcessReconcile(ctx context.Context, resource duckv1.KRShaped) {
 }
 
 // PostProcessReconcile contains logic to apply after reconciliation of a resource.
+// The first time the top-level condition of the resource becomes True, the time
+// since its creation is recorded as the time_to_ready metric.
 func PostProcessReconcile(ctx context.Context, resource, oldResource duckv1.KRShaped) {
 	logger := logging.FromContext(ctx)
 	status := resource.GetStatus()
@@ -83,6 +85,7 @@ func PostProcessReconcile(ctx context.Context, resource, oldResource duckv1.KRSh
 	}
 
 	groomConditionsTransitionTime(resource, oldResource)
+	recordTimeToReady(ctx, resource, oldResource)
 }
 
 // groomConditionsTransitionTime ensures that the LastTransitionTime only advances for resources
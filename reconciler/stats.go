@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	corev1 "k8s.io/api/core/v1"
+
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/metrics"
+	"knative.dev/pkg/metrics/metricskey"
+)
+
+// readyObjectsCacheSize bounds the number of objects remembered as having
+// had their time to ready recorded.
+const readyObjectsCacheSize = 10000
+
+var (
+	timeToReadyStat = stats.Int64("time_to_ready",
+		"Time from the creation of an object to its top-level condition first becoming True",
+		stats.UnitMilliseconds)
+
+	// timeToReadyDistribution defines the bucket boundaries for the histogram of the time to ready metric.
+	// Bucket boundaries are 1s, 10s, 30s, 1m, 5m, 10m, 30m and 1h.
+	timeToReadyDistribution = view.Distribution(1000, 10000, 30000, 60000, 300000, 600000, 1800000, 3600000)
+
+	kindTagKey      = tag.MustNewKey("kind")
+	namespaceTagKey = tag.MustNewKey(metricskey.LabelNamespaceName)
+
+	// readyObjects holds the UIDs of the objects whose time to ready was
+	// recorded, so that it is recorded once per object even if it flaps.
+	readyObjects, _ = lru.New(readyObjectsCacheSize)
+)
+
+func init() {
+	if err := view.Register(&view.View{
+		Description: "Time from the creation of an object to its top-level condition first becoming True",
+		Measure:     timeToReadyStat,
+		Aggregation: timeToReadyDistribution,
+		TagKeys:     []tag.Key{kindTagKey, namespaceTagKey},
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// recordTimeToReady records the time from the creation of resource to now,
+// if its top-level condition became True since oldResource. It is recorded
+// once per object.
+func recordTimeToReady(ctx context.Context, resource, oldResource duckv1.KRShaped) {
+	condType := resource.GetConditionSet().GetTopLevelConditionType()
+	if cond := resource.GetStatus().GetCondition(condType); cond == nil || cond.Status != corev1.ConditionTrue {
+		return
+	}
+	if cond := oldResource.GetStatus().GetCondition(condType); cond != nil && cond.Status == corev1.ConditionTrue {
+		return
+	}
+	created := resource.GetCreationTimestamp()
+	if created.IsZero() {
+		return
+	}
+	if seen, _ := readyObjects.ContainsOrAdd(resource.GetUID(), struct{}{}); seen {
+		return
+	}
+
+	ctx, err := tag.New(ctx,
+		tag.Insert(kindTagKey, kindOf(resource)),
+		tag.Insert(namespaceTagKey, resource.GetNamespace()))
+	if err != nil {
+		return
+	}
+	metrics.Record(ctx, timeToReadyStat.M(time.Since(created.Time).Milliseconds()))
+}
+
+// kindOf returns the kind of resource, which the objects read from the
+// informers' caches usually lack, falling back to the name of its type.
+func kindOf(resource duckv1.KRShaped) string {
+	if kind := resource.GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	return reflect.Indirect(reflect.ValueOf(resource)).Type().Name()
+}
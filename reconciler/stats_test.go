@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/metrics/metricstest"
+	_ "knative.dev/pkg/metrics/testing"
+)
+
+func TestPostProcessReconcileRecordsTimeToReady(t *testing.T) {
+	t.Cleanup(readyObjects.Purge)
+
+	makeObject := func(status corev1.ConditionStatus) *TestResource {
+		r := makeResource()
+		r.Namespace = "ns"
+		r.UID = types.UID("uid")
+		r.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Minute))
+		r.Status.Conditions[1].Status = status
+		return r
+	}
+	wantTags := map[string]string{"kind": "TestResource", "namespace_name": "ns"}
+
+	// Not yet Ready.
+	PostProcessReconcile(context.Background(),
+		duckv1.KRShaped(makeObject(corev1.ConditionUnknown)),
+		duckv1.KRShaped(makeObject(corev1.ConditionUnknown)))
+	metricstest.CheckStatsNotReported(t, "time_to_ready")
+
+	// Becoming Ready.
+	PostProcessReconcile(context.Background(),
+		duckv1.KRShaped(makeObject(corev1.ConditionTrue)),
+		duckv1.KRShaped(makeObject(corev1.ConditionUnknown)))
+	metricstest.CheckDistributionCount(t, "time_to_ready", wantTags, 1)
+
+	// Staying Ready.
+	PostProcessReconcile(context.Background(),
+		duckv1.KRShaped(makeObject(corev1.ConditionTrue)),
+		duckv1.KRShaped(makeObject(corev1.ConditionTrue)))
+	metricstest.CheckDistributionCount(t, "time_to_ready", wantTags, 1)
+
+	// Becoming Ready again after flapping.
+	PostProcessReconcile(context.Background(),
+		duckv1.KRShaped(makeObject(corev1.ConditionTrue)),
+		duckv1.KRShaped(makeObject(corev1.ConditionFalse)))
+	metricstest.CheckDistributionCount(t, "time_to_ready", wantTags, 1)
+}
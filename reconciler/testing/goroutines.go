@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// VerifyNoGoroutineLeaks snapshots the running goroutines and returns a
+// function that fails the test if any goroutine started since is still
+// running after timeout. It is meant to wrap the lifecycle of a controller or
+// webhook, e.g.
+//
+//	defer VerifyNoGoroutineLeaks(t, 5*time.Second)()
+//	ctx, cancel := context.WithCancel(ctx)
+//	go impl.Run(ctx)
+//	...
+//	cancel()
+//
+// The goroutines still running are given some time to wind down, as they may
+// only return shortly after the shutdown of the controller.
+func VerifyNoGoroutineLeaks(t testing.TB, timeout time.Duration) func() {
+	t.Helper()
+	before := goroutines()
+	return func() {
+		t.Helper()
+		for _, stack := range leakedGoroutines(before, timeout) {
+			t.Error("Leaked goroutine:\n", stack)
+		}
+	}
+}
+
+// leakedGoroutines returns the stacks of the goroutines that are not in
+// before, polling for them to return until timeout.
+func leakedGoroutines(before map[string]string, timeout time.Duration) []string {
+	deadline := time.Now().Add(timeout)
+	for {
+		var leaked []string
+		for id, stack := range goroutines() {
+			if _, ok := before[id]; !ok {
+				leaked = append(leaked, stack)
+			}
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// goroutines returns the stacks of the running goroutines, other than the
+// calling one, by goroutine ID.
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := bytes.Split(buf, []byte("\n\n"))
+	ret := make(map[string]string, len(stacks)-1)
+	// The first stack is that of the calling goroutine.
+	for _, stack := range stacks[1:] {
+		// Each stack starts with e.g. "goroutine 42 [running]:".
+		fields := strings.Fields(string(stack))
+		if len(fields) < 2 || fields[0] != "goroutine" {
+			continue
+		}
+		ret[fields[1]] = string(stack)
+	}
+	return ret
+}
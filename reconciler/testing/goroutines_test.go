@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/controller"
+	logtesting "knative.dev/pkg/logging/testing"
+)
+
+func leakGoroutine(stop chan struct{}) {
+	<-stop
+}
+
+func TestLeakedGoroutines(t *testing.T) {
+	before := goroutines()
+
+	stop := make(chan struct{})
+	go leakGoroutine(stop)
+
+	leaked := leakedGoroutines(before, 50*time.Millisecond)
+	if len(leaked) != 1 {
+		t.Fatalf("Leaked goroutines = %v, want the leaked one", leaked)
+	}
+	if !strings.Contains(leaked[0], "leakGoroutine") {
+		t.Errorf("Leaked goroutine = %s, want the stack of leakGoroutine", leaked[0])
+	}
+
+	// Goroutines returning within the timeout are not leaked.
+	time.AfterFunc(10*time.Millisecond, func() { close(stop) })
+	if leaked := leakedGoroutines(before, time.Second); len(leaked) != 0 {
+		t.Errorf("Leaked goroutines = %v, want none", leaked)
+	}
+}
+
+func TestVerifyNoGoroutineLeaksController(t *testing.T) {
+	ctx := logtesting.TestContextWithLogger(t)
+	defer VerifyNoGoroutineLeaks(t, 5*time.Second)()
+
+	impl := controller.NewContext(ctx, nopReconciler{}, controller.ControllerOptions{
+		WorkQueueName: "leaks",
+		Logger:        logtesting.TestLogger(t),
+	})
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		impl.RunContext(ctx, 2)
+	}()
+	impl.EnqueueKey(types.NamespacedName{Namespace: "ns", Name: "name"})
+	cancel()
+	<-done
+}
+
+type nopReconciler struct{}
+
+func (nopReconciler) Reconcile(context.Context, string) error {
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+// waitForReadyInterval is how often WaitForReady checks the informer's cache.
+const waitForReadyInterval = 100 * time.Millisecond
+
+// WaitForReady blocks until the object with the given key, as seen by the
+// informer, has a true top-level condition (e.g. Ready), which it returns.
+// When ctx is done first, it returns the top-level condition last observed,
+// if any, along with the error of ctx. The objects of the informer must
+// implement duckv1.KRShaped.
+func WaitForReady(ctx context.Context, informer cache.SharedInformer, key types.NamespacedName) (*apis.Condition, error) {
+	ticker := time.NewTicker(waitForReadyInterval)
+	defer ticker.Stop()
+
+	var cond *apis.Condition
+	for {
+		obj, exists, err := informer.GetStore().GetByKey(key.String())
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			kr, ok := obj.(duckv1.KRShaped)
+			if !ok {
+				return nil, fmt.Errorf("%T is not a duckv1.KRShaped", obj)
+			}
+			cond = kr.GetConditionSet().Manage(kr.GetStatus()).GetTopLevelCondition()
+			if cond.IsTrue() {
+				return cond, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return cond, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
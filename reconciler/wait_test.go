@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+func TestWaitForReady(t *testing.T) {
+	key := types.NamespacedName{Namespace: "ns", Name: "dependency"}
+	notReady := &duckv1.KResource{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, ResourceVersion: "1"},
+		Status: duckv1.Status{Conditions: duckv1.Conditions{{
+			Type:   apis.ConditionReady,
+			Status: corev1.ConditionFalse,
+			Reason: "NotYet",
+		}}},
+	}
+	watcher := watch.NewFake()
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{
+		ListFunc: func(metav1.ListOptions) (runtime.Object, error) {
+			return &duckv1.KResourceList{Items: []duckv1.KResource{*notReady}}, nil
+		},
+		WatchFunc: func(metav1.ListOptions) (watch.Interface, error) {
+			return watcher, nil
+		},
+	}, &duckv1.KResource{}, 0, cache.Indexers{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go informer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		t.Fatal("Failed to sync the informer")
+	}
+
+	// The object doesn't become Ready before the deadline.
+	waitCtx, waitCancel := context.WithTimeout(ctx, 3*waitForReadyInterval)
+	defer waitCancel()
+	cond, err := WaitForReady(waitCtx, informer, key)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForReady() = %v, want: %v", err, context.DeadlineExceeded)
+	}
+	if cond == nil || cond.Reason != "NotYet" {
+		t.Errorf("WaitForReady() = %v, wanted the terminal NotYet condition", cond)
+	}
+
+	// The object becomes Ready while waiting.
+	go func() {
+		time.Sleep(2 * waitForReadyInterval)
+		ready := notReady.DeepCopy()
+		ready.ResourceVersion = "2"
+		ready.Status.Conditions[0].Status = corev1.ConditionTrue
+		ready.Status.Conditions[0].Reason = ""
+		watcher.Modify(ready)
+	}()
+	waitCtx, waitCancel = context.WithTimeout(ctx, 10*time.Second)
+	defer waitCancel()
+	cond, err = WaitForReady(waitCtx, informer, key)
+	if err != nil {
+		t.Fatal("WaitForReady() =", err)
+	}
+	if !cond.IsTrue() {
+		t.Errorf("WaitForReady() = %v, wanted a true condition", cond)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package system
 
 import (
+	"errors"
 	"fmt"
 	"os"
 )
@@ -29,14 +30,30 @@ const (
 	ResourceLabelEnvKey = "SYSTEM_RESOURCE_LABEL"
 )
 
+// ErrNamespaceNotSet is the error NamespaceE returns when the environment
+// variable specifying the system namespace is not set.
+var ErrNamespaceNotSet = errors.New("the system namespace is not set")
+
 // Namespace returns the name of the K8s namespace where our system components
-// run.
+// run. It panics if the namespace is not set, see NamespaceE.
 func Namespace() string {
+	ns, err := NamespaceE()
+	if err != nil {
+		panic(err.Error())
+	}
+	return ns
+}
+
+// NamespaceE returns the name of the K8s namespace where our system
+// components run, or an error wrapping ErrNamespaceNotSet, explaining how to
+// set it, if it is not set. This lets processes fail fast at startup with a
+// clear message, rather than panic on first use of Namespace.
+func NamespaceE() (string, error) {
 	if ns := os.Getenv(NamespaceEnvKey); ns != "" {
-		return ns
+		return ns, nil
 	}
 
-	panic(fmt.Sprintf(`The environment variable %q is not set
+	return "", fmt.Errorf(`%w: the environment variable %q is not set
 
 If this is a process running on Kubernetes, then it should be using the downward
 API to initialize this variable via:
@@ -52,7 +69,7 @@ following import:
 
 import (
 	_ "knative.dev/pkg/system/testing"
-)`, NamespaceEnvKey, NamespaceEnvKey))
+)`, ErrNamespaceNotSet, NamespaceEnvKey, NamespaceEnvKey)
 }
 
 // ResourceLabel returns the label key identifying K8s objects our system
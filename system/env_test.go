@@ -0,0 +1,51 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package system
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNamespaceE(t *testing.T) {
+	t.Setenv(NamespaceEnvKey, "knative-testing")
+	if ns, err := NamespaceE(); err != nil || ns != "knative-testing" {
+		t.Errorf("NamespaceE() = %q, %v, want: %q", ns, err, "knative-testing")
+	}
+	if ns := Namespace(); ns != "knative-testing" {
+		t.Errorf("Namespace() = %q, want: %q", ns, "knative-testing")
+	}
+}
+
+func TestNamespaceENotSet(t *testing.T) {
+	t.Setenv(NamespaceEnvKey, "")
+	ns, err := NamespaceE()
+	if !errors.Is(err, ErrNamespaceNotSet) {
+		t.Fatalf("NamespaceE() = %q, %v, want: %v", ns, err, ErrNamespaceNotSet)
+	}
+	if !strings.Contains(err.Error(), NamespaceEnvKey) {
+		t.Errorf("NamespaceE() = %v, wanted the error to name %s", err, NamespaceEnvKey)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Namespace() didn't panic")
+		}
+	}()
+	Namespace()
+}
@@ -21,7 +21,9 @@ import (
 
 	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/trace"
+	ocpropagation "go.opencensus.io/trace/propagation"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/pkg/network"
 	"knative.dev/pkg/tracing/propagation/tracecontextb3"
 )
 
@@ -55,3 +57,25 @@ func HTTPSpanIgnoringPaths(pathsToIgnore ...string) func(http.Handler) http.Hand
 		}
 	}
 }
+
+// HTTPPropagatingTransport returns an http.RoundTripper that injects the
+// context of the span found in the request's context into the headers of the
+// outgoing request, using the given propagation format. If format is nil,
+// tracecontextb3.TraceContextEgress is used, which writes the W3C
+// traceparent/tracestate headers. Requests without an active span are
+// passed through as is.
+func HTTPPropagatingTransport(inner http.RoundTripper, format ocpropagation.HTTPFormat) http.RoundTripper {
+	if format == nil {
+		format = tracecontextb3.TraceContextEgress
+	}
+	return network.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		span := trace.FromContext(r.Context())
+		if span == nil {
+			return inner.RoundTrip(r)
+		}
+		// RoundTrippers should not modify the request they are given.
+		r = r.Clone(r.Context())
+		format.SpanContextToRequest(span.SpanContext(), r)
+		return inner.RoundTrip(r)
+	})
+}
@@ -17,10 +17,14 @@ limitations under the License.
 package tracing_test
 
 import (
+	"context"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
+	"go.opencensus.io/trace"
+	"knative.dev/pkg/network"
 	. "knative.dev/pkg/tracing"
 	"knative.dev/pkg/tracing/config"
 	. "knative.dev/pkg/tracing/testing"
@@ -179,6 +183,41 @@ func TestHTTPSpanIgnoringPaths(t *testing.T) {
 	}
 }
 
+func TestHTTPPropagatingTransport(t *testing.T) {
+	var got http.Header
+	rt := HTTPPropagatingTransport(network.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		got = r.Header
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}), nil)
+
+	t.Run("no span", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatal("RoundTrip() =", err)
+		}
+		if h := got.Get("traceparent"); h != "" {
+			t.Errorf("traceparent = %q, wanted no header", h)
+		}
+	})
+
+	t.Run("with span", func(t *testing.T) {
+		ctx, span := trace.StartSpan(context.Background(), "test", trace.WithSampler(trace.AlwaysSample()))
+		defer span.End()
+
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatal("RoundTrip() =", err)
+		}
+		h := got.Get("traceparent")
+		if want := span.SpanContext().TraceID.String(); !strings.Contains(h, want) {
+			t.Errorf("traceparent = %q, wanted it to contain trace ID %q", h, want)
+		}
+		if req.Header.Get("traceparent") != "" {
+			t.Error("The original request was modified")
+		}
+	})
+}
+
 func BenchmarkSpanMiddleware(b *testing.B) {
 	cfg := config.Config{
 		Backend: config.Zipkin,
@@ -17,15 +17,26 @@ limitations under the License.
 package webhook
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 	admissionv1 "k8s.io/api/admission/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer/protobuf"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/logging/logkey"
@@ -46,6 +57,30 @@ const (
 
 	// AdmissionReviewPatchType is the key used to represent the type of Patch in logs
 	AdmissionReviewPatchType = "admissionreview/patchtype"
+
+	// AdmissionReviewPatchSize is the key used to represent the size in bytes
+	// of the Patch in audit logs
+	AdmissionReviewPatchSize = "admissionreview/patchsize"
+
+	// AdmissionReviewPatchOperations is the key used to represent the number
+	// of operations of a JSONPatch in audit logs
+	AdmissionReviewPatchOperations = "admissionreview/patchoperations"
+
+	// AdmissionReviewUsername is the key used to represent the name of the
+	// user making the admission request in audit logs
+	AdmissionReviewUsername = "admissionreview/username"
+
+	// AdmissionReviewGroups is the key used to represent the groups of the
+	// user making the admission request in audit logs
+	AdmissionReviewGroups = "admissionreview/groups"
+
+	// AuditLoggerName is the name of the logger the admission decisions are
+	// recorded with (see Options.AuditLogging).
+	AuditLoggerName = "admission-audit"
+
+	// maxDecompressedBodySize bounds the size a gzip-encoded request body
+	// may expand to, to guard against decompression bombs.
+	maxDecompressedBodySize = 8 << 20 // 8MiB
 )
 
 // AdmissionController provides the interface for different admission controllers
@@ -74,7 +109,22 @@ func MakeErrorStatus(reason string, args ...interface{}) *admissionv1.AdmissionR
 	}
 }
 
-func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, c AdmissionController, synced <-chan struct{}) http.HandlerFunc {
+// makeCanceledStatus creates a 'Timeout' error AdmissionResponse for a
+// request whose context was canceled, or reached its deadline, before it was
+// admitted.
+func makeCanceledStatus(err error) *admissionv1.AdmissionResponse {
+	msg := "admission request canceled: "
+	if errors.Is(err, context.DeadlineExceeded) {
+		msg = "admission request timed out: "
+	}
+	result := apierrors.NewTimeoutError(msg+err.Error(), 0).Status()
+	return &admissionv1.AdmissionResponse{
+		Result:  &result,
+		Allowed: false,
+	}
+}
+
+func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, audit *zap.SugaredLogger, c AdmissionController, synced <-chan struct{}) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if _, ok := c.(StatelessAdmissionController); ok {
 			// Stateless admission controllers do not require Informers to have
@@ -89,11 +139,28 @@ func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, c Admi
 		logger := rootLogger
 		logger.Infof("Webhook ServeHTTP request=%#v", r)
 
+		body, err := requestBody(r)
+		if err != nil {
+			http.Error(w, fmt.Sprint("could not read body:", err), http.StatusBadRequest)
+			return
+		}
+		defer body.Close()
+
 		var review admissionv1.AdmissionReview
-		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		if isProtobuf(r.Header.Get("Content-Type")) {
+			err = decodeProtobufReview(body, &review)
+		} else {
+			err = json.NewDecoder(body).Decode(&review)
+		}
+		if err != nil {
 			http.Error(w, fmt.Sprint("could not decode body:", err), http.StatusBadRequest)
 			return
 		}
+		typeMeta, err := reviewTypeMeta(review.TypeMeta)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		logger = logger.With(
 			logkey.Kind, review.Request.Kind.String(),
@@ -106,15 +173,33 @@ func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, c Admi
 
 		ctx := logging.WithLogger(r.Context(), logger)
 		ctx = apis.WithHTTPRequest(ctx, r)
+		ctx = apis.WithAuditAnnotations(ctx)
 
 		response := admissionv1.AdmissionReview{
-			// Use the same type meta as the request - this is required by the K8s API
-			// note: v1beta1 & v1 AdmissionReview shapes are identical so even though
-			// we're using v1 types we still support v1beta1 admission requests
-			TypeMeta: review.TypeMeta,
+			// Respond with the version of the request, as required by the K8s API.
+			TypeMeta: typeMeta,
 		}
 
-		reviewResponse := c.Admit(ctx, review.Request)
+		reviewResponse, admitted := admit(ctx, c, review.Request)
+		if err := ctx.Err(); err != nil {
+			// The API server gave up on the request, e.g. the client went
+			// away, or the processing deadline passed, so don't report the
+			// outcome of an aborted admission.
+			reviewResponse = makeCanceledStatus(err)
+		}
+		// Surface the audit annotations recorded by callbacks, without
+		// overriding the ones set by the admission controller itself. They
+		// are left out if Admit is still running, as it may still record some.
+		if annotations := apis.GetAuditAnnotations(ctx); admitted && len(annotations) > 0 {
+			if reviewResponse.AuditAnnotations == nil {
+				reviewResponse.AuditAnnotations = make(map[string]string, len(annotations))
+			}
+			for k, v := range annotations {
+				if _, ok := reviewResponse.AuditAnnotations[k]; !ok {
+					reviewResponse.AuditAnnotations[k] = v
+				}
+			}
+		}
 		var patchType string
 		if reviewResponse.PatchType != nil {
 			patchType = string(*reviewResponse.PatchType)
@@ -132,8 +217,23 @@ func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, c Admi
 
 		logger.Infof("remote admission controller audit annotations=%#v", reviewResponse.AuditAnnotations)
 		logger.Debugf("AdmissionReview patch={ type: %s, body: %s }", patchType, string(reviewResponse.Patch))
+		if audit != nil {
+			auditAdmission(audit, review.Request, response.Response)
+		}
 
-		if err := json.NewEncoder(w).Encode(response); err != nil {
+		encode := func(w io.Writer) error { return json.NewEncoder(w).Encode(response) }
+		if acceptsProtobuf(r) {
+			w.Header().Set("Content-Type", runtime.ContentTypeProtobuf)
+			encode = func(w io.Writer) error { return protobufSerializer.Encode(&response, w) }
+		}
+		out := io.Writer(w)
+		if acceptsGzip(r) {
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			defer zw.Close()
+			out = zw
+		}
+		if err := encode(out); err != nil {
 			http.Error(w, fmt.Sprint("could not encode response:", err), http.StatusInternalServerError)
 			return
 		}
@@ -145,6 +245,234 @@ func admissionHandler(rootLogger *zap.SugaredLogger, stats StatsReporter, c Admi
 	}
 }
 
+// auditAdmission records the decision resp made on req with audit. Only the
+// fields needed to trace the decision are recorded: neither the objects, nor
+// the content of the patch, nor the UID and extra information of the user,
+// which may carry credentials, are.
+func auditAdmission(audit *zap.SugaredLogger, req *admissionv1.AdmissionRequest, resp *admissionv1.AdmissionResponse) {
+	fields := []interface{}{
+		AdmissionReviewUID, string(req.UID),
+		AdmissionReviewUsername, req.UserInfo.Username,
+		AdmissionReviewGroups, req.UserInfo.Groups,
+		logkey.Kind, req.Kind.String(),
+		logkey.Namespace, req.Namespace,
+		logkey.Name, req.Name,
+		logkey.Operation, string(req.Operation),
+		logkey.SubResource, req.SubResource,
+		AdmissionReviewAllowed, resp.Allowed,
+	}
+	if resp.Result != nil {
+		fields = append(fields, AdmissionReviewResult, resp.Result.Message)
+	}
+	if resp.PatchType != nil {
+		fields = append(fields,
+			AdmissionReviewPatchType, string(*resp.PatchType),
+			AdmissionReviewPatchSize, len(resp.Patch))
+		var ops []json.RawMessage
+		if *resp.PatchType == admissionv1.PatchTypeJSONPatch && json.Unmarshal(resp.Patch, &ops) == nil {
+			fields = append(fields, AdmissionReviewPatchOperations, len(ops))
+		}
+	}
+	audit.Infow("Admission decision", fields...)
+}
+
+// admit returns the response of c to the request, or false if ctx is done
+// before c responds, e.g. when the processing deadline passes, so that a
+// runaway admission controller doesn't hold the request. Admit is then left
+// to return on its own.
+func admit(ctx context.Context, c AdmissionController, req *admissionv1.AdmissionRequest) (*admissionv1.AdmissionResponse, bool) {
+	type result struct {
+		resp  *admissionv1.AdmissionResponse
+		panic interface{}
+	}
+	// Buffered, so that Admit returning after ctx is done doesn't leak.
+	results := make(chan result, 1)
+	go func() {
+		// Carry the panics of Admit over to the handler, for the server
+		// to recover from, rather than crash the webhook.
+		defer func() {
+			if r := recover(); r != nil {
+				results <- result{panic: r}
+			}
+		}()
+		results <- result{resp: c.Admit(ctx, req)}
+	}()
+	select {
+	case r := <-results:
+		if r.panic != nil {
+			panic(r.panic)
+		}
+		return r.resp, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// newAdmissionTimeout returns a function setting a deadline of d on the
+// processing of the requests by the handlers it wraps, see
+// Options.AdmissionTimeout. A non-positive d disables the deadline.
+func newAdmissionTimeout(d time.Duration) func(http.Handler) http.Handler {
+	if d <= 0 {
+		return func(h http.Handler) http.Handler { return h }
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// newInFlightLimiter returns a function limiting the handlers it wraps to
+// serving n requests at any given time altogether, failing the others fast
+// with a retryable error rather than queuing them. A non-positive n disables
+// the limit.
+func newInFlightLimiter(n int) func(http.Handler) http.Handler {
+	if n <= 0 {
+		return func(h http.Handler) http.Handler { return h }
+	}
+	sem := make(chan struct{}, n)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				h.ServeHTTP(w, r)
+			default:
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "too many admission requests in flight", http.StatusTooManyRequests)
+			}
+		})
+	}
+}
+
+// requestBody returns a reader over the request's body, transparently
+// decompressing it when it is gzip-encoded.
+func requestBody(r *http.Request) (io.ReadCloser, error) {
+	if !strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		return r.Body, nil
+	}
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedReadCloser{
+		Reader: io.LimitReader(zr, maxDecompressedBodySize+1),
+		Closer: zr,
+		limit:  maxDecompressedBodySize,
+	}, nil
+}
+
+// limitedReadCloser fails reads once more than limit bytes have been read.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+
+	limit int64
+	read  int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	n, err := l.Reader.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n, fmt.Errorf("decompressed body exceeds %d bytes", l.limit)
+	}
+	return n, err
+}
+
+// acceptsGzip returns whether the client accepts gzip-encoded responses.
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(enc, ";")
+		if !strings.EqualFold(strings.TrimSpace(parts[0]), "gzip") {
+			continue
+		}
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// admissionV1beta1 is the group version of the v1beta1 AdmissionReviews,
+// which are still sent by older API servers, and clients.
+var admissionV1beta1 = schema.GroupVersion{Group: admissionv1.GroupName, Version: "v1beta1"}
+
+// reviewTypeMeta returns the type meta of the response to the AdmissionReview
+// of the given type meta, i.e. of the same version, or unset if it is unset.
+// The v1 and v1beta1 AdmissionReviews have identical shapes, so those of both
+// versions are decoded into, and responded to with, the v1 types. The other
+// versions are rejected.
+func reviewTypeMeta(tm metav1.TypeMeta) (metav1.TypeMeta, error) {
+	const kind = "AdmissionReview"
+	if tm.Kind != "" && tm.Kind != kind {
+		return metav1.TypeMeta{}, fmt.Errorf("unsupported kind %q, want %s", tm.Kind, kind)
+	}
+	switch tm.APIVersion {
+	case "":
+		return tm, nil
+	case admissionv1.SchemeGroupVersion.String(), admissionV1beta1.String():
+		return metav1.TypeMeta{APIVersion: tm.APIVersion, Kind: kind}, nil
+	default:
+		return metav1.TypeMeta{}, fmt.Errorf("unsupported AdmissionReview version %q, want %s or %s",
+			tm.APIVersion, admissionv1.SchemeGroupVersion, admissionV1beta1)
+	}
+}
+
+// protobufSerializer (de)serializes protobuf-encoded AdmissionReviews.
+var protobufSerializer = func() *protobuf.Serializer {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(admissionv1.AddToScheme(scheme))
+	return protobuf.NewSerializer(scheme, scheme)
+}()
+
+// isProtobuf returns whether the media type is the protobuf encoding of
+// Kubernetes objects.
+func isProtobuf(mediaType string) bool {
+	mt, _, err := mime.ParseMediaType(mediaType)
+	return err == nil && mt == runtime.ContentTypeProtobuf
+}
+
+// acceptsProtobuf returns whether the client accepts protobuf-encoded
+// responses, falling back to JSON otherwise.
+func acceptsProtobuf(r *http.Request) bool {
+	for _, mediaType := range strings.Split(r.Header.Get("Accept"), ",") {
+		if isProtobuf(strings.TrimSpace(mediaType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeProtobufReview decodes a protobuf-encoded AdmissionReview. Since the
+// v1 and v1beta1 messages are identical, the envelope's type meta is carried
+// over as is, the same way it is for JSON.
+func decodeProtobufReview(body io.Reader, review *admissionv1.AdmissionReview) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	var unk runtime.Unknown
+	if _, _, err := protobufSerializer.Decode(b, nil, &unk); err != nil {
+		return err
+	}
+	if err := review.Unmarshal(unk.Raw); err != nil {
+		return err
+	}
+	review.TypeMeta = metav1.TypeMeta{
+		APIVersion: unk.APIVersion,
+		Kind:       unk.Kind,
+	}
+	return nil
+}
+
 // StatelessAdmissionImpl marks a reconciler as stateless.
 // Inline this type to implement StatelessAdmissionController.
 type StatelessAdmissionImpl struct{}
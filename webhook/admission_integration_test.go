@@ -17,24 +17,33 @@ package webhook
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"path"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/sync/errgroup"
 	jsonpatch "gomodules.xyz/jsonpatch/v2"
 	admissionv1 "k8s.io/api/admission/v1"
 	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"knative.dev/pkg/apis"
 	kubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	"knative.dev/pkg/logging/logkey"
+	logtesting "knative.dev/pkg/logging/testing"
 	"knative.dev/pkg/metrics/metricstest"
 	_ "knative.dev/pkg/metrics/testing"
 )
@@ -69,6 +78,655 @@ func TestAdmissionEmptyRequestBody(t *testing.T) {
 	testEmptyRequestBody(t, c)
 }
 
+// auditingAdmissionController records an audit annotation on the context
+// passed to Admit, the way a defaulting or validating callback would.
+type auditingAdmissionController struct {
+	fixedAdmissionController
+}
+
+func (aac *auditingAdmissionController) Admit(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	apis.SetAuditAnnotation(ctx, "policy-decision", "abc123")
+	apis.SetAuditAnnotation(ctx, "overridden", "from-callback")
+	return aac.fixedAdmissionController.Admit(ctx, req)
+}
+
+func TestAdmissionAuditAnnotations(t *testing.T) {
+	ac := &auditingAdmissionController{
+		fixedAdmissionController: fixedAdmissionController{
+			path: "/bazinga",
+			response: &admissionv1.AdmissionResponse{
+				Allowed:          true,
+				AuditAnnotations: map[string]string{"overridden": "from-response"},
+			},
+		},
+	}
+	synced := make(chan struct{})
+	close(synced)
+	handler := admissionHandler(logtesting.TestLogger(t), nil, nil, ac, synced)
+
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "some-uid",
+			Operation: admissionv1.Create,
+		},
+	})
+	if err != nil {
+		t.Fatal("Failed to marshal admission review:", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, ac.Path(), bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got, want := rec.Code, http.StatusOK; got != want {
+		t.Fatalf("Response status code = %v, wanted %v", got, want)
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(rec.Body).Decode(&review); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	want := map[string]string{
+		"policy-decision": "abc123",
+		"overridden":      "from-response",
+	}
+	if diff := cmp.Diff(want, review.Response.AuditAnnotations); diff != "" {
+		t.Error("Unexpected audit annotations (-want, +got):", diff)
+	}
+}
+
+func TestAdmissionAuditLogging(t *testing.T) {
+	jsonPatch := admissionv1.PatchTypeJSONPatch
+	tests := []struct {
+		name     string
+		response *admissionv1.AdmissionResponse
+		want     map[string]interface{}
+	}{{
+		name: "allowed",
+		response: &admissionv1.AdmissionResponse{
+			Allowed:   true,
+			PatchType: &jsonPatch,
+			Patch:     []byte(`[{"op":"add","path":"/spec/a","value":"secret"},{"op":"remove","path":"/spec/b"}]`),
+		},
+		want: map[string]interface{}{
+			AdmissionReviewAllowed:         true,
+			AdmissionReviewPatchType:       string(jsonPatch),
+			AdmissionReviewPatchSize:       float64(81),
+			AdmissionReviewPatchOperations: float64(2),
+		},
+	}, {
+		name:     "denied",
+		response: MakeErrorStatus("spec.a is invalid"),
+		want: map[string]interface{}{
+			AdmissionReviewAllowed: false,
+			AdmissionReviewResult:  "spec.a is invalid",
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			audit := zap.New(zapcore.NewCore(
+				zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+				zapcore.AddSync(&buf), zapcore.InfoLevel)).Sugar().Named(AuditLoggerName)
+
+			ac := &fixedAdmissionController{path: "/bazinga", response: test.response}
+			synced := make(chan struct{})
+			close(synced)
+			handler := admissionHandler(logtesting.TestLogger(t), nil, audit, ac, synced)
+
+			body, err := json.Marshal(&admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					UID:       "some-uid",
+					Kind:      metav1.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1", Kind: "Resource"},
+					Namespace: "ns",
+					Name:      "name",
+					Operation: admissionv1.Create,
+					UserInfo: authenticationv1.UserInfo{
+						Username: "jane",
+						UID:      "jane-uid",
+						Groups:   []string{"devs"},
+						Extra:    map[string]authenticationv1.ExtraValue{"token": {"hunter2"}},
+					},
+					Object: runtime.RawExtension{Raw: []byte(`{"spec":{"a":"secret"}}`)},
+				},
+			})
+			if err != nil {
+				t.Fatal("Failed to marshal admission review:", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, ac.Path(), bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if got, want := rec.Code, http.StatusOK; got != want {
+				t.Fatalf("Response status code = %v, wanted %v", got, want)
+			}
+
+			for _, secret := range []string{"hunter2", "jane-uid", "secret"} {
+				if strings.Contains(buf.String(), secret) {
+					t.Errorf("Audit record %s contains %q", buf.String(), secret)
+				}
+			}
+			var record map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("Failed to decode audit record %s: %v", buf.String(), err)
+			}
+			want := map[string]interface{}{
+				"logger":                "admission-audit",
+				AdmissionReviewUID:      "some-uid",
+				AdmissionReviewUsername: "jane",
+				AdmissionReviewGroups:   []interface{}{"devs"},
+				logkey.Kind:             "pkg.knative.dev/v1, Kind=Resource",
+				logkey.Namespace:        "ns",
+				logkey.Name:             "name",
+				logkey.Operation:        "CREATE",
+			}
+			for k, v := range test.want {
+				want[k] = v
+			}
+			for k, v := range want {
+				if !cmp.Equal(record[k], v) {
+					t.Errorf("Audit record %s = %v, want: %v", k, record[k], v)
+				}
+			}
+		})
+	}
+}
+
+// slowAdmissionController blocks in Admit until the request is canceled,
+// the way a long-running validator honoring its context would.
+type slowAdmissionController struct {
+	fixedAdmissionController
+	started chan struct{}
+}
+
+func (sac *slowAdmissionController) Admit(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	close(sac.started)
+	<-ctx.Done()
+	return MakeErrorStatus("validation failed: %v", ctx.Err())
+}
+
+func TestAdmissionCanceledRequest(t *testing.T) {
+	ac := &slowAdmissionController{
+		fixedAdmissionController: fixedAdmissionController{path: "/bazinga"},
+		started:                  make(chan struct{}),
+	}
+	synced := make(chan struct{})
+	close(synced)
+	handler := admissionHandler(logtesting.TestLogger(t), nil, nil, ac, synced)
+
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "some-uid",
+			Operation: admissionv1.Create,
+		},
+	})
+	if err != nil {
+		t.Fatal("Failed to marshal admission review:", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest(http.MethodPost, ac.Path(), bytes.NewReader(body)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(rec, req)
+	}()
+
+	<-ac.started
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the canceled request to be handled")
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(rec.Body).Decode(&review); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	if review.Response.Allowed {
+		t.Error("Canceled request was allowed")
+	}
+	if got, want := review.Response.Result.Reason, metav1.StatusReasonTimeout; got != want {
+		t.Errorf("Response reason = %v, wanted %v", got, want)
+	}
+	if got, want := review.Response.UID, types.UID("some-uid"); got != want {
+		t.Errorf("Response UID = %v, wanted %v", got, want)
+	}
+}
+
+func TestAdmissionTimeout(t *testing.T) {
+	// The controller ignores its context, the way a runaway validator would.
+	ac := &blockingAdmissionController{
+		fixedAdmissionController: fixedAdmissionController{path: "/bazinga"},
+		started:                  make(chan struct{}, 1),
+		release:                  make(chan struct{}),
+	}
+	defer close(ac.release)
+	synced := make(chan struct{})
+	close(synced)
+	const timeout = 100 * time.Millisecond
+	handler := newAdmissionTimeout(timeout)(admissionHandler(logtesting.TestLogger(t), nil, nil, ac, synced))
+
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "some-uid",
+			Operation: admissionv1.Create,
+		},
+	})
+	if err != nil {
+		t.Fatal("Failed to marshal admission review:", err)
+	}
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ac.Path(), bytes.NewReader(body)))
+	if elapsed := time.Since(start); elapsed < timeout || elapsed > 5*time.Second {
+		t.Errorf("Handling the request took %v, wanted about %v", elapsed, timeout)
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(rec.Body).Decode(&review); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	if review.Response.Allowed {
+		t.Error("Timed out request was allowed")
+	}
+	if got, want := review.Response.Result.Reason, metav1.StatusReasonTimeout; got != want {
+		t.Errorf("Response reason = %v, wanted %v", got, want)
+	}
+	if got, want := review.Response.Result.Message, "timed out"; !strings.Contains(got, want) {
+		t.Errorf("Response message = %q, wanted it to contain %q", got, want)
+	}
+	if got, want := review.Response.UID, types.UID("some-uid"); got != want {
+		t.Errorf("Response UID = %v, wanted %v", got, want)
+	}
+}
+
+// blockingAdmissionController blocks in Admit until it is released.
+type blockingAdmissionController struct {
+	fixedAdmissionController
+	started, release chan struct{}
+}
+
+func (bac *blockingAdmissionController) Admit(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	bac.started <- struct{}{}
+	<-bac.release
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+func TestAdmissionMaxInFlight(t *testing.T) {
+	ac := &blockingAdmissionController{
+		fixedAdmissionController: fixedAdmissionController{path: "/bazinga"},
+		started:                  make(chan struct{}),
+		release:                  make(chan struct{}),
+	}
+	synced := make(chan struct{})
+	close(synced)
+	handler := newInFlightLimiter(2)(admissionHandler(logtesting.TestLogger(t), nil, nil, ac, synced))
+
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "some-uid",
+			Operation: admissionv1.Create,
+		},
+	})
+	if err != nil {
+		t.Fatal("Failed to marshal admission review:", err)
+	}
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ac.Path(), bytes.NewReader(body)))
+		return rec
+	}
+
+	// Saturate the limit.
+	var wg sync.WaitGroup
+	recs := make([]*httptest.ResponseRecorder, 2)
+	for i := range recs {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recs[i] = serve()
+		}()
+		<-ac.started
+	}
+
+	// Requests beyond the limit are shed without reaching the controller.
+	start := time.Now()
+	rec := serve()
+	if got, want := rec.Code, http.StatusTooManyRequests; got != want {
+		t.Errorf("Status = %d, wanted %d", got, want)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Shed request has no Retry-After header")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("Shedding the request took", elapsed)
+	}
+
+	close(ac.release)
+	wg.Wait()
+	for i, rec := range recs {
+		if got, want := rec.Code, http.StatusOK; got != want {
+			t.Errorf("Request #%d status = %d, wanted %d", i+1, got, want)
+		}
+	}
+
+	// Once the in-flight requests are done, requests are served again.
+	go func() { <-ac.started }()
+	if got, want := serve().Code, http.StatusOK; got != want {
+		t.Errorf("Status after draining = %d, wanted %d", got, want)
+	}
+}
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatal("Failed to gzip body:", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal("Failed to gzip body:", err)
+	}
+	return buf.Bytes()
+}
+
+func TestAdmissionGzipRequest(t *testing.T) {
+	ac := &fixedAdmissionController{
+		path:     "/bazinga",
+		response: &admissionv1.AdmissionResponse{Allowed: true},
+	}
+	synced := make(chan struct{})
+	close(synced)
+	handler := admissionHandler(logtesting.TestLogger(t), nil, nil, ac, synced)
+
+	body, err := json.Marshal(&admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "some-uid",
+			Operation: admissionv1.Create,
+		},
+	})
+	if err != nil {
+		t.Fatal("Failed to marshal admission review:", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, ac.Path(), bytes.NewReader(gzipBytes(t, body)))
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got, want := rec.Code, http.StatusOK; got != want {
+		t.Fatalf("Response status code = %v, wanted %v: %s", got, want, rec.Body.String())
+	}
+	if got, want := rec.Header().Get("Content-Encoding"), "gzip"; got != want {
+		t.Fatalf("Content-Encoding = %q, wanted %q", got, want)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal("Failed to read gzipped response:", err)
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(zr).Decode(&review); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	if got, want := review.Response.UID, types.UID("some-uid"); got != want {
+		t.Errorf("Response UID = %q, wanted %q", got, want)
+	}
+	if !review.Response.Allowed {
+		t.Error("Expected the request to be allowed")
+	}
+}
+
+func TestAdmissionGzipRequestTooLarge(t *testing.T) {
+	ac := &fixedAdmissionController{
+		path:     "/bazinga",
+		response: &admissionv1.AdmissionResponse{Allowed: true},
+	}
+	synced := make(chan struct{})
+	close(synced)
+	handler := admissionHandler(logtesting.TestLogger(t), nil, nil, ac, synced)
+
+	// A highly compressible body that expands beyond the limit.
+	body := append([]byte(`{"request":{"uid":"`), bytes.Repeat([]byte("a"), maxDecompressedBodySize)...)
+	body = append(body, []byte(`"}}`)...)
+	req := httptest.NewRequest(http.MethodPost, ac.Path(), bytes.NewReader(gzipBytes(t, body)))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got, want := rec.Code, http.StatusBadRequest; got != want {
+		t.Errorf("Response status code = %v, wanted %v", got, want)
+	}
+}
+
+func TestAdmissionProtobufRequest(t *testing.T) {
+	ac := &fixedAdmissionController{
+		path:     "/bazinga",
+		response: &admissionv1.AdmissionResponse{Allowed: true},
+	}
+	cc := &fixedConversionController{path: "/conversion"}
+	// Go through the webhook, for its Content-Type check.
+	_, wh, cancel := newNonRunningTestWebhook(t, Options{}, ac, cc)
+	defer cancel()
+	wh.InformersHaveSynced()
+	server := httptest.NewServer(wh)
+	defer server.Close()
+
+	review := &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionv1.SchemeGroupVersion.String(),
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       "some-uid",
+			Operation: admissionv1.Create,
+			Kind: metav1.GroupVersionKind{
+				Group:   "pkg.knative.dev",
+				Version: "v1alpha1",
+				Kind:    "Resource",
+			},
+		},
+	}
+	var body bytes.Buffer
+	if err := protobufSerializer.Encode(review, &body); err != nil {
+		t.Fatal("Failed to encode admission review:", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, server.URL+ac.Path(), bytes.NewReader(body.Bytes()))
+	if err != nil {
+		t.Fatal("http.NewRequest() =", err)
+	}
+	req.Header.Set("Content-Type", runtime.ContentTypeProtobuf)
+	req.Header.Set("Accept", runtime.ContentTypeProtobuf+", "+runtime.ContentTypeJSON)
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal("Failed to send request:", err)
+	}
+	defer resp.Body.Close()
+
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		b, _ := ioutil.ReadAll(resp.Body)
+		t.Fatalf("Response status code = %v, wanted %v: %s", got, want, b)
+	}
+	if got, want := resp.Header.Get("Content-Type"), runtime.ContentTypeProtobuf; got != want {
+		t.Fatalf("Content-Type = %q, wanted %q", got, want)
+	}
+	var got admissionv1.AdmissionReview
+	if err := decodeProtobufReview(resp.Body, &got); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	if diff := cmp.Diff(review.TypeMeta, got.TypeMeta); diff != "" {
+		t.Error("Unexpected response type meta (-want, +got):", diff)
+	}
+	if got, want := got.Response.UID, types.UID("some-uid"); got != want {
+		t.Errorf("Response UID = %q, wanted %q", got, want)
+	}
+	if !got.Response.Allowed {
+		t.Error("Expected the request to be allowed")
+	}
+
+	// The conversion controllers only accept JSON.
+	resp, err = server.Client().Post(server.URL+cc.Path(), runtime.ContentTypeProtobuf, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		t.Fatal("Failed to send request:", err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusUnsupportedMediaType; got != want {
+		t.Errorf("Conversion response status code = %v, wanted %v", got, want)
+	}
+}
+
+func TestAdmissionProtobufRequestJSONResponse(t *testing.T) {
+	ac := &fixedAdmissionController{
+		path:     "/bazinga",
+		response: &admissionv1.AdmissionResponse{Allowed: true},
+	}
+	synced := make(chan struct{})
+	close(synced)
+	handler := admissionHandler(logtesting.TestLogger(t), nil, nil, ac, synced)
+
+	var body bytes.Buffer
+	if err := protobufSerializer.Encode(&admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionv1.SchemeGroupVersion.String(),
+			Kind:       "AdmissionReview",
+		},
+		Request: &admissionv1.AdmissionRequest{UID: "some-uid"},
+	}, &body); err != nil {
+		t.Fatal("Failed to encode admission review:", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, ac.Path(), &body)
+	req.Header.Set("Content-Type", runtime.ContentTypeProtobuf)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	// Without protobuf being accepted, the response falls back to JSON.
+	var got admissionv1.AdmissionReview
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal("Failed to decode response:", err)
+	}
+	if got, want := got.Response.UID, types.UID("some-uid"); got != want {
+		t.Errorf("Response UID = %q, wanted %q", got, want)
+	}
+}
+
+func TestAdmissionReviewVersions(t *testing.T) {
+	ac := &fixedAdmissionController{
+		path:     "/bazinga",
+		response: &admissionv1.AdmissionResponse{Allowed: true},
+	}
+	synced := make(chan struct{})
+	close(synced)
+	handler := admissionHandler(logtesting.TestLogger(t), nil, nil, ac, synced)
+
+	tests := []struct {
+		name       string
+		apiVersion string
+		protobuf   bool
+		wantCode   int
+	}{{
+		name:       "v1",
+		apiVersion: "admission.k8s.io/v1",
+		wantCode:   http.StatusOK,
+	}, {
+		name:       "v1beta1",
+		apiVersion: "admission.k8s.io/v1beta1",
+		wantCode:   http.StatusOK,
+	}, {
+		name:       "v1, protobuf",
+		apiVersion: "admission.k8s.io/v1",
+		protobuf:   true,
+		wantCode:   http.StatusOK,
+	}, {
+		name:       "v1beta1, protobuf",
+		apiVersion: "admission.k8s.io/v1beta1",
+		protobuf:   true,
+		wantCode:   http.StatusOK,
+	}, {
+		name:       "unsupported version",
+		apiVersion: "admission.k8s.io/v2",
+		wantCode:   http.StatusBadRequest,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			typeMeta := metav1.TypeMeta{
+				APIVersion: test.apiVersion,
+				Kind:       "AdmissionReview",
+			}
+			review := &admissionv1.AdmissionReview{
+				TypeMeta: typeMeta,
+				Request: &admissionv1.AdmissionRequest{
+					UID:       "some-uid",
+					Operation: admissionv1.Create,
+				},
+			}
+			var body bytes.Buffer
+			if test.protobuf {
+				if err := protobufSerializer.Encode(review, &body); err != nil {
+					t.Fatal("Failed to encode admission review:", err)
+				}
+			} else if err := json.NewEncoder(&body).Encode(review); err != nil {
+				t.Fatal("Failed to encode admission review:", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, ac.Path(), &body)
+			if test.protobuf {
+				req.Header.Set("Content-Type", runtime.ContentTypeProtobuf)
+				req.Header.Set("Accept", runtime.ContentTypeProtobuf)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Code; got != test.wantCode {
+				t.Fatalf("Response status code = %v, wanted %v: %s", got, test.wantCode, rec.Body.String())
+			}
+			if test.wantCode != http.StatusOK {
+				return
+			}
+
+			var got admissionv1.AdmissionReview
+			if test.protobuf {
+				if err := decodeProtobufReview(rec.Body, &got); err != nil {
+					t.Fatal("Failed to decode response:", err)
+				}
+			} else if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal("Failed to decode response:", err)
+			}
+			// The response is of the version of the request.
+			if diff := cmp.Diff(typeMeta, got.TypeMeta); diff != "" {
+				t.Error("Unexpected response type meta (-want, +got):", diff)
+			}
+			if got, want := got.Response.UID, types.UID("some-uid"); got != want {
+				t.Errorf("Response UID = %q, wanted %q", got, want)
+			}
+			if !got.Response.Allowed {
+				t.Error("Expected the request to be allowed")
+			}
+		})
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, GZIP", true},
+		{"gzip;q=0.5, br", true},
+		{"gzip;q=0", false},
+		{"br", false},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("Accept-Encoding", test.header)
+		if got := acceptsGzip(r); got != test.want {
+			t.Errorf("acceptsGzip(%q) = %v, wanted %v", test.header, got, test.want)
+		}
+	}
+}
+
 func TestAdmissionValidResponseForResourceTLS(t *testing.T) {
 	ac := &fixedAdmissionController{
 		path:     "/bazinga",
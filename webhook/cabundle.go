@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+)
+
+// CABundleFreshness describes whether the CABundle of a webhook
+// configuration trusts the CA of the current serving certificate.
+type CABundleFreshness string
+
+const (
+	// CABundleCurrent means the CABundle trusts the current CA.
+	CABundleCurrent CABundleFreshness = "Current"
+
+	// CABundleStale means the CABundle doesn't trust the current CA, so
+	// the API server rejects the webhook's serving certificate.
+	CABundleStale CABundleFreshness = "Stale"
+
+	// CABundleMissing means the CABundle is not set.
+	CABundleMissing CABundleFreshness = "Missing"
+)
+
+// MutatingWebhookCABundleFreshness reports whether the CABundles of the
+// webhooks of mwh trust the CA cert in secret, the certificate secret
+// maintained by the certificates reconciler. Since a CABundle may trust
+// several CAs, e.g. during a CA bundle overlap (see Options.CABundleOverlap),
+// it is current as long as it contains the CA cert. When the webhooks
+// disagree, missing takes precedence over stale, and stale over current.
+func MutatingWebhookCABundleFreshness(secret *corev1.Secret, mwh *admissionregistrationv1.MutatingWebhookConfiguration) CABundleFreshness {
+	caCert := bytes.TrimSpace(secret.Data[certresources.CACert])
+
+	if len(mwh.Webhooks) == 0 {
+		return CABundleMissing
+	}
+	freshness := CABundleCurrent
+	for _, wh := range mwh.Webhooks {
+		switch bundle := wh.ClientConfig.CABundle; {
+		case len(bundle) == 0:
+			return CABundleMissing
+		case len(caCert) == 0 || !bytes.Contains(bundle, caCert):
+			freshness = CABundleStale
+		}
+	}
+	return freshness
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+)
+
+func TestMutatingWebhookCABundleFreshness(t *testing.T) {
+	const (
+		currentCA  = "-----BEGIN CERTIFICATE-----\ncurrent\n-----END CERTIFICATE-----\n"
+		outgoingCA = "-----BEGIN CERTIFICATE-----\noutgoing\n-----END CERTIFICATE-----\n"
+	)
+	secret := &corev1.Secret{
+		Data: map[string][]byte{
+			certresources.CACert: []byte(currentCA),
+		},
+	}
+	mwh := func(bundles ...string) *admissionregistrationv1.MutatingWebhookConfiguration {
+		mwh := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		for _, bundle := range bundles {
+			mwh.Webhooks = append(mwh.Webhooks, admissionregistrationv1.MutatingWebhook{
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					CABundle: []byte(bundle),
+				},
+			})
+		}
+		return mwh
+	}
+
+	tests := []struct {
+		name   string
+		secret *corev1.Secret
+		mwh    *admissionregistrationv1.MutatingWebhookConfiguration
+		want   CABundleFreshness
+	}{{
+		name:   "matching",
+		secret: secret,
+		mwh:    mwh(currentCA),
+		want:   CABundleCurrent,
+	}, {
+		name:   "matching during the overlap",
+		secret: secret,
+		mwh:    mwh(currentCA + "\n" + outgoingCA),
+		want:   CABundleCurrent,
+	}, {
+		name:   "mismatched",
+		secret: secret,
+		mwh:    mwh(outgoingCA),
+		want:   CABundleStale,
+	}, {
+		name:   "mismatched on one of the webhooks",
+		secret: secret,
+		mwh:    mwh(currentCA, outgoingCA),
+		want:   CABundleStale,
+	}, {
+		name:   "secret without CA",
+		secret: &corev1.Secret{},
+		mwh:    mwh(currentCA),
+		want:   CABundleStale,
+	}, {
+		name:   "absent",
+		secret: secret,
+		mwh:    mwh(""),
+		want:   CABundleMissing,
+	}, {
+		name:   "absent on one of the webhooks",
+		secret: secret,
+		mwh:    mwh(outgoingCA, ""),
+		want:   CABundleMissing,
+	}, {
+		name:   "no webhooks",
+		secret: secret,
+		mwh:    mwh(),
+		want:   CABundleMissing,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := MutatingWebhookCABundleFreshness(test.secret, test.mwh); got != test.want {
+				t.Errorf("MutatingWebhookCABundleFreshness() = %v, want: %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"knative.dev/pkg/logging"
+)
+
+// certFileReloader serves a certificate loaded from files on disk, and
+// reloads it whenever either of the files changes, so that certificates
+// rotated by an external agent are picked up without a restart.
+type certFileReloader struct {
+	certFile, keyFile string
+	logger            *zap.SugaredLogger
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// newCertFileReloader returns a certFileReloader of the given files, which
+// reloads them as they change until ctx is done.
+func newCertFileReloader(ctx context.Context, certFile, keyFile string) (*certFileReloader, error) {
+	r := &certFileReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   logging.FromContext(ctx),
+	}
+	// Watch the files before loading them, so that no change is missed.
+	if err := WatchFiles(ctx, r.reload, certFile, keyFile); err != nil {
+		return nil, err
+	}
+	r.reload()
+	return r, nil
+}
+
+// reload loads the certificate from the files.
+func (r *certFileReloader) reload() {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		// The files may be missing, or in the middle of being rewritten, keep
+		// serving the previous certificate until they are consistent again.
+		r.logger.Errorw("failed to load server cert and key", zap.Error(err))
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *certFileReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// WatchFiles calls onChange whenever any of the named files may have changed,
+// until ctx is done. The directories of the files are watched, rather than
+// the files themselves, so that files replaced by a rename, e.g. those of the
+// volumes of secrets and config maps, keep being watched.
+func WatchFiles(ctx context.Context, onChange func(), names ...string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	dirs := sets.NewString()
+	for _, name := range names {
+		dirs.Insert(filepath.Dir(name))
+	}
+	for _, dir := range dirs.List() {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	logger := logging.FromContext(ctx)
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// The content of the files doesn't change with their mode.
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				onChange()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Errorw("Error watching files", zap.Error(err))
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	logtesting "knative.dev/pkg/logging/testing"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+)
+
+// writeCertFiles writes new server certs to certFile and keyFile, and
+// returns the PEM encoded server cert and CA cert.
+func writeCertFiles(t *testing.T, certFile, keyFile string) (cert, caCert []byte) {
+	t.Helper()
+	key, cert, caCert, err := certresources.CreateCerts(context.Background(), "webhook", "ns", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal("CreateCerts() =", err)
+	}
+	// Write the key first, so that the pair is only consistent once both
+	// are written.
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{keyFile, key}, {certFile, cert}} {
+		if err := os.WriteFile(f.name, f.data, 0600); err != nil {
+			t.Fatal("WriteFile() =", err)
+		}
+	}
+	return cert, caCert
+}
+
+func TestCertFileReloader(t *testing.T) {
+	ctx, cancel := context.WithCancel(logtesting.TestContextWithLogger(t))
+	defer cancel()
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	r, err := newCertFileReloader(ctx, certFile, keyFile)
+	if err != nil {
+		t.Fatal("newCertFileReloader() =", err)
+	}
+	if cert, err := r.GetCertificate(nil); err != nil || cert != nil {
+		t.Fatalf("GetCertificate() = %v, %v, wanted no certificate before the files exist", cert, err)
+	}
+
+	first, _ := writeCertFiles(t, certFile, keyFile)
+	waitForCert(t, "the initial certificate", first, func() []byte {
+		if cert, _ := r.GetCertificate(nil); cert != nil {
+			return cert.Certificate[0]
+		}
+		return nil
+	})
+
+	second, _ := writeCertFiles(t, certFile, keyFile)
+	waitForCert(t, "the rewritten certificate", second, func() []byte {
+		cert, _ := r.GetCertificate(nil)
+		return cert.Certificate[0]
+	})
+}
+
+func TestCertFileHandshake(t *testing.T) {
+	dir := t.TempDir()
+	opts := newDefaultOptions()
+	opts.CertFile, opts.KeyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	first, firstCA := writeCertFiles(t, opts.CertFile, opts.KeyFile)
+
+	_, wh, cancel := newNonRunningTestWebhook(t, opts)
+	defer cancel()
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", wh.tlsConfig)
+	if err != nil {
+		t.Fatal("Listen() =", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			// Complete the handshake, which the client waits for.
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	// handshake returns the certificate served, verified against caCert.
+	handshake := func(caCert []byte) []byte {
+		rootCAs := x509.NewCertPool()
+		rootCAs.AppendCertsFromPEM(caCert)
+		conn, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{
+			RootCAs:    rootCAs,
+			ServerName: "webhook.ns.svc",
+			MinVersion: tls.VersionTLS12,
+		})
+		if err != nil {
+			return nil
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Raw
+	}
+
+	waitForCert(t, "the initial certificate", first, func() []byte {
+		return handshake(firstCA)
+	})
+
+	second, secondCA := writeCertFiles(t, opts.CertFile, opts.KeyFile)
+	waitForCert(t, "the rewritten certificate", second, func() []byte {
+		return handshake(secondCA)
+	})
+}
+
+// waitForCert waits for served to return the DER encoding of the PEM encoded
+// cert.
+func waitForCert(t *testing.T, desc string, cert []byte, served func() []byte) {
+	t.Helper()
+	want := pemToDER(t, cert)
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return bytes.Equal(served(), want), nil
+	}); err != nil {
+		t.Fatal("Never served", desc)
+	}
+}
+
+func pemToDER(t *testing.T, data []byte) []byte {
+	t.Helper()
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatal("Failed to decode PEM data")
+	}
+	return block.Bytes
+}
@@ -23,14 +23,18 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/webhook"
 	certresources "knative.dev/pkg/webhook/certificates/resources"
 )
 
@@ -44,8 +48,20 @@ type reconciler struct {
 
 	client       kubernetes.Interface
 	secretlister corelisters.SecretLister
+	recorder     record.EventRecorder
 	key          types.NamespacedName
 	serviceName  string
+
+	// serviceNamespace is the namespace of the webhook's service, which the
+	// certificates are issued for. Defaults to the namespace of the secret.
+	serviceNamespace string
+
+	// clock is used to determine whether the certificate is due for rotation,
+	// and when the new certificates are valid from.
+	clock clock.Clock
+
+	// status, when set, tracks whether the certificates are ready.
+	status *webhook.Status
 }
 
 var _ controller.Reconciler = (*reconciler)(nil)
@@ -55,7 +71,13 @@ var _ pkgreconciler.LeaderAware = (*reconciler)(nil)
 func (r *reconciler) Reconcile(ctx context.Context, key string) error {
 	if r.IsLeaderFor(r.key) {
 		// only reconciler the certificate when we are leader.
-		return r.reconcileCertificate(ctx)
+		if err := r.reconcileCertificate(ctx); err != nil {
+			if ok, _ := controller.IsRequeueKey(err); !ok {
+				r.status.MarkCertificatesNotReady("ReconcileFailed", "%v", err)
+			}
+			return err
+		}
+		return nil
 	}
 	return controller.NewSkipKey(key)
 }
@@ -68,13 +90,18 @@ func (r *reconciler) reconcileCertificate(ctx context.Context) error {
 		// The secret should be created explicitly by a higher-level system
 		// that's responsible for install/updates.  We simply populate the
 		// secret information.
+		r.status.MarkCertificatesNotReady("SecretMissing", "Certificate secret %q does not exist", r.key.Name)
 		return nil
 	} else if err != nil {
 		logger.Errorf("Error accessing certificate secret %q: %v", r.key.Name, err)
 		return err
 	}
 
-	if _, haskey := secret.Data[certresources.ServerKey]; !haskey {
+	forceRotate := secret.Annotations[webhook.ForceRotateAnnotationKey]
+	if forceRotate != "" && forceRotate != secret.Annotations[webhook.ForceRotatedAnnotationKey] {
+		logger.Infof("Certificate secret %q requests the rotation of its certificates (%s=%q)",
+			r.key.Name, webhook.ForceRotateAnnotationKey, forceRotate)
+	} else if _, haskey := secret.Data[certresources.ServerKey]; !haskey {
 		logger.Infof("Certificate secret %q is missing key %q", r.key.Name, certresources.ServerKey)
 	} else if _, haskey := secret.Data[certresources.ServerCert]; !haskey {
 		logger.Infof("Certificate secret %q is missing key %q", r.key.Name, certresources.ServerCert)
@@ -89,20 +116,39 @@ func (r *reconciler) reconcileCertificate(ctx context.Context) error {
 			certData, err := x509.ParseCertificate(cert.Certificate[0])
 			if err != nil {
 				logger.Errorw("Error parsing certificate", zap.Error(err))
-			} else if time.Now().Add(oneDay).Before(certData.NotAfter) {
-				return nil
+			} else if rotateAt := certData.NotAfter.Add(-oneDay); r.clock.Now().Before(rotateAt) {
+				r.status.MarkCertificatesReady()
+				// Requeue to rotate the certificate once it enters the grace
+				// period, rather than waiting for the secret to change.
+				return controller.NewRequeueAfter(rotateAt.Sub(r.clock.Now()))
 			}
 		}
 	}
 	// Don't modify the informer copy.
 	secret = secret.DeepCopy()
 
-	// One of the secret's keys is missing, so synthesize a new one and update the secret.
-	newSecret, err := certresources.MakeSecret(ctx, r.key.Name, r.key.Namespace, r.serviceName)
+	serviceNamespace := r.serviceNamespace
+	if serviceNamespace == "" {
+		serviceNamespace = r.key.Namespace
+	}
+	// One of the secret's keys is missing, or the rotation of the certificates
+	// is due or forced, so synthesize new ones and update the secret.
+	// Only its data is used, so it may be made in the service's namespace.
+	newSecret, err := certresources.MakeSecret(certresources.WithClock(ctx, r.clock), r.key.Name, serviceNamespace, r.serviceName)
 	if err != nil {
 		return err
 	}
 	secret.Data = newSecret.Data
-	_, err = r.client.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
-	return err
+	if forceRotate != "" {
+		// Record that the rotation was forced, so it isn't forced again
+		// until the annotation changes.
+		secret.Annotations[webhook.ForceRotatedAnnotationKey] = forceRotate
+	}
+	if _, err := r.client.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	r.recorder.Eventf(secret, corev1.EventTypeNormal, webhook.ReasonCertRotated,
+		"Generated new certificates for secret %q", r.key.Name)
+	r.status.MarkCertificatesReady()
+	return nil
 }
@@ -18,6 +18,8 @@ package certificates
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"testing"
 	"time"
@@ -32,6 +34,9 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/system"
@@ -69,6 +74,8 @@ func TestReconcile(t *testing.T) {
 		Name:    "well formed secret exists",
 		Key:     key,
 		Objects: []runtime.Object{secret},
+		// The rotation of the certificate is scheduled by requeuing.
+		WantErr: true,
 	}, {
 		Name: "secret does not exist",
 		Key:  key,
@@ -89,6 +96,9 @@ func TestReconcile(t *testing.T) {
 		WantUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: secret,
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonCertRotated, "Generated new certificates for secret %q", secretName),
+		},
 	}, {
 		Name: "missing server cert",
 		Key:  key,
@@ -106,6 +116,9 @@ func TestReconcile(t *testing.T) {
 		WantUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: secret,
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonCertRotated, "Generated new certificates for secret %q", secretName),
+		},
 	}, {
 		Name: "missing CA cert",
 		Key:  key,
@@ -123,6 +136,9 @@ func TestReconcile(t *testing.T) {
 		WantUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: secret,
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonCertRotated, "Generated new certificates for secret %q", secretName),
+		},
 	}, {
 		Name: "certificate expiring soon",
 		Key:  key,
@@ -131,22 +147,71 @@ func TestReconcile(t *testing.T) {
 		WantUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: secret,
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonCertRotated, "Generated new certificates for secret %q", secretName),
+		},
 	}, {
 		Name: "certificate not expiring soon",
 		Key:  key,
 		// 25 hours falls outside of the grace period of 1 day so the secret will not be updated.
 		Objects: []runtime.Object{secretWithCertData(t, time.Now().Add(25*time.Hour))},
+		WantErr: true,
+	}, {
+		Name: "rotation forced",
+		Key:  key,
+		// The certificate isn't expiring soon, but the annotation forces its
+		// rotation, along with that of the CA certificate, which the webhooks
+		// take their CABundle from.
+		Objects: []runtime.Object{withAnnotations(secretWithCertData(t, time.Now().Add(25*time.Hour)), map[string]string{
+			webhook.ForceRotateAnnotationKey: "2022-01-01T00:00:00Z",
+		})},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: withAnnotations(secret, map[string]string{
+				webhook.ForceRotateAnnotationKey:  "2022-01-01T00:00:00Z",
+				webhook.ForceRotatedAnnotationKey: "2022-01-01T00:00:00Z",
+			}),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonCertRotated, "Generated new certificates for secret %q", secretName),
+		},
+	}, {
+		Name: "rotation forced again",
+		Key:  key,
+		Objects: []runtime.Object{withAnnotations(secretWithCertData(t, time.Now().Add(25*time.Hour)), map[string]string{
+			webhook.ForceRotateAnnotationKey:  "2022-02-01T00:00:00Z",
+			webhook.ForceRotatedAnnotationKey: "2022-01-01T00:00:00Z",
+		})},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: withAnnotations(secret, map[string]string{
+				webhook.ForceRotateAnnotationKey:  "2022-02-01T00:00:00Z",
+				webhook.ForceRotatedAnnotationKey: "2022-02-01T00:00:00Z",
+			}),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonCertRotated, "Generated new certificates for secret %q", secretName),
+		},
+	}, {
+		Name: "forced rotation already done",
+		Key:  key,
+		Objects: []runtime.Object{withAnnotations(secretWithCertData(t, time.Now().Add(25*time.Hour)), map[string]string{
+			webhook.ForceRotateAnnotationKey:  "2022-01-01T00:00:00Z",
+			webhook.ForceRotatedAnnotationKey: "2022-01-01T00:00:00Z",
+		})},
+		// The rotation of the certificate is scheduled by requeuing.
+		WantErr: true,
 	}}
 
 	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
 		return &reconciler{
 			client:       kubeclient.Get(ctx),
 			secretlister: listers.GetSecretLister(),
+			recorder:     controller.GetEventRecorder(ctx),
 			key: types.NamespacedName{
 				Namespace: system.Namespace(),
 				Name:      secretName,
 			},
 			serviceName: serviceName,
+			clock:       clock.RealClock{},
 		}
 	}))
 }
@@ -175,6 +240,8 @@ func TestReconcileMakeSecretFailure(t *testing.T) {
 		Name:    "would return error, but not called",
 		Key:     key,
 		Objects: []runtime.Object{secret},
+		// The rotation of the certificate is scheduled by requeuing.
+		WantErr: true,
 	}, {
 		Name:    "malformed secret",
 		Key:     key,
@@ -211,15 +278,82 @@ func TestReconcileMakeSecretFailure(t *testing.T) {
 		return &reconciler{
 			client:       kubeclient.Get(ctx),
 			secretlister: listers.GetSecretLister(),
+			recorder:     controller.GetEventRecorder(ctx),
 			key: types.NamespacedName{
 				Namespace: system.Namespace(),
 				Name:      secretName,
 			},
 			serviceName: serviceName,
+			clock:       clock.RealClock{},
 		}
 	}))
 }
 
+func TestReconcileWithClock(t *testing.T) {
+	const (
+		secretName  = "webhook-secret"
+		serviceName = "webhook-service"
+	)
+	// Certificates carry their expiration with a precision of a second.
+	now := time.Now().Truncate(time.Second)
+	fakeClock := clocktesting.NewFakeClock(now)
+
+	// 25 hours falls outside of the grace period of 1 day.
+	secret := secretWithCertData(t, now.Add(25*time.Hour))
+	ctx, kubeClient := kubeclient.With(context.Background(), secret)
+	ls := NewListers([]runtime.Object{secret})
+	r := &reconciler{
+		client:       kubeClient,
+		secretlister: ls.GetSecretLister(),
+		recorder:     record.NewFakeRecorder(1),
+		key: types.NamespacedName{
+			Namespace: system.Namespace(),
+			Name:      secretName,
+		},
+		serviceName: serviceName,
+		clock:       fakeClock,
+	}
+
+	// The reconciler requeues to rotate the certificate exactly when it
+	// enters the grace period.
+	err := r.reconcileCertificate(ctx)
+	if ok, delay := controller.IsRequeueKey(err); !ok {
+		t.Fatalf("reconcileCertificate() = %v, wanted a requeue", err)
+	} else if want := time.Hour; delay != want {
+		t.Errorf("Requeue delay = %v, want: %v", delay, want)
+	}
+	if got := len(kubeClient.Actions()); got != 0 {
+		t.Fatalf("Got %d actions before the rotation threshold, want 0: %v", got, kubeClient.Actions())
+	}
+
+	// Advancing by 2 hours moves the certificate inside of the grace period.
+	fakeClock.Step(2 * time.Hour)
+	if err := r.reconcileCertificate(ctx); err != nil {
+		t.Fatal("reconcileCertificate() =", err)
+	}
+	actions := kubeClient.Actions()
+	if got := len(actions); got != 1 {
+		t.Fatalf("Got %d actions past the rotation threshold, want 1: %v", got, actions)
+	}
+	if !actions[0].Matches("update", "secrets") {
+		t.Fatalf("Action = %v, wanted an update of the secret", actions[0])
+	}
+
+	// The new certificates are valid from the time of the clock.
+	updated := actions[0].(clientgotesting.UpdateAction).GetObject().(*corev1.Secret)
+	block, _ := pem.Decode(updated.Data[certresources.ServerCert])
+	if block == nil {
+		t.Fatal("The server cert is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal("ParseCertificate() =", err)
+	}
+	if want := fakeClock.Now(); !cert.NotBefore.Equal(want) {
+		t.Errorf("NotBefore = %v, wanted %v", cert.NotBefore, want)
+	}
+}
+
 func TestNew(t *testing.T) {
 	ctx, _ := SetupFakeContext(t)
 	ctx = webhook.WithOptions(ctx, webhook.Options{})
@@ -250,6 +384,58 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewWithClock(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	ctx = webhook.WithOptions(ctx, webhook.Options{Clock: fakeClock})
+
+	c := NewController(ctx, configmap.NewStaticWatcher())
+	if got := c.Reconciler.(*reconciler).clock; got != fakeClock {
+		t.Errorf("clock = %v, wanted the clock of the options", got)
+	}
+}
+
+func TestNewWithInformerResyncPeriod(t *testing.T) {
+	ctx, cancel, informers := SetupFakeContextWithCancel(t)
+	defer cancel()
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName:           "webhook-secret",
+		InformerResyncPeriod: 50 * time.Millisecond,
+	})
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "webhook-secret",
+			Namespace: system.Namespace(),
+		},
+	}
+	kubeclient.Get(ctx).CoreV1().Secrets(secret.Namespace).Create(ctx, secret, metav1.CreateOptions{})
+
+	c := NewController(ctx, configmap.NewStaticWatcher())
+
+	waitInformers, err := RunAndSyncInformers(ctx, informers...)
+	if err != nil {
+		t.Fatal("RunAndSyncInformers() =", err)
+	}
+	defer func() {
+		cancel()
+		waitInformers()
+	}()
+
+	// The initial add enqueues the key, drain it and expect the resync
+	// of the informer to enqueue it again without any change to the secret.
+	for i := 0; i < 2; i++ {
+		if wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+			return c.WorkQueue().Len() == 1, nil
+		}) != nil {
+			t.Fatalf("Queue length was never 1 (iteration %d)", i)
+		}
+		item, _ := c.WorkQueue().Get()
+		c.WorkQueue().Forget(item)
+		c.WorkQueue().Done(item)
+	}
+}
+
 func secretWithCertData(t *testing.T, expiration time.Time) *corev1.Secret {
 	const secretName = "webhook-secret"
 	serverKey, serverCert, caCert, err := certresources.CreateCerts(context.Background(), "webhook-service", system.Namespace(), expiration)
@@ -268,3 +454,10 @@ func secretWithCertData(t *testing.T, expiration time.Time) *corev1.Secret {
 		},
 	}
 }
+
+// withAnnotations returns a copy of secret with the given annotations.
+func withAnnotations(secret *corev1.Secret, annotations map[string]string) *corev1.Secret {
+	secret = secret.DeepCopy()
+	secret.Annotations = annotations
+	return secret
+}
@@ -25,6 +25,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
@@ -59,23 +60,36 @@ func NewController(
 				return nil
 			},
 		},
-		key:         key,
-		serviceName: options.ServiceName,
+		key:              key,
+		serviceName:      options.ServiceName,
+		serviceNamespace: options.ServiceNamespace,
+		clock:            options.Clock,
+		status:           options.Status,
 
 		client:       client,
 		secretlister: secretInformer.Lister(),
 	}
 
+	if wh.clock == nil {
+		wh.clock = clock.RealClock{}
+	}
+
 	const queueName = "WebhookCertificates"
+	wh.recorder = webhook.NewEventRecorder(ctx, queueName)
 	c := controller.NewContext(ctx, wh, controller.ControllerOptions{WorkQueueName: queueName, Logger: logging.FromContext(ctx).Named(queueName)})
 
 	// Reconcile when the cert bundle changes.
-	secretInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+	handler := cache.FilteringResourceEventHandler{
 		FilterFunc: controller.FilterWithNameAndNamespace(key.Namespace, key.Name),
 		// It doesn't matter what we enqueue because we will always Reconcile
 		// the named MWH resource.
 		Handler: controller.HandleAll(c.Enqueue),
-	})
+	}
+	if options.InformerResyncPeriod > 0 {
+		secretInformer.Informer().AddEventHandlerWithResyncPeriod(handler, options.InformerResyncPeriod)
+	} else {
+		secretInformer.Informer().AddEventHandler(handler)
+	}
 
 	return c
 }
@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	"k8s.io/utils/clock"
 
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/network"
@@ -38,9 +39,26 @@ const (
 	organization = "knative.dev"
 )
 
+// clockKey is used as the key for associating a clock with the context.
+type clockKey struct{}
+
+// WithClock returns a copy of ctx in which the certificates are made at the
+// time of c, rather than of the real clock, e.g. a fake clock in tests.
+func WithClock(ctx context.Context, c clock.PassiveClock) context.Context {
+	return context.WithValue(ctx, clockKey{}, c)
+}
+
+// getClock returns the clock associated with ctx, or the real clock.
+func getClock(ctx context.Context) clock.PassiveClock {
+	if c, ok := ctx.Value(clockKey{}).(clock.PassiveClock); ok {
+		return c
+	}
+	return clock.RealClock{}
+}
+
 // Create the common parts of the cert. These don't change between
 // the root/CA cert and the server cert.
-func createCertTemplate(name, namespace string, notAfter time.Time) (*x509.Certificate, error) {
+func createCertTemplate(name, namespace string, notBefore, notAfter time.Time) (*x509.Certificate, error) {
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
 	if err != nil {
@@ -64,7 +82,7 @@ func createCertTemplate(name, namespace string, notAfter time.Time) (*x509.Certi
 			CommonName:   commonName,
 		},
 		SignatureAlgorithm:    x509.ECDSAWithSHA256,
-		NotBefore:             time.Now(),
+		NotBefore:             notBefore,
 		NotAfter:              notAfter,
 		BasicConstraintsValid: true,
 		DNSNames:              serviceNames,
@@ -73,8 +91,8 @@ func createCertTemplate(name, namespace string, notAfter time.Time) (*x509.Certi
 }
 
 // Create cert template suitable for CA and hence signing
-func createCACertTemplate(name, namespace string, notAfter time.Time) (*x509.Certificate, error) {
-	rootCert, err := createCertTemplate(name, namespace, notAfter)
+func createCACertTemplate(name, namespace string, notBefore, notAfter time.Time) (*x509.Certificate, error) {
+	rootCert, err := createCertTemplate(name, namespace, notBefore, notAfter)
 	if err != nil {
 		return nil, err
 	}
@@ -86,8 +104,8 @@ func createCACertTemplate(name, namespace string, notAfter time.Time) (*x509.Cer
 }
 
 // Create cert template that we can use on the server for TLS
-func createServerCertTemplate(name, namespace string, notAfter time.Time) (*x509.Certificate, error) {
-	serverCert, err := createCertTemplate(name, namespace, notAfter)
+func createServerCertTemplate(name, namespace string, notBefore, notAfter time.Time) (*x509.Certificate, error) {
+	serverCert, err := createCertTemplate(name, namespace, notBefore, notAfter)
 	if err != nil {
 		return nil, err
 	}
@@ -122,7 +140,7 @@ func createCA(ctx context.Context, name, namespace string, notAfter time.Time) (
 	}
 	publicKey := privateKey.Public()
 
-	rootCertTmpl, err := createCACertTemplate(name, namespace, notAfter)
+	rootCertTmpl, err := createCACertTemplate(name, namespace, getClock(ctx).Now(), notAfter)
 	if err != nil {
 		logger.Errorw("error generating CA cert", zap.Error(err))
 		return nil, nil, nil, err
@@ -140,7 +158,8 @@ func createCA(ctx context.Context, name, namespace string, notAfter time.Time) (
 // key for the server. serverKey and serverCert are used by the server
 // to establish trust for clients, CA certificate is used by the
 // client to verify the server authentication chain. notAfter specifies
-// the expiration date.
+// the expiration date. The certificates are valid from the time of the clock
+// associated with ctx, see WithClock.
 func CreateCerts(ctx context.Context, name, namespace string, notAfter time.Time) (serverKey, serverCert, caCert []byte, err error) {
 	logger := logging.FromContext(ctx)
 	// First create a CA certificate and private key
@@ -157,7 +176,7 @@ func CreateCerts(ctx context.Context, name, namespace string, notAfter time.Time
 	}
 	publicKey := privateKey.Public()
 
-	servCertTemplate, err := createServerCertTemplate(name, namespace, notAfter)
+	servCertTemplate, err := createServerCertTemplate(name, namespace, getClock(ctx).Now(), notAfter)
 	if err != nil {
 		logger.Errorw("failed to create the server certificate template", zap.Error(err))
 		return nil, nil, nil, err
@@ -37,13 +37,14 @@ const (
 )
 
 // MakeSecret synthesizes a Kubernetes Secret object with the keys specified by
-// ServerKey, ServerCert, and CACert populated with a fresh certificate.
-// This is mutable to make deterministic testing possible.
+// ServerKey, ServerCert, and CACert populated with a fresh certificate,
+// valid for a week from the time of the clock associated with the context
+// (see WithClock). This is mutable to make deterministic testing possible.
 var MakeSecret = MakeSecretInternal
 
 // MakeSecretInternal is only public so MakeSecret can be restored in testing.  Use MakeSecret.
 func MakeSecretInternal(ctx context.Context, name, namespace, serviceName string) (*corev1.Secret, error) {
-	serverKey, serverCert, caCert, err := CreateCerts(ctx, serviceName, namespace, time.Now().Add(oneWeek))
+	serverKey, serverCert, caCert, err := CreateCerts(ctx, serviceName, namespace, getClock(ctx).Now().Add(oneWeek))
 	if err != nil {
 		return nil, err
 	}
@@ -17,7 +17,12 @@ limitations under the License.
 package resources
 
 import (
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
+	"time"
+
+	clocktesting "k8s.io/utils/clock/testing"
 
 	. "knative.dev/pkg/logging/testing"
 )
@@ -35,3 +40,30 @@ func TestMakeSecret(t *testing.T) {
 		}
 	}
 }
+
+func TestMakeSecretWithClock(t *testing.T) {
+	// Certificates carry their validity with a precision of a second.
+	now := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	ctx := WithClock(TestContextWithLogger(t), clocktesting.NewFakeClock(now))
+	secret, err := MakeSecret(ctx, "foo", "ns", "bar")
+	if err != nil {
+		t.Fatal("MakeSecret() =", err)
+	}
+
+	for _, key := range []string{ServerCert, CACert} {
+		block, _ := pem.Decode(secret.Data[key])
+		if block == nil {
+			t.Fatalf("secret.Data[%q] is not PEM encoded", key)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("secret.Data[%q] is not a certificate: %v", key, err)
+		}
+		if !cert.NotBefore.Equal(now) {
+			t.Errorf("%s NotBefore = %v, wanted %v", key, cert.NotBefore, now)
+		}
+		if want := now.Add(oneWeek); !cert.NotAfter.Equal(want) {
+			t.Errorf("%s NotAfter = %v, wanted %v", key, cert.NotAfter, want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+)
+
+// Reasons of the events emitted by the webhook reconcilers. Events are only
+// emitted for reconciliations that changed something, or failed, so that the
+// periodic no-ops don't flood the events. The results of the reconciliations
+// are also reported through the StatsReporter, and logged, by reason.
+const (
+	// ReasonAlreadyReconciled is reported, but not emitted, when the
+	// reconciled object already had the desired state.
+	ReasonAlreadyReconciled = "AlreadyReconciled"
+
+	// ReasonUpdated is emitted when the reconciled object was updated.
+	ReasonUpdated = "Updated"
+
+	// ReasonDriftCorrected is emitted, along with ReasonUpdated, when the
+	// update reverted changes made to the reconciled object by another
+	// actor, which may hint at controllers fighting over it.
+	ReasonDriftCorrected = "DriftCorrected"
+
+	// ReasonCertRotated is emitted when new certificates were generated.
+	ReasonCertRotated = "CertRotated"
+
+	// ReasonInvalidRegistration is emitted when a type registered with a
+	// webhook is skipped because its registration is malformed.
+	ReasonInvalidRegistration = "InvalidRegistration"
+
+	// ReasonReconcileFailed is emitted, at a limited rate, when the
+	// reconciliation of the object keeps failing.
+	ReasonReconcileFailed = "ReconcileFailed"
+)
+
+// NewEventRecorder returns the EventRecorder associated with the context,
+// or, if there is none, creates one that records events through the
+// injected kubernetes client on behalf of the given component.
+func NewEventRecorder(ctx context.Context, component string) record.EventRecorder {
+	if recorder := controller.GetEventRecorder(ctx); recorder != nil {
+		return recorder
+	}
+
+	logger := logging.FromContext(ctx)
+	logger.Debug("Creating event broadcaster")
+	eventBroadcaster := record.NewBroadcaster()
+	watches := []watch.Interface{
+		eventBroadcaster.StartLogging(logger.Named("event-broadcaster").Infof),
+		eventBroadcaster.StartRecordingToSink(
+			&typedcorev1.EventSinkImpl{Interface: kubeclient.Get(ctx).CoreV1().Events("")}),
+	}
+	go func() {
+		<-ctx.Done()
+		for _, w := range watches {
+			w.Stop()
+		}
+	}()
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component})
+}
@@ -62,3 +62,65 @@ func ensureLabelSelectorRequirements(
 
 	return append(want, nonKnative...)
 }
+
+// UpdateLabelSelectorExpressions returns the current label selector with the
+// minimal changes to its MatchExpressions for the knative-keys to be the
+// wanted ones, so that updates to it are stable and easy to diff.
+// Unlike EnsureLabelSelectorExpressions, the non-knative keys keep their
+// position, as do the wanted knative-keys already there, which are updated in
+// place. The knative-keys no longer wanted are removed, and those not yet
+// there are added after the last knative-key kept, or first if none is.
+// The MatchLabels of the current selector are kept too. Neither selector is
+// modified.
+func UpdateLabelSelectorExpressions(
+	current *metav1.LabelSelector,
+	want *metav1.LabelSelector) *metav1.LabelSelector {
+
+	var wantExpressions []metav1.LabelSelectorRequirement
+	if want != nil {
+		wantExpressions = want.MatchExpressions
+	}
+	if current == nil {
+		if want == nil {
+			return nil
+		}
+		return &metav1.LabelSelector{MatchExpressions: append([]metav1.LabelSelectorRequirement(nil), wantExpressions...)}
+	}
+
+	wanted := make(map[string]metav1.LabelSelectorRequirement, len(wantExpressions))
+	for _, r := range wantExpressions {
+		wanted[r.Key] = r
+	}
+
+	updated := current.DeepCopy()
+	updated.MatchExpressions = make([]metav1.LabelSelectorRequirement, 0, len(current.MatchExpressions)+len(wantExpressions))
+	kept := make(map[string]bool, len(wantExpressions))
+	insertAt := 0
+	for _, r := range current.MatchExpressions {
+		w, ok := wanted[r.Key]
+		if !ok && !strings.Contains(r.Key, "knative.dev") {
+			updated.MatchExpressions = append(updated.MatchExpressions, *r.DeepCopy())
+			continue
+		}
+		if !ok || kept[r.Key] {
+			// Stale, or a duplicate of a kept one.
+			continue
+		}
+		kept[r.Key] = true
+		updated.MatchExpressions = append(updated.MatchExpressions, *w.DeepCopy())
+		insertAt = len(updated.MatchExpressions)
+	}
+
+	var added []metav1.LabelSelectorRequirement
+	for _, r := range wantExpressions {
+		if !kept[r.Key] {
+			added = append(added, *r.DeepCopy())
+		}
+	}
+	updated.MatchExpressions = append(updated.MatchExpressions[:insertAt],
+		append(added, updated.MatchExpressions[insertAt:]...)...)
+	if len(updated.MatchExpressions) == 0 {
+		updated.MatchExpressions = nil
+	}
+	return updated
+}
@@ -129,6 +129,109 @@ func TestEnsureLabelSelectorExpressions(t *testing.T) {
 	}
 }
 
+func TestUpdateLabelSelectorExpressions(t *testing.T) {
+	fooExpression := metav1.LabelSelectorRequirement{
+		Key:      "foo.bar/baz",
+		Operator: metav1.LabelSelectorOpDoesNotExist,
+	}
+	barExpression := metav1.LabelSelectorRequirement{
+		Key:      "bar.baz/foo",
+		Operator: metav1.LabelSelectorOpExists,
+	}
+	knativeExpression := metav1.LabelSelectorRequirement{
+		Key:      "knative.dev/foo",
+		Operator: metav1.LabelSelectorOpDoesNotExist,
+	}
+	canaryExpression := metav1.LabelSelectorRequirement{
+		Key:      "knative.dev/canary",
+		Operator: metav1.LabelSelectorOpIn,
+		Values:   []string{"canary"},
+	}
+	staleExpression := metav1.LabelSelectorRequirement{
+		Key:      "knative.dev/bar",
+		Operator: metav1.LabelSelectorOpDoesNotExist,
+	}
+	selector := func(exprs ...metav1.LabelSelectorRequirement) *metav1.LabelSelector {
+		return &metav1.LabelSelector{MatchExpressions: exprs}
+	}
+
+	tests := []struct {
+		name    string
+		current *metav1.LabelSelector
+		want    *metav1.LabelSelector
+		expect  *metav1.LabelSelector
+	}{{
+		name: "all nil",
+	}, {
+		name:   "current nil",
+		want:   selector(knativeExpression),
+		expect: selector(knativeExpression),
+	}, {
+		name:    "no-op",
+		current: selector(fooExpression, knativeExpression, barExpression),
+		want:    selector(knativeExpression),
+		expect:  selector(fooExpression, knativeExpression, barExpression),
+	}, {
+		name:    "add to foreign",
+		current: selector(fooExpression, barExpression),
+		want:    selector(knativeExpression),
+		expect:  selector(knativeExpression, fooExpression, barExpression),
+	}, {
+		name:    "add after the kept knative-keys",
+		current: selector(fooExpression, knativeExpression, barExpression),
+		want:    selector(knativeExpression, canaryExpression),
+		expect:  selector(fooExpression, knativeExpression, canaryExpression, barExpression),
+	}, {
+		name:    "remove stale",
+		current: selector(staleExpression, fooExpression, knativeExpression, barExpression),
+		want:    selector(knativeExpression),
+		expect:  selector(fooExpression, knativeExpression, barExpression),
+	}, {
+		name:    "remove all knative-keys",
+		current: selector(fooExpression, knativeExpression, barExpression),
+		want:    nil,
+		expect:  selector(fooExpression, barExpression),
+	}, {
+		name: "update in place",
+		current: selector(fooExpression, metav1.LabelSelectorRequirement{
+			Key:      "knative.dev/canary",
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   []string{"beta"},
+		}, barExpression),
+		want:   selector(canaryExpression),
+		expect: selector(fooExpression, canaryExpression, barExpression),
+	}, {
+		name:    "remove duplicates",
+		current: selector(knativeExpression, fooExpression, knativeExpression),
+		want:    selector(knativeExpression),
+		expect:  selector(knativeExpression, fooExpression),
+	}, {
+		name: "keep match labels",
+		current: &metav1.LabelSelector{
+			MatchLabels:      map[string]string{"foo": "bar"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{fooExpression},
+		},
+		want: selector(knativeExpression),
+		expect: &metav1.LabelSelector{
+			MatchLabels:      map[string]string{"foo": "bar"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{knativeExpression, fooExpression},
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			current := tc.current.DeepCopy()
+			got := UpdateLabelSelectorExpressions(tc.current, tc.want)
+			if !cmp.Equal(got, tc.expect) {
+				t.Errorf("LabelSelector mismatch: diff(-want,+got):\n%s", cmp.Diff(tc.expect, got))
+			}
+			if !cmp.Equal(tc.current, current) {
+				t.Errorf("Current LabelSelector was modified: diff(-want,+got):\n%s", cmp.Diff(current, tc.current))
+			}
+		})
+	}
+}
+
 func waitForServerAvailable(t *testing.T, serverURL string, timeout time.Duration) error {
 	t.Helper()
 	var interval = 100 * time.Millisecond
@@ -19,7 +19,14 @@ package json
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"knative.dev/pkg/apis"
 )
 
 var (
@@ -28,6 +35,32 @@ var (
 	metaSuffix = []byte(`}`)
 )
 
+// unknownFieldPrefix is the prefix of the error encoding/json returns
+// when it encounters an unknown field while decoding strictly.
+const unknownFieldPrefix = "json: unknown field "
+
+// UnknownFieldError is returned by Decode when unknown fields are disallowed
+// and the input contains a field the target does not know about.
+type UnknownFieldError struct {
+	// Err is the original error returned by the strict decoder.
+	Err error
+
+	// Field describes the paths of the offending field within the object.
+	Field *apis.FieldError
+}
+
+var _ error = (*UnknownFieldError)(nil)
+
+// Error implements error
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Err, e.Field)
+}
+
+// Unwrap returns the original decoding error.
+func (e *UnknownFieldError) Unwrap() error {
+	return e.Err
+}
+
 var (
 	// Unmarshal is an alias for json.Unmarshal
 	Unmarshal = json.Unmarshal
@@ -55,7 +88,7 @@ func Decode(bites []byte, target interface{}, disallowUnknownFields bool) error
 		// If for some reason the json does not have metadata continue with normal parsing
 		dec := json.NewDecoder(bytes.NewReader(bites))
 		dec.DisallowUnknownFields()
-		return dec.Decode(target)
+		return unknownFieldError(dec.Decode(target), bites, target)
 	}
 
 	before := bites[:start]
@@ -71,7 +104,7 @@ func Decode(bites []byte, target interface{}, disallowUnknownFields bool) error
 
 	dec.DisallowUnknownFields()
 	if err := dec.Decode(target); err != nil {
-		return err
+		return unknownFieldError(err, bites, target)
 	}
 
 	// Now we parse just the metadata
@@ -125,3 +158,84 @@ func findMetadataOffsets(bites []byte) (start, end int64, err error) {
 	}
 	return -1, -1, nil
 }
+
+// unknownFieldError decorates strict decoding errors about unknown fields
+// with the paths at which the offending field appears in bites. Other errors
+// are returned as is.
+func unknownFieldError(err error, bites []byte, target interface{}) error {
+	if err == nil || !strings.HasPrefix(err.Error(), unknownFieldPrefix) {
+		return err
+	}
+	field, uerr := strconv.Unquote(strings.TrimPrefix(err.Error(), unknownFieldPrefix))
+	if uerr != nil {
+		return err
+	}
+	paths := unknownFieldPaths(bites, target, field)
+	if len(paths) == 0 {
+		return err
+	}
+	return &UnknownFieldError{
+		Err:   err,
+		Field: apis.ErrDisallowedFields(paths...),
+	}
+}
+
+// unknownFieldPaths finds where field occurs in bites without being retained
+// when bites is round-tripped through a fresh instance of target's type.
+func unknownFieldPaths(bites []byte, target interface{}, field string) []string {
+	t := reflect.TypeOf(target)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil
+	}
+	known := reflect.New(t.Elem()).Interface()
+	if err := json.Unmarshal(bites, known); err != nil {
+		return nil
+	}
+	roundTripped, err := json.Marshal(known)
+	if err != nil {
+		return nil
+	}
+
+	var in, out interface{}
+	if err := json.Unmarshal(bites, &in); err != nil {
+		return nil
+	}
+	if err := json.Unmarshal(roundTripped, &out); err != nil {
+		return nil
+	}
+
+	var paths []string
+	var walk func(path string, in, out interface{})
+	walk = func(path string, in, out interface{}) {
+		switch in := in.(type) {
+		case map[string]interface{}:
+			out, _ := out.(map[string]interface{})
+			for k, v := range in {
+				// Metadata is opaque to us and validated by the API server.
+				if path == "" && k == "metadata" {
+					continue
+				}
+				p := k
+				if path != "" {
+					p = path + "." + k
+				}
+				if ov, ok := out[k]; ok {
+					walk(p, v, ov)
+				} else if k == field {
+					paths = append(paths, p)
+				}
+			}
+		case []interface{}:
+			out, _ := out.([]interface{})
+			for i, v := range in {
+				if i < len(out) {
+					walk(fmt.Sprintf("%s[%d]", path, i), v, out[i])
+				}
+			}
+		}
+	}
+	walk("", in, out)
+
+	sort.Strings(paths)
+	return paths
+}
@@ -180,6 +180,49 @@ func TestDecode_AllowUnknownFields(t *testing.T) {
 	}
 }
 
+func TestDecode_UnknownFieldPaths(t *testing.T) {
+	type item struct {
+		Name string `json:"name,omitempty"`
+	}
+	type nested struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Spec              struct {
+			Items []item `json:"items,omitempty"`
+		} `json:"spec"`
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{{
+		name:  "top level",
+		input: `{"metadata":{"name":"some-name"},"bomba":"boom"}`,
+		want:  `json: unknown field "bomba": must not set the field(s): bomba`,
+	}, {
+		name:  "nested in a list",
+		input: `{"metadata":{"name":"some-name"},"spec":{"items":[{"name":"a"},{"name":"b","bomba":"boom"}]}}`,
+		want:  `json: unknown field "bomba": must not set the field(s): spec.items[1].bomba`,
+	}, {
+		name:  "multiple occurrences",
+		input: `{"spec":{"items":[{"bomba":"boom"},{"bomba":"boom"}]}}`,
+		want:  `json: unknown field "bomba": must not set the field(s): spec.items[0].bomba, spec.items[1].bomba`,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Decode([]byte(tc.input), &nested{}, true)
+			var ufe *UnknownFieldError
+			if !errors.As(err, &ufe) {
+				t.Fatalf("Decode() = %v, wanted an UnknownFieldError", err)
+			}
+			if got := err.Error(); got != tc.want {
+				t.Errorf("Decode() = %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
 // Note: this test is paired with the failingFixture and knows
 // that the implementation of Decode parses the json in two passes
 // the first being with an empty metadata '{}' - the second being the real
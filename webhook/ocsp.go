@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// ocspRefreshWindow is how long before its NextUpdate the stapled OCSP
+	// response is refreshed. Responses without a NextUpdate are refreshed
+	// after as long.
+	ocspRefreshWindow = time.Hour
+
+	// ocspRetryInterval is how long to wait before fetching the OCSP
+	// response again after a failure.
+	ocspRetryInterval = time.Minute
+)
+
+// OCSPResponseFromFile returns a function suitable for Options.OCSPResponse,
+// which reads the DER encoded OCSP response from the file at path, e.g. kept
+// up to date by an external agent.
+func OCSPResponseFromFile(path string) func(*tls.Certificate) ([]byte, error) {
+	return func(*tls.Certificate) ([]byte, error) {
+		return os.ReadFile(path)
+	}
+}
+
+// ocspStapler staples the OCSP response it fetches to the serving
+// certificate, and refreshes it ahead of its expiry.
+type ocspStapler struct {
+	fetch  func(*tls.Certificate) ([]byte, error)
+	logger *zap.SugaredLogger
+
+	mu sync.Mutex
+	// leaf is the DER encoded certificate the staple is for.
+	leaf      []byte
+	staple    []byte
+	refreshAt time.Time
+	expiresAt time.Time
+}
+
+// wrap returns a tls.Config.GetCertificate stapling the OCSP response to the
+// certificates returned by getCertificate.
+func (s *ocspStapler) wrap(getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, err := getCertificate(hello)
+		if err != nil || cert == nil || len(cert.Certificate) == 0 {
+			return cert, err
+		}
+		staple := s.stapleFor(cert)
+		if staple == nil {
+			return cert, nil
+		}
+		// Don't modify the certificate, which may be cached by getCertificate.
+		stapled := *cert
+		stapled.OCSPStaple = staple
+		return &stapled, nil
+	}
+}
+
+// stapleFor returns the OCSP response to staple to cert, if any, fetching it
+// if it is due for a refresh.
+func (s *ocspStapler) stapleFor(cert *tls.Certificate) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if !bytes.Equal(s.leaf, cert.Certificate[0]) {
+		// The certificate changed, e.g. it was rotated, so the previous
+		// response no longer applies.
+		s.leaf, s.staple = cert.Certificate[0], nil
+		s.refreshAt, s.expiresAt = time.Time{}, time.Time{}
+	}
+
+	if !now.Before(s.refreshAt) {
+		if err := s.refresh(cert, now); err != nil {
+			s.logger.Errorw("failed to refresh the OCSP response", zap.Error(err))
+			s.refreshAt = now.Add(ocspRetryInterval)
+		}
+	}
+
+	if !s.expiresAt.IsZero() && !now.Before(s.expiresAt) {
+		// Don't staple an expired response, clients would reject it.
+		return nil
+	}
+	return s.staple
+}
+
+// refresh fetches and validates the OCSP response for cert.
+func (s *ocspStapler) refresh(cert *tls.Certificate, now time.Time) error {
+	der, err := s.fetch(cert)
+	if err != nil {
+		return err
+	}
+	resp, err := ocsp.ParseResponse(der, nil)
+	if err != nil {
+		return fmt.Errorf("failed to parse the OCSP response: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse the serving certificate: %w", err)
+	}
+	if resp.SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+		return fmt.Errorf("the OCSP response is for serial number %v, not %v", resp.SerialNumber, leaf.SerialNumber)
+	}
+
+	s.staple, s.expiresAt = der, resp.NextUpdate
+	if resp.NextUpdate.IsZero() {
+		s.refreshAt = now.Add(ocspRefreshWindow)
+	} else if s.refreshAt = resp.NextUpdate.Add(-ocspRefreshWindow); s.refreshAt.Before(now) {
+		// The response is already due for a refresh, don't fetch it again
+		// on every handshake.
+		s.refreshAt = now.Add(ocspRetryInterval)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+	logtesting "knative.dev/pkg/logging/testing"
+)
+
+func TestOCSPStapling(t *testing.T) {
+	ca, caKey := makeTestCert(t, nil, nil)
+	leaf, leafKey := makeTestCert(t, ca, caKey)
+	cert := &tls.Certificate{Certificate: [][]byte{leaf.Raw}, PrivateKey: leafKey}
+
+	now := time.Now()
+	resp, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: leaf.SerialNumber,
+		ThisUpdate:   now.Add(-time.Minute),
+		NextUpdate:   now.Add(24 * time.Hour),
+	}, caKey)
+	if err != nil {
+		t.Fatal("CreateResponse() =", err)
+	}
+	respFile := filepath.Join(t.TempDir(), "ocsp.der")
+	if err := os.WriteFile(respFile, resp, 0600); err != nil {
+		t.Fatal("WriteFile() =", err)
+	}
+
+	fetches := 0
+	stapler := &ocspStapler{
+		fetch: func(c *tls.Certificate) ([]byte, error) {
+			fetches++
+			return OCSPResponseFromFile(respFile)(c)
+		},
+		logger: logtesting.TestLogger(t),
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: stapler.wrap(func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return cert, nil
+		}),
+	})
+	if err != nil {
+		t.Fatal("Listen() =", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.(*tls.Conn).Handshake()
+			c.Close()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	for i := 0; i < 2; i++ {
+		c, err := tls.Dial("tcp", l.Addr().String(), &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    roots,
+			ServerName: "webhook.example.com",
+		})
+		if err != nil {
+			t.Fatal("Dial() =", err)
+		}
+		if got := c.ConnectionState().OCSPResponse; !bytes.Equal(got, resp) {
+			t.Errorf("Handshake #%d stapled OCSP response %x, wanted %x", i+1, got, resp)
+		}
+		c.Close()
+	}
+
+	// The response is cached until it nears its NextUpdate.
+	if fetches != 1 {
+		t.Errorf("Fetched the OCSP response %d times, wanted 1", fetches)
+	}
+	// The served certificate isn't modified.
+	if cert.OCSPStaple != nil {
+		t.Error("The served certificate was modified")
+	}
+}
+
+func TestOCSPStaplingRejectsOtherCertificates(t *testing.T) {
+	ca, caKey := makeTestCert(t, nil, nil)
+	leaf, leafKey := makeTestCert(t, ca, caKey)
+	cert := &tls.Certificate{Certificate: [][]byte{leaf.Raw}, PrivateKey: leafKey}
+
+	// The response is for the CA, e.g. a stale file after a rotation.
+	resp, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: ca.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(24 * time.Hour),
+	}, caKey)
+	if err != nil {
+		t.Fatal("CreateResponse() =", err)
+	}
+	stapler := &ocspStapler{
+		fetch:  func(*tls.Certificate) ([]byte, error) { return resp, nil },
+		logger: logtesting.TestLogger(t),
+	}
+	got, err := stapler.wrap(func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return cert, nil
+	})(nil)
+	if err != nil {
+		t.Fatal("GetCertificate() =", err)
+	}
+	if got.OCSPStaple != nil {
+		t.Error("Stapled the OCSP response of another certificate")
+	}
+}
+
+// makeTestCert makes a certificate for webhook.example.com signed by parent,
+// or a self-signed CA if parent is nil.
+func makeTestCert(t *testing.T, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey() =", err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal("rand.Int() =", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "webhook.example.com"},
+		DNSNames:     []string{"webhook.example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal("CreateCertificate() =", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal("ParseCertificate() =", err)
+	}
+	return cert, key
+}
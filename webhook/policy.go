@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+)
+
+// PolicyProfile names a set of the failure policy, timeout and side effects
+// of a webhook (see Options.PolicyProfile).
+type PolicyProfile string
+
+const (
+	// PolicyProfileStrict fails the requests that the webhook didn't admit
+	// within 10 seconds.
+	PolicyProfileStrict PolicyProfile = "Strict"
+
+	// PolicyProfileLenient lets through the requests that the webhook didn't
+	// admit within 30 seconds, so that an unavailable webhook doesn't block
+	// the cluster operations.
+	PolicyProfileLenient PolicyProfile = "Lenient"
+)
+
+// WebhookPolicy is the failure policy, timeout and side effects of a webhook.
+// Nil fields are left as configured on the webhook.
+type WebhookPolicy struct {
+	FailurePolicy  *admissionregistrationv1.FailurePolicyType
+	TimeoutSeconds *int32
+	SideEffects    *admissionregistrationv1.SideEffectClass
+}
+
+// Policy returns the policy that the profile expands to. The empty profile
+// expands to the empty policy.
+func (p PolicyProfile) Policy() (WebhookPolicy, error) {
+	var (
+		failurePolicy admissionregistrationv1.FailurePolicyType
+		timeout       int32
+	)
+	switch p {
+	case "":
+		return WebhookPolicy{}, nil
+	case PolicyProfileStrict:
+		failurePolicy, timeout = admissionregistrationv1.Fail, 10
+	case PolicyProfileLenient:
+		failurePolicy, timeout = admissionregistrationv1.Ignore, 30
+	default:
+		return WebhookPolicy{}, fmt.Errorf("unknown policy profile %q", p)
+	}
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	return WebhookPolicy{
+		FailurePolicy:  &failurePolicy,
+		TimeoutSeconds: &timeout,
+		SideEffects:    &sideEffects,
+	}, nil
+}
+
+// WebhookPolicy returns the policy of Options.PolicyProfile, with the
+// non-nil fields of Options.PolicyOverrides taking precedence.
+func (o *Options) WebhookPolicy() (WebhookPolicy, error) {
+	policy, err := o.PolicyProfile.Policy()
+	if err != nil {
+		return WebhookPolicy{}, err
+	}
+	if o.PolicyOverrides.FailurePolicy != nil {
+		policy.FailurePolicy = o.PolicyOverrides.FailurePolicy
+	}
+	if o.PolicyOverrides.TimeoutSeconds != nil {
+		policy.TimeoutSeconds = o.PolicyOverrides.TimeoutSeconds
+	}
+	if o.PolicyOverrides.SideEffects != nil {
+		policy.SideEffects = o.PolicyOverrides.SideEffects
+	}
+	return policy, nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"knative.dev/pkg/ptr"
+)
+
+func TestWebhookPolicy(t *testing.T) {
+	fail, ignore := admissionregistrationv1.Fail, admissionregistrationv1.Ignore
+	none := admissionregistrationv1.SideEffectClassNone
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    WebhookPolicy
+		wantErr bool
+	}{{
+		name: "no profile",
+	}, {
+		name: "strict",
+		opts: Options{PolicyProfile: PolicyProfileStrict},
+		want: WebhookPolicy{
+			FailurePolicy:  &fail,
+			TimeoutSeconds: ptr.Int32(10),
+			SideEffects:    &none,
+		},
+	}, {
+		name: "lenient",
+		opts: Options{PolicyProfile: PolicyProfileLenient},
+		want: WebhookPolicy{
+			FailurePolicy:  &ignore,
+			TimeoutSeconds: ptr.Int32(30),
+			SideEffects:    &none,
+		},
+	}, {
+		name: "strict with overrides",
+		opts: Options{
+			PolicyProfile: PolicyProfileStrict,
+			PolicyOverrides: WebhookPolicy{
+				FailurePolicy:  &ignore,
+				TimeoutSeconds: ptr.Int32(5),
+			},
+		},
+		want: WebhookPolicy{
+			FailurePolicy:  &ignore,
+			TimeoutSeconds: ptr.Int32(5),
+			SideEffects:    &none,
+		},
+	}, {
+		name: "overrides only",
+		opts: Options{
+			PolicyOverrides: WebhookPolicy{TimeoutSeconds: ptr.Int32(5)},
+		},
+		want: WebhookPolicy{TimeoutSeconds: ptr.Int32(5)},
+	}, {
+		name:    "unknown profile",
+		opts:    Options{PolicyProfile: "Relaxed"},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.opts.WebhookPolicy()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("WebhookPolicy() = %v, wanted error: %v", err, test.wantErr)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Error("WebhookPolicy (-got, +want):", cmp.Diff(got, test.want))
+			}
+		})
+	}
+}
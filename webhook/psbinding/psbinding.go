@@ -340,7 +340,7 @@ func (ac *Reconciler) reconcileMutatingWebhook(ctx context.Context, caCert []byt
 			continue
 		}
 		cur := &current.Webhooks[i]
-		selector := webhook.EnsureLabelSelectorExpressions(cur.NamespaceSelector, &ac.selector)
+		selector := webhook.UpdateLabelSelectorExpressions(cur.NamespaceSelector, &ac.selector)
 
 		cur.MatchPolicy = &matchPolicy
 		cur.Rules = rules
@@ -0,0 +1,51 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcesemantics
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/webhook"
+)
+
+// RequestKind returns the GroupVersionKind of the object of an admission
+// request.
+func RequestKind(req *admissionv1.AdmissionRequest) schema.GroupVersionKind {
+	// Why, oh why are these different types...
+	return schema.GroupVersionKind{
+		Group:   req.Kind.Group,
+		Version: req.Kind.Version,
+		Kind:    req.Kind.Kind,
+	}
+}
+
+// UnregisteredKindResponse returns the response to an admission request for
+// a kind no handler is registered for, which is a denial if deny is true and
+// an allowance otherwise. Since such requests mean the rules of the webhook
+// configuration are out of sync with the handlers, the kind is logged.
+func UnregisteredKindResponse(ctx context.Context, gvk schema.GroupVersionKind, deny bool) *admissionv1.AdmissionResponse {
+	logging.FromContext(ctx).Warnw("Received an admission request for an unregistered kind",
+		zap.Stringer("gvk", gvk), zap.Bool("denied", deny))
+	if deny {
+		return webhook.MakeErrorStatus("unhandled kind: %v", gvk)
+	}
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"context"
+	"net/http"
+)
+
+// httpClientKey is used as the key for associating an HTTP client with a
+// context.Context.
+type httpClientKey struct{}
+
+// WithHTTPClient associates the HTTP client with the returned context, for
+// the conversions to make their outbound calls with, e.g. to a sidecar
+// performing the conversion.
+func WithHTTPClient(ctx context.Context, client *http.Client) context.Context {
+	return context.WithValue(ctx, httpClientKey{}, client)
+}
+
+// GetHTTPClient retrieves the HTTP client associated with the given context
+// via WithHTTPClient (above), or http.DefaultClient if there is none.
+// The ConvertTo and ConvertFrom methods of the ConvertibleObjects should make
+// their outbound calls with it, so that the client of their
+// GroupKindConversion is used.
+func GetHTTPClient(ctx context.Context) *http.Client {
+	if client, ok := ctx.Value(httpClientKey{}).(*http.Client); ok && client != nil {
+		return client
+	}
+	return http.DefaultClient
+}
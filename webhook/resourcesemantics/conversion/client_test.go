@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apixv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/network"
+	"knative.dev/pkg/webhook/resourcesemantics/conversion/internal"
+)
+
+// sidecarResource is a hub converting the property of the resources through
+// a sidecar.
+type sidecarResource struct {
+	metav1.TypeMeta `json:",inline"`
+	Spec            internal.Spec `json:"spec"`
+
+	sidecarURL string
+}
+
+func (r *sidecarResource) DeepCopyObject() runtime.Object {
+	c := *r
+	return &c
+}
+
+func (r *sidecarResource) ConvertTo(_ context.Context, to apis.Convertible) error {
+	sink, ok := to.(*internal.V1Resource)
+	if !ok {
+		return fmt.Errorf("unsupported type %T", to)
+	}
+	sink.Spec.Property = r.Spec.Property
+	return nil
+}
+
+func (r *sidecarResource) ConvertFrom(ctx context.Context, from apis.Convertible) error {
+	source, ok := from.(*internal.V1Resource)
+	if !ok {
+		return fmt.Errorf("unsupported type %T", from)
+	}
+	resp, err := GetHTTPClient(ctx).Post(r.sidecarURL, "text/plain", strings.NewReader(source.Spec.Property))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("the sidecar responded with status %d", resp.StatusCode)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	r.Spec.Property = string(b)
+	return nil
+}
+
+func TestConversionHTTPClient(t *testing.T) {
+	const header = "X-Injected-Client"
+	sidecar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(header) == "" {
+			http.Error(w, "not called with the injected client", http.StatusBadRequest)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprint(w, strings.ToUpper(string(b)))
+	}))
+	t.Cleanup(sidecar.Close)
+
+	client := &http.Client{
+		Transport: network.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r = r.Clone(r.Context())
+			r.Header.Set(header, "true")
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}
+	ctx, conversion := newConversionWithKinds(t, map[schema.GroupKind]GroupKindConversion{
+		testGK: {
+			DefinitionName: "resource.webhook.pkg.knative.dev",
+			HubVersion:     "sidecar",
+			Zygotes: map[string]ConvertibleObject{
+				"sidecar": &sidecarResource{sidecarURL: sidecar.URL},
+				"v1":      &internal.V1Resource{},
+			},
+			HTTPClient: client,
+		},
+	})
+
+	req := &apixv1.ConversionRequest{
+		UID:               "some-uid",
+		DesiredAPIVersion: testAPIVersion("v1"),
+		Objects:           []runtime.RawExtension{toRaw(t, internal.NewV1("bing"))},
+	}
+	want := &apixv1.ConversionResponse{
+		UID:              "some-uid",
+		Result:           metav1.Status{Status: metav1.StatusSuccess},
+		ConvertedObjects: []runtime.RawExtension{toRaw(t, internal.NewV1("BING"))},
+	}
+
+	got := conversion.Convert(ctx, req)
+	if diff := cmp.Diff(want, got, cmpOpts...); diff != "" {
+		t.Error("unexpected response:", diff)
+	}
+}
+
+func TestGetHTTPClient(t *testing.T) {
+	if got := GetHTTPClient(context.Background()); got != http.DefaultClient {
+		t.Error("GetHTTPClient() without a client =", got, "wanted http.DefaultClient")
+	}
+	client := &http.Client{}
+	if got := GetHTTPClient(WithHTTPClient(context.Background(), client)); got != client {
+		t.Error("GetHTTPClient() =", got, "wanted the injected client")
+	}
+}
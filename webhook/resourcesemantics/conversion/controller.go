@@ -18,6 +18,7 @@ package conversion
 
 import (
 	"context"
+	"net/http"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -74,6 +75,13 @@ type GroupKindConversion struct {
 	// During a conversion request these zygotes will be deep copied
 	// and manipulated using the apis.Convertible interface
 	Zygotes map[string]ConvertibleObject
+
+	// HTTPClient, when set, is the client the conversions of the kind make
+	// their outbound calls with, e.g. to a sidecar performing the conversion
+	// through the backoff transports of knative.dev/pkg/network. It is
+	// available to the ConvertTo and ConvertFrom methods of the zygotes
+	// through GetHTTPClient.
+	HTTPClient *http.Client
 }
 
 // NewConversionController returns a K8s controller that will
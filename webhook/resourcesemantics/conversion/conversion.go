@@ -69,6 +69,28 @@ func (r *reconciler) Convert(
 	return res
 }
 
+// DryRun attempts to convert each of the objects to the desired API
+// version, the same way the conversion webhook would with the conversions of
+// kinds, without writing anything. This allows checking that the existing
+// objects convert cleanly before switching the storage version. It returns
+// the errors of the objects that failed to convert, indexed by their
+// position in objects.
+func DryRun(
+	ctx context.Context,
+	kinds map[schema.GroupKind]GroupKindConversion,
+	objects []runtime.RawExtension,
+	desiredAPIVersion string,
+) map[int]error {
+	r := &reconciler{kinds: kinds}
+	failures := make(map[int]error)
+	for i, obj := range objects {
+		if _, err := r.convert(ctx, obj, desiredAPIVersion); err != nil {
+			failures[i] = err
+		}
+	}
+	return failures
+}
+
 func (r *reconciler) convert(
 	ctx context.Context,
 	inRaw runtime.RawExtension,
@@ -131,6 +153,9 @@ func (r *reconciler) convert(
 		logger.Infof("Could not get Accessor for %s: %v", formatGK(inGVK.GroupKind()), err)
 	}
 	ctx = logging.WithLogger(ctx, logger)
+	if conv.HTTPClient != nil {
+		ctx = WithHTTPClient(ctx, conv.HTTPClient)
+	}
 
 	if inGVK.Version == conv.HubVersion {
 		hub = in
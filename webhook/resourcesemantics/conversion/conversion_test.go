@@ -462,6 +462,33 @@ func TestConversionFailureToConvert(t *testing.T) {
 
 }
 
+func TestDryRun(t *testing.T) {
+	// v1 => error resource => v3
+	kinds := map[schema.GroupKind]GroupKindConversion{
+		testGK: {
+			DefinitionName: "resource.webhook.pkg.knative.dev",
+			HubVersion:     "error",
+			Zygotes:        zygotes,
+		},
+	}
+	objects := []runtime.RawExtension{
+		toRaw(t, internal.NewV1("bing")),
+		toRaw(t, internal.NewV1(internal.ErrorConvertTo)),
+		toRaw(t, internal.NewV1("bang")),
+	}
+
+	ctx, _ := SetupFakeContext(t)
+	got := DryRun(ctx, kinds, objects, testAPIVersion("v3"))
+	if len(got) != 1 {
+		t.Fatalf("DryRun() = %v, wanted a single failure", got)
+	}
+	if err, ok := got[1]; !ok {
+		t.Errorf("DryRun() = %v, wanted the second object to fail", got)
+	} else if !strings.HasPrefix(err.Error(), "conversion failed") {
+		t.Errorf("expected error to start with 'conversion failed' got %q", err)
+	}
+}
+
 func TestConversionFailureInvalidDesiredAPIVersion(t *testing.T) {
 	tests := []struct {
 		name    string
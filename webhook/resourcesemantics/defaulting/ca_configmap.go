@@ -0,0 +1,60 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package defaulting
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+)
+
+// WatchCABundle has the admission controller returned by
+// NewAdmissionController configure its webhook configuration to trust the
+// PEM encoded CA cert bundle under key in the named ConfigMap, in the
+// namespace watched by cmw, rather than the CA cert of the webhook secret or
+// of webhook.Options.CACertFile. This decouples the CA from the serving
+// secret, e.g. for clusters publishing it in the
+// extension-apiserver-authentication ConfigMap of kube-system. The webhook
+// configuration is reconciled again whenever the ConfigMap changes.
+func WatchCABundle(impl *controller.Impl, cmw configmap.Watcher, name, key string) {
+	ac := impl.Reconciler.(*reconciler)
+	ac.watchCABundle(cmw, name, key, func() { impl.EnqueueKey(ac.key) })
+}
+
+func (ac *reconciler) watchCABundle(cmw configmap.Watcher, name, key string, enqueue func()) {
+	ac.caConfigMapMu.Lock()
+	ac.caConfigMapName, ac.caConfigMapKey = name, key
+	ac.caConfigMapMu.Unlock()
+
+	cmw.Watch(name, func(cm *corev1.ConfigMap) {
+		caCert := []byte(cm.Data[key])
+		ac.caConfigMapMu.Lock()
+		ac.configMapCACert = caCert
+		ac.caConfigMapMu.Unlock()
+		enqueue()
+	})
+}
+
+// configMapCA returns the CA cert bundle read from the ConfigMap watched
+// through WatchCABundle, along with the name and key of the ConfigMap,
+// which are empty if none is watched.
+func (ac *reconciler) configMapCA() (caCert []byte, name, key string) {
+	ac.caConfigMapMu.RLock()
+	defer ac.caConfigMapMu.RUnlock()
+	return ac.configMapCACert, ac.caConfigMapName, ac.caConfigMapKey
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package defaulting
+
+import (
+	"testing"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+
+	"knative.dev/pkg/configmap"
+	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
+
+	. "knative.dev/pkg/logging/testing"
+	. "knative.dev/pkg/webhook/testing"
+)
+
+func TestReconcileCABundleFromConfigMap(t *testing.T) {
+	const (
+		name, path = "foo.bar.baz", "/blah"
+		cmName     = "extension-apiserver-authentication"
+		cmKey      = "client-ca-file"
+	)
+
+	// There is no webhook secret, the CA comes from the ConfigMap alone.
+	objs := []runtime.Object{
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: system.Namespace()},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{{
+				Name: name,
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{
+						Namespace: system.Namespace(),
+						Name:      "webhook",
+					},
+				},
+			}},
+		},
+	}
+	listers := NewListers(objs)
+	client := fakekubeclientset.NewSimpleClientset(objs...)
+
+	ac := &reconciler{
+		key:          types.NamespacedName{Name: name},
+		path:         path,
+		handlers:     handlers,
+		client:       client,
+		mwhlister:    listers.GetMutatingWebhookConfigurationLister(),
+		secretlister: listers.GetSecretLister(),
+		recorder:     record.NewFakeRecorder(10),
+		secretName:   "webhook-secret",
+		clock:        clock.RealClock{},
+		stats:        newTestStatsReporter(),
+	}
+	ac.Promote(pkgreconciler.UniversalBucket(), func(pkgreconciler.Bucket, types.NamespacedName) {})
+
+	enqueued := 0
+	cmw := &configmap.ManualWatcher{Namespace: "kube-system"}
+	ac.watchCABundle(cmw, cmName, cmKey, func() { enqueued++ })
+
+	// reconcile reconciles the webhook configuration with the given CA
+	// ConfigMap data, and returns the resulting CA bundle.
+	reconcile := func(data map[string]string) ([]byte, error) {
+		t.Helper()
+		cmw.OnChange(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cmName,
+				Namespace: "kube-system",
+			},
+			Data: data,
+		})
+		if err := ac.Reconcile(TestContextWithLogger(t), system.Namespace()+"/does not matter"); err != nil {
+			return nil, err
+		}
+		mwh, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(
+			TestContextWithLogger(t), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal("Get() =", err)
+		}
+		// Feed the update back to the lister, as the informer would.
+		if err := listers.IndexerFor(mwh).Update(mwh); err != nil {
+			t.Fatal("Update() =", err)
+		}
+		return mwh.Webhooks[0].ClientConfig.CABundle, nil
+	}
+
+	if got, err := reconcile(map[string]string{cmKey: "ca-1"}); err != nil {
+		t.Fatal("Reconcile() =", err)
+	} else if want := "ca-1"; string(got) != want {
+		t.Errorf("CABundle = %q, wanted %q", got, want)
+	}
+
+	// Updating the ConfigMap updates the CA bundle.
+	if got, err := reconcile(map[string]string{cmKey: "ca-2"}); err != nil {
+		t.Fatal("Reconcile() =", err)
+	} else if want := "ca-2"; string(got) != want {
+		t.Errorf("CABundle = %q, wanted %q", got, want)
+	}
+
+	// The CA cert is required.
+	if _, err := reconcile(map[string]string{"other-key": "ca-3"}); err == nil {
+		t.Error("Reconcile() = nil, wanted an error for the missing key")
+	}
+
+	if enqueued != 3 {
+		t.Errorf("Enqueued %d times, wanted 3", enqueued)
+	}
+}
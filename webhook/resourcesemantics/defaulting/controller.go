@@ -18,6 +18,7 @@ package defaulting
 
 import (
 	"context"
+	"time"
 
 	// Injection stuff
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
@@ -26,9 +27,17 @@ import (
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
 
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	admissionregistrationinformers "k8s.io/client-go/informers/admissionregistration/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/system"
@@ -36,6 +45,22 @@ import (
 	"knative.dev/pkg/webhook/resourcesemantics"
 )
 
+// reconcileJitter is the maximum jitter factor applied to the period
+// with which the webhook configuration is reconciled.
+const reconcileJitter = 0.2
+
+// The bounds of the backoff with which failed reconciles of the webhook
+// configuration are retried. Persistent failures are reported once per
+// failureMaxDelay.
+const (
+	failureBaseDelay = 500 * time.Millisecond
+	failureMaxDelay  = 5 * time.Minute
+)
+
+// pruneRulesTimeout bounds how long pruning the rules of the webhook may
+// delay the shutdown of the leader.
+const pruneRulesTimeout = 10 * time.Second
+
 // NewAdmissionController constructs a reconciler
 func NewAdmissionController(
 	ctx context.Context,
@@ -47,12 +72,44 @@ func NewAdmissionController(
 ) *controller.Impl {
 
 	client := kubeclient.Get(ctx)
-	mwhInformer := mwhinformer.Get(ctx)
 	secretInformer := secretinformer.Get(ctx)
 	options := webhook.GetOptions(ctx)
 
+	name += options.ConfigurationNameSuffix
 	key := types.NamespacedName{Name: name}
 
+	var (
+		mwhInformer admissionregistrationinformers.MutatingWebhookConfigurationInformer
+		mwhFactory  informers.SharedInformerFactory
+	)
+	if options.ConfigurationClient != nil {
+		// Watch the configuration where it is managed, rather than through
+		// the informers of the cluster the webhook runs in.
+		mwhFactory = informers.NewSharedInformerFactoryWithOptions(options.ConfigurationClient, 0,
+			informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+				opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+			}))
+		mwhInformer = mwhFactory.Admissionregistration().V1().MutatingWebhookConfigurations()
+	} else {
+		mwhInformer = mwhinformer.Get(ctx)
+	}
+
+	reviewVersions := options.AdmissionReviewVersions
+	if len(reviewVersions) == 0 {
+		reviewVersions = webhook.DefaultAdmissionReviewVersions
+	}
+
+	policy, err := options.WebhookPolicy()
+	if err != nil {
+		panic(err)
+	}
+
+	// Fail fast on the handlers that would fail the admission of every object
+	// of their kinds.
+	if err := resourcesemantics.ValidateHandlers(handlers); err != nil {
+		panic(err)
+	}
+
 	// This not ideal, we are using a variadic argument to effectively make callbacks optional
 	// This allows this addition to be non-breaking to consumers of /pkg
 	// TODO: once all sub-repos have adopted this, we might move this back to a traditional param.
@@ -66,6 +123,17 @@ func NewAdmissionController(
 		panic("NewAdmissionController may not be called with multiple callback maps")
 	}
 
+	// The options may not have a reporter yet, webhook.New only defaults it
+	// once the admission controllers are constructed.
+	stats := options.StatsReporter
+	if stats == nil {
+		reporter, err := webhook.NewStatsReporter()
+		if err != nil {
+			panic(err)
+		}
+		stats = reporter
+	}
+
 	wh := &reconciler{
 		LeaderAwareFuncs: pkgreconciler.LeaderAwareFuncs{
 			// Have this reconciler enqueue our singleton whenever it becomes leader.
@@ -83,15 +151,44 @@ func NewAdmissionController(
 		withContext:           wc,
 		disallowUnknownFields: disallowUnknownFields,
 		secretName:            options.SecretName,
+		serviceName:           options.ServiceName,
+		serviceNamespace:      options.ServiceNamespace,
+		servicePort:           options.ServicePort,
+		serverSideApply:       options.ServerSideApply,
+		caCertFile:            options.CACertFile,
+		canaryCohorts:         options.CanaryCohorts,
+		labels:                options.ConfigurationLabels,
+		annotations:           options.ConfigurationAnnotations,
+		owner:                 options.ConfigurationOwner,
+		caBundleOverlap:       options.CABundleOverlap,
+		reviewVersions:        reviewVersions,
+		denyUnregisteredKinds: options.DenyUnregisteredKinds,
+		policy:                policy,
+		clock:                 clock.RealClock{},
+		status:                options.Status,
+		stats:                 stats,
 
-		client:       client,
-		mwhlister:    mwhInformer.Lister(),
-		secretlister: secretInformer.Lister(),
+		client:              client,
+		configurationClient: options.ConfigurationClient,
+		mwhlister:           mwhInformer.Lister(),
+		secretlister:        secretInformer.Lister(),
+		secretsSynced:       secretInformer.Informer().HasSynced,
+	}
+	if mwhFactory != nil {
+		// The informers of the configuration client are not started, nor
+		// waited for, along with the injected ones.
+		wh.mwhSynced = mwhInformer.Informer().HasSynced
 	}
 
 	logger := logging.FromContext(ctx)
 	const queueName = "DefaultingWebhook"
-	c := controller.NewContext(ctx, wh, controller.ControllerOptions{WorkQueueName: queueName, Logger: logger.Named(queueName)})
+	wh.recorder = webhook.NewEventRecorder(ctx, queueName)
+	c := controller.NewContext(ctx, wh, controller.ControllerOptions{
+		WorkQueueName: queueName,
+		Logger:        logger.Named(queueName),
+		RateLimiter:   workqueue.NewItemExponentialFailureRateLimiter(failureBaseDelay, failureMaxDelay),
+	})
+	wh.enqueueAfter = c.EnqueueKeyAfter
 
 	// Reconcile when the named MutatingWebhookConfiguration changes.
 	mwhInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
@@ -100,6 +197,16 @@ func NewAdmissionController(
 		// the named MWH resource.
 		Handler: controller.HandleAll(c.Enqueue),
 	})
+	if mwhFactory != nil {
+		mwhFactory.Start(ctx.Done())
+	}
+
+	if options.CACertFile != "" {
+		// Reconcile when the CA cert file changes.
+		if err := webhook.WatchFiles(ctx, func() { c.EnqueueKey(key) }, options.CACertFile); err != nil {
+			logger.Errorw("Failed to watch the CA cert file, its changes are only picked up periodically", zap.Error(err))
+		}
+	}
 
 	// Reconcile when the cert bundle changes.
 	secretInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
@@ -109,5 +216,89 @@ func NewAdmissionController(
 		Handler: controller.HandleAll(c.Enqueue),
 	})
 
+	if options.FailurePolicyFallback {
+		// Reconcile when the endpoints of the webhook's service change.
+		ns := options.ServiceNamespace
+		if ns == "" {
+			ns = system.Namespace()
+		}
+		factory := informers.NewSharedInformerFactoryWithOptions(client, 0, informers.WithNamespace(ns))
+		endpointsInformer := factory.Core().V1().Endpoints()
+		handler := cache.ResourceEventHandler(controller.HandleAll(c.Enqueue))
+		if options.ServiceName != "" {
+			handler = cache.FilteringResourceEventHandler{
+				FilterFunc: controller.FilterWithName(options.ServiceName),
+				Handler:    handler,
+			}
+		}
+		endpointsInformer.Informer().AddEventHandler(handler)
+		wh.endpointslister = endpointsInformer.Lister()
+		wh.endpointsSynced = endpointsInformer.Informer().HasSynced
+		factory.Start(ctx.Done())
+	}
+
+	if options.PruneRulesOnUninstall {
+		wh.pruneRulesOnUninstall = true
+		wh.PromoteFunc = func(bkt pkgreconciler.Bucket, enq func(pkgreconciler.Bucket, types.NamespacedName)) error {
+			if bkt.Has(key) {
+				wh.leading.Store(true)
+			}
+			enq(bkt, key)
+			return nil
+		}
+		wh.DemoteFunc = func(bkt pkgreconciler.Bucket) {
+			if bkt.Has(key) {
+				wh.demote(logging.WithLogger(context.Background(), logger.Named(queueName)))
+			}
+		}
+
+		// Reconcile when the system namespace changes, e.g. as it is being
+		// deleted.
+		factory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+			informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+				opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", system.Namespace()).String()
+			}))
+		factory.Core().V1().Namespaces().Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+			FilterFunc: controller.FilterWithName(system.Namespace()),
+			Handler:    controller.HandleAll(c.Enqueue),
+		})
+		factory.Start(ctx.Done())
+	}
+
+	period := options.ReconcilePeriod
+	if period == 0 {
+		period = webhook.DefaultReconcilePeriod
+	}
+	if period > 0 {
+		// Reconcile periodically, so that drift introduced by other actors
+		// is corrected even when no events are received.
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait.Jitter(period, reconcileJitter)):
+					c.EnqueueKey(key)
+				}
+			}
+		}()
+	}
+
 	return c
 }
+
+// SetHandlers replaces the handlers of the admission controller returned by
+// NewAdmissionController, e.g. as CRDs are enabled or disabled at runtime,
+// and reconciles the rules of its webhook configuration accordingly.
+func SetHandlers(impl *controller.Impl, handlers map[schema.GroupVersionKind]resourcesemantics.GenericCRD) {
+	ac := impl.Reconciler.(*reconciler)
+	ac.setHandlers(handlers)
+	impl.EnqueueKey(ac.key)
+}
+
+// RegisteredGVKs returns the kinds the admission controller returned by
+// NewAdmissionController is registered for, i.e. those of its handlers and
+// callbacks, sorted by group, version and kind, e.g. for a debug endpoint.
+func RegisteredGVKs(impl *controller.Impl) []schema.GroupVersionKind {
+	return impl.Reconciler.(*reconciler).registeredGVKs()
+}
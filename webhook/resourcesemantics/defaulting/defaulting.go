@@ -17,29 +17,42 @@ limitations under the License.
 package defaulting
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gobuffalo/flect"
+	"go.opencensus.io/trace"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"gomodules.xyz/jsonpatch/v2"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
+	admissionclient "k8s.io/client-go/kubernetes/typed/admissionregistration/v1"
 	admissionlisters "k8s.io/client-go/listers/admissionregistration/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/apis/duck"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/kmap"
 	"knative.dev/pkg/kmp"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/ptr"
@@ -53,6 +66,15 @@ import (
 
 var errMissingNewObject = errors.New("the new object may not be nil")
 
+// errSecretNotSynced is returned when the webhook secret isn't found while
+// the secret informer has not synced yet, so that it may just be missing
+// from the cache.
+var errSecretNotSynced = errors.New("the secret informer has not synced yet")
+
+// informerSyncDelay is how long to wait before reconciling the webhook
+// configuration again when an informer it is read from has not synced yet.
+const informerSyncDelay = time.Second
+
 // reconciler implements the AdmissionController for resources
 type reconciler struct {
 	webhook.StatelessAdmissionImpl
@@ -60,17 +82,95 @@ type reconciler struct {
 
 	key       types.NamespacedName
 	path      string
-	handlers  map[schema.GroupVersionKind]resourcesemantics.GenericCRD
 	callbacks map[schema.GroupVersionKind]Callback
 
+	// handlersMu guards handlers, which may be replaced at runtime.
+	handlersMu sync.RWMutex
+	handlers   map[schema.GroupVersionKind]resourcesemantics.GenericCRD
+
 	withContext func(context.Context) context.Context
 
 	client       kubernetes.Interface
 	mwhlister    admissionlisters.MutatingWebhookConfigurationLister
 	secretlister corelisters.SecretLister
+	recorder     record.EventRecorder
+
+	// configurationClient, when set, is the client the webhook configuration
+	// is managed with, in lieu of client, see
+	// webhook.Options.ConfigurationClient. mwhlister then lists its
+	// configurations.
+	configurationClient kubernetes.Interface
+	// mwhSynced, when set, reports whether mwhlister is synced.
+	mwhSynced func() bool
+
+	// secretsSynced, when set, reports whether secretlister is synced.
+	secretsSynced func() bool
+
+	// endpointslister, when set, is used to relax the failure policy of the
+	// webhook while its service has no ready endpoints.
+	endpointslister corelisters.EndpointsLister
+	// endpointsSynced, when set, reports whether endpointslister is synced.
+	endpointsSynced func() bool
 
 	disallowUnknownFields bool
 	secretName            string
+	serviceName           string
+	serviceNamespace      string
+	servicePort           int32
+	serverSideApply       bool
+	caCertFile            string
+	canaryCohorts         []string
+	labels                map[string]string
+	annotations           map[string]string
+	owner                 *metav1.OwnerReference
+	caBundleOverlap       time.Duration
+	reviewVersions        []string
+	denyUnregisteredKinds bool
+	policy                webhook.WebhookPolicy
+
+	// clock is used to track the CA bundle overlap window.
+	clock clock.Clock
+	// enqueueAfter, when set, is used to revisit the webhook once the CA
+	// bundle overlap window passes.
+	enqueueAfter func(types.NamespacedName, time.Duration)
+
+	// status, when set, tracks whether the webhook is configured.
+	status *webhook.Status
+
+	// stats reports the metrics of the webhook, e.g. its drift corrections.
+	stats webhook.StatsReporter
+
+	// pruneRulesOnUninstall is whether the rules of the webhook are pruned
+	// once the system namespace is being deleted, see
+	// webhook.Options.PruneRulesOnUninstall.
+	pruneRulesOnUninstall bool
+	// leading is whether this replica leads for key, so that only the leader
+	// prunes the rules as it shuts down.
+	leading atomic.Bool
+
+	// reportedInvalid holds the messages of the events emitted for the
+	// invalid registrations, so that those are emitted once, rather than on
+	// every reconcile. It is only accessed as the webhook is reconciled.
+	reportedInvalid sets.String
+
+	// exclusionsMu guards the namespaces excluded from the webhook through
+	// a ConfigMap, and whether such a ConfigMap is watched.
+	exclusionsMu       sync.RWMutex
+	excludedNamespaces []string
+	watchesExclusions  bool
+
+	// caConfigMapMu guards the CA cert bundle read from the ConfigMap watched
+	// through WatchCABundle, and the name and key of that ConfigMap, if any.
+	caConfigMapMu   sync.RWMutex
+	caConfigMapName string
+	caConfigMapKey  string
+	configMapCACert []byte
+
+	// failuresMu guards the failed reconciles that were not reported yet
+	// and when they were last reported.
+	failuresMu         sync.Mutex
+	failures           int
+	failuresReportedAt time.Time
 }
 
 // CallbackFunc is the function to be invoked.
@@ -111,25 +211,159 @@ var _ webhook.StatelessAdmissionController = (*reconciler)(nil)
 
 // Reconcile implements controller.Reconciler
 func (ac *reconciler) Reconcile(ctx context.Context, key string) error {
-	logger := logging.FromContext(ctx)
-
 	if !ac.IsLeaderFor(ac.key) {
 		return controller.NewSkipKey(key)
 	}
 
+	if ac.mwhSynced != nil && !ac.mwhSynced() {
+		// Don't report the configuration as missing before the cache had a
+		// chance to catch up, just try again later.
+		logging.FromContext(ctx).Debug("Webhook configuration informer not synced yet, retrying later")
+		ac.revisitIn(informerSyncDelay)
+		return nil
+	}
+
+	if ac.pruneRulesOnUninstall {
+		uninstalling, err := ac.reconcileUninstall(ctx)
+		if err != nil {
+			ac.reportFailure(err)
+			return err
+		}
+		if uninstalling {
+			// Don't restore the rules pruned on the way out.
+			return nil
+		}
+	}
+
+	caCert, err := ac.fetchCACert(ctx)
+	if errors.Is(err, errSecretNotSynced) {
+		// Don't report the secret as missing on startup, just try again
+		// once the cache had a chance to catch up.
+		logging.FromContext(ctx).Debug("Secret informer not synced yet, retrying later")
+		ac.revisitIn(informerSyncDelay)
+		return nil
+	} else if err != nil {
+		ac.status.MarkWebhookNotConfigured("CACertMissing", "%v", err)
+		ac.reportFailure(err)
+		return err
+	}
+
+	// Reconcile the webhook configuration.
+	if err := ac.reconcileMutatingWebhook(ctx, caCert); err != nil {
+		ac.status.MarkWebhookNotConfigured("ReconcileFailed", "%v", err)
+		ac.reportFailure(err)
+		return err
+	}
+	ac.resetFailures()
+	if invalid := ac.invalidRegistrations(); len(invalid) > 0 {
+		msgs := make([]string, 0, len(invalid))
+		for gvk, err := range invalid {
+			msgs = append(msgs, fmt.Sprintf("%s: %v", gvk, err))
+		}
+		sort.Strings(msgs)
+		ac.status.MarkWebhookConfiguredWithReason("InvalidRegistrations",
+			"Skipped invalid registrations: %s", strings.Join(msgs, "; "))
+	} else {
+		ac.status.MarkWebhookConfigured()
+	}
+	return nil
+}
+
+// invalidRegistrations returns the registered types which cannot be
+// configured on the webhook, along with the reason why.
+func (ac *reconciler) invalidRegistrations() map[schema.GroupVersionKind]error {
+	invalid := make(map[schema.GroupVersionKind]error)
+	check := func(gvk schema.GroupVersionKind) {
+		switch {
+		case gvk.Kind == "":
+			invalid[gvk] = errors.New("missing kind")
+		case gvk.Version == "":
+			invalid[gvk] = errors.New("missing version")
+		}
+	}
+	for gvk, handler := range ac.registeredHandlers() {
+		check(gvk)
+		if _, ok := invalid[gvk]; !ok && handler == nil {
+			invalid[gvk] = errors.New("missing handler")
+		}
+	}
+	for gvk := range ac.callbacks {
+		check(gvk)
+	}
+	return invalid
+}
+
+// fetchCACert returns the CA cert bundle, from the ConfigMap watched through
+// WatchCABundle if any, or else from the CA cert file if one is configured,
+// or else from the webhook secret.
+func (ac *reconciler) fetchCACert(ctx context.Context) ([]byte, error) {
+	logger := logging.FromContext(ctx)
+
+	if caCert, name, key := ac.configMapCA(); name != "" {
+		if len(caCert) == 0 {
+			return nil, fmt.Errorf("ConfigMap %q is missing %q key", name, key)
+		}
+		return caCert, nil
+	}
+
+	if ac.caCertFile != "" {
+		caCert, err := os.ReadFile(ac.caCertFile)
+		if err != nil {
+			logger.Errorw("Error reading CA cert file", zap.Error(err))
+			return nil, err
+		}
+		return caCert, nil
+	}
+
 	// Look up the webhook secret, and fetch the CA cert bundle.
 	secret, err := ac.secretlister.Secrets(system.Namespace()).Get(ac.secretName)
-	if err != nil {
+	if apierrors.IsNotFound(err) && ac.secretsSynced != nil && !ac.secretsSynced() {
+		return nil, errSecretNotSynced
+	} else if err != nil {
 		logger.Errorw("Error fetching secret", zap.Error(err))
-		return err
+		return nil, err
 	}
 	caCert, ok := secret.Data[certresources.CACert]
 	if !ok {
-		return fmt.Errorf("secret %q is missing %q key", ac.secretName, certresources.CACert)
+		return nil, fmt.Errorf("secret %q is missing %q key", ac.secretName, certresources.CACert)
 	}
+	return caCert, nil
+}
 
-	// Reconcile the webhook configuration.
-	return ac.reconcileMutatingWebhook(ctx, caCert)
+// registeredHandlers returns the handlers currently registered. The map
+// must not be modified, it is replaced as a whole by setHandlers.
+func (ac *reconciler) registeredHandlers() map[schema.GroupVersionKind]resourcesemantics.GenericCRD {
+	ac.handlersMu.RLock()
+	defer ac.handlersMu.RUnlock()
+	return ac.handlers
+}
+
+// registeredGVKs returns the kinds of the registered handlers and callbacks,
+// sorted by group, version and kind.
+func (ac *reconciler) registeredGVKs() []schema.GroupVersionKind {
+	handlers := ac.registeredHandlers()
+	gvks := make([]schema.GroupVersionKind, 0, len(handlers)+len(ac.callbacks))
+	for gvk := range handlers {
+		gvks = append(gvks, gvk)
+	}
+	for gvk := range ac.callbacks {
+		if _, ok := handlers[gvk]; !ok {
+			gvks = append(gvks, gvk)
+		}
+	}
+	resourcesemantics.SortGVKs(gvks)
+	return gvks
+}
+
+// setHandlers replaces the registered handlers with a copy of handlers.
+func (ac *reconciler) setHandlers(handlers map[schema.GroupVersionKind]resourcesemantics.GenericCRD) {
+	copied := make(map[schema.GroupVersionKind]resourcesemantics.GenericCRD, len(handlers))
+	for gvk, handler := range handlers {
+		copied[gvk] = handler
+	}
+	ac.handlersMu.Lock()
+	defer ac.handlersMu.Unlock()
+	ac.handlers = copied
 }
 
 // Path implements AdmissionController
@@ -151,10 +385,18 @@ func (ac *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionR
 		return &admissionv1.AdmissionResponse{Allowed: true}
 	}
 
+	gvk := resourcesemantics.RequestKind(request)
+	if _, ok := ac.registeredHandlers()[gvk]; !ok {
+		if _, ok := ac.callbacks[gvk]; !ok {
+			return resourcesemantics.UnregisteredKindResponse(ctx, gvk, ac.denyUnregisteredKinds)
+		}
+	}
+
 	patchBytes, err := ac.mutate(ctx, request)
 	if err != nil {
 		return webhook.MakeErrorStatus("mutation failed: %v", err)
 	}
+
 	logger.Infof("Kind: %q PatchBytes: %v", request.Kind, string(patchBytes))
 
 	return &admissionv1.AdmissionResponse{
@@ -170,17 +412,311 @@ func (ac *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionR
 func (ac *reconciler) reconcileMutatingWebhook(ctx context.Context, caCert []byte) error {
 	logger := logging.FromContext(ctx)
 
-	rules := make([]admissionregistrationv1.RuleWithOperations, 0, len(ac.handlers))
-	gvks := make(map[schema.GroupVersionKind]struct{}, len(ac.handlers)+len(ac.callbacks))
-	for gvk := range ac.handlers {
+	// Skip the malformed registrations, rather than failing to configure
+	// the webhook for all of the other types.
+	invalid := ac.invalidRegistrations()
+	for gvk, err := range invalid {
+		logger.Errorw("Skipping invalid registration", zap.String("gvk", gvk.String()), zap.Error(err))
+	}
+	rules := ac.rules(invalid)
+
+	configuredWebhook, err := ac.mwhlister.Get(ac.key.Name)
+	if err != nil {
+		return fmt.Errorf("error retrieving webhook: %w", err)
+	}
+
+	invalidGVKs := make([]schema.GroupVersionKind, 0, len(invalid))
+	for gvk := range invalid {
+		invalidGVKs = append(invalidGVKs, gvk)
+	}
+	sort.Slice(invalidGVKs, func(i, j int) bool {
+		return invalidGVKs[i].String() < invalidGVKs[j].String()
+	})
+	reported := make(sets.String, len(invalidGVKs))
+	for _, gvk := range invalidGVKs {
+		msg := fmt.Sprintf("Skipping invalid registration of %s: %v", gvk, invalid[gvk])
+		reported.Insert(msg)
+		if !ac.reportedInvalid.Has(msg) {
+			ac.recorder.Event(configuredWebhook, corev1.EventTypeWarning, webhook.ReasonInvalidRegistration, msg)
+		}
+	}
+	// Forget the registrations that were fixed, or unregistered, so that
+	// they are reported again if they are registered broken anew.
+	ac.reportedInvalid = reported
+
+	current := configuredWebhook.DeepCopy()
+
+	if ac.owner != nil {
+		current.OwnerReferences = []metav1.OwnerReference{*ac.owner}
+	} else if ac.configurationClient == nil {
+		ns, err := ac.client.CoreV1().Namespaces().Get(ctx, system.Namespace(), metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to fetch namespace: %w", err)
+		}
+		nsRef := *metav1.NewControllerRef(ns, corev1.SchemeGroupVersion.WithKind("Namespace"))
+		current.OwnerReferences = []metav1.OwnerReference{nsRef}
+	}
+
+	// Add the managed labels and annotations, preserving foreign ones.
+	if len(ac.labels) > 0 {
+		current.Labels = kmap.Union(current.Labels, ac.labels)
+	}
+	if len(ac.annotations) > 0 {
+		current.Annotations = kmap.Union(current.Annotations, ac.annotations)
+	}
+
+	// Only the webhook named after the configuration is managed, the other
+	// entries, e.g. managed by hand, are preserved as is.
+	for i, wh := range current.Webhooks {
+		if wh.Name != current.Name {
+			continue
+		}
+
+		cur := &current.Webhooks[i]
+		cur.Rules = rules
+		cur.ClientConfig.CABundle = ac.caBundle(current, cur.ClientConfig.CABundle, caCert)
+		if err := ac.configureWebhook(cur); err != nil {
+			return err
+		}
+
+		if ac.endpointslister != nil && (ac.endpointsSynced == nil || ac.endpointsSynced()) {
+			ac.fallBackFailurePolicy(current, cur)
+		}
+	}
+
+	if diff, err := kmp.SafeDiff(configuredWebhook, current); err != nil {
+		return fmt.Errorf("error diffing webhooks: %w", err)
+	} else if diff != "" {
+		// Log the field-level changes, so that it's clear what keeps
+		// drifting when the webhook is updated over and over.
+		logger.Infow("Updating webhook", zap.String("diff", diff))
+		mwhclient := ac.mwhClient()
+		update := func() error { return updateWebhook(ctx, mwhclient, current) }
+		if ac.serverSideApply {
+			update = func() error { return applyWebhook(ctx, mwhclient, ac.appliedConfiguration(current)) }
+		}
+		if err := update(); apierrors.IsRequestEntityTooLargeError(err) {
+			// Retrying won't help until fewer types are registered, which
+			// reconciles the webhook again anyway.
+			return controller.NewPermanentError(fmt.Errorf(
+				"failed to update webhook: its %d rules exceed the API server's object size limit, "+
+					"split the registered types across several admission controllers: %w", len(rules), err))
+		} else if err != nil {
+			return fmt.Errorf("failed to update webhook: %w", err)
+		}
+		ac.recorder.Eventf(configuredWebhook, corev1.EventTypeNormal, webhook.ReasonUpdated,
+			"Updated webhook configuration %q", ac.key.Name)
+		ac.reportReconcile(ctx, webhook.ReasonUpdated)
+		if correctsDrift(configuredWebhook, current) {
+			ac.recorder.Eventf(configuredWebhook, corev1.EventTypeWarning, webhook.ReasonDriftCorrected,
+				"Reverted changes made by others to webhook configuration %q", ac.key.Name)
+			if err := ac.stats.ReportDriftCorrection(ac.key.Name); err != nil {
+				logger.Warnw("Failed to report the drift correction", zap.Error(err))
+			}
+		}
+	} else {
+		logger.Infow("Webhook is valid", zap.String("reason", webhook.ReasonAlreadyReconciled))
+		ac.reportReconcile(ctx, webhook.ReasonAlreadyReconciled)
+	}
+	return nil
+}
+
+// reportReconcile reports that the webhook was reconciled for reason.
+func (ac *reconciler) reportReconcile(ctx context.Context, reason string) {
+	if err := ac.stats.ReportReconcile(ac.key.Name, reason); err != nil {
+		logging.FromContext(ctx).Warnw("Failed to report the reconcile", zap.Error(err))
+	}
+}
+
+// correctsDrift returns whether updating the webhook configuration from
+// configured to current reverts changes made by another actor, rather than
+// setting the webhook up, or applying new certificates or registered types.
+// The webhook is set up once it has a CA bundle, after which the reconciler
+// keeps setting the fields compared here to the same values.
+func correctsDrift(configured, current *admissionregistrationv1.MutatingWebhookConfiguration) bool {
+	for i, wh := range configured.Webhooks {
+		if wh.Name != configured.Name || len(wh.ClientConfig.CABundle) == 0 {
+			continue
+		}
+		cur := current.Webhooks[i]
+		return !equality.Semantic.DeepEqual(configured.OwnerReferences, current.OwnerReferences) ||
+			!equality.Semantic.DeepEqual(wh.ClientConfig.Service, cur.ClientConfig.Service) ||
+			!equality.Semantic.DeepEqual(wh.ClientConfig.URL, cur.ClientConfig.URL) ||
+			!equality.Semantic.DeepEqual(wh.NamespaceSelector, cur.NamespaceSelector) ||
+			!equality.Semantic.DeepEqual(wh.AdmissionReviewVersions, cur.AdmissionReviewVersions)
+	}
+	return false
+}
+
+// mwhClient returns the client of the MutatingWebhookConfigurations the
+// webhook configuration is managed with.
+func (ac *reconciler) mwhClient() admissionclient.MutatingWebhookConfigurationInterface {
+	if ac.configurationClient != nil {
+		return ac.configurationClient.AdmissionregistrationV1().MutatingWebhookConfigurations()
+	}
+	return ac.client.AdmissionregistrationV1().MutatingWebhookConfigurations()
+}
+
+// reconcileUninstall prunes the rules of the webhook once the system
+// namespace is being deleted, and returns whether it is.
+func (ac *reconciler) reconcileUninstall(ctx context.Context) (bool, error) {
+	ns, err := ac.client.CoreV1().Namespaces().Get(ctx, system.Namespace(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		// The webhook configuration is about to be garbage collected.
+		return true, ac.pruneRules(ctx)
+	} else if err != nil {
+		return false, fmt.Errorf("failed to fetch namespace: %w", err)
+	}
+	if ns.DeletionTimestamp == nil {
+		return false, nil
+	}
+	return true, ac.pruneRules(ctx)
+}
+
+// demote prunes the rules of the webhook as the leader stops leading, e.g. on
+// shutdown, if the webhook is being uninstalled, since the deletion of the
+// system namespace stops the webhook too. The elector, and so the shutdown of
+// the controller, waits for it to return.
+func (ac *reconciler) demote(ctx context.Context) {
+	if !ac.leading.Swap(false) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, pruneRulesTimeout)
+	defer cancel()
+	if _, err := ac.reconcileUninstall(ctx); err != nil {
+		logging.FromContext(ctx).Errorw("Failed to prune the rules of the webhook", zap.Error(err))
+	}
+}
+
+// pruneRules removes the rules of the webhook, so that the API server no
+// longer calls it, see webhook.Options.PruneRulesOnUninstall.
+func (ac *reconciler) pruneRules(ctx context.Context) error {
+	mwhclient := ac.mwhClient()
+	current, err := mwhclient.Get(ctx, ac.key.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error retrieving webhook: %w", err)
+	}
+
+	pruned := false
+	for i, wh := range current.Webhooks {
+		if wh.Name == current.Name && len(wh.Rules) > 0 {
+			current.Webhooks[i].Rules = nil
+			pruned = true
+		}
+	}
+	if !pruned {
+		return nil
+	}
+	logging.FromContext(ctx).Info("Pruning the rules of the webhook")
+	if err := updateWebhook(ctx, mwhclient, current); err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+// updateWebhook updates the webhook configuration within a span, a child of
+// the reconcile's span carried by ctx.
+func updateWebhook(ctx context.Context, client admissionclient.MutatingWebhookConfigurationInterface, wh *admissionregistrationv1.MutatingWebhookConfiguration) error {
+	ctx, span := trace.StartSpan(ctx, "UpdateMutatingWebhookConfiguration")
+	defer span.End()
+	span.AddAttributes(trace.StringAttribute("name", wh.Name))
+	_, err := client.Update(ctx, wh, metav1.UpdateOptions{})
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+	return err
+}
+
+// applyWebhook applies the webhook configuration with server-side apply
+// within a span, a child of the reconcile's span carried by ctx.
+func applyWebhook(ctx context.Context, client admissionclient.MutatingWebhookConfigurationInterface, wh *admissionregistrationv1.MutatingWebhookConfiguration) error {
+	ctx, span := trace.StartSpan(ctx, "ApplyMutatingWebhookConfiguration")
+	defer span.End()
+	span.AddAttributes(trace.StringAttribute("name", wh.Name))
+	patch, err := json.Marshal(wh)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook: %w", err)
+	}
+	// Take over the fields that other managers may have set, as the
+	// reconciler is the authority on them.
+	_, err = client.Patch(ctx, wh.Name, types.ApplyPatchType, patch, metav1.PatchOptions{
+		FieldManager: webhook.FieldManager,
+		Force:        ptr.Bool(true),
+	})
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+	return err
+}
+
+// appliedConfiguration returns the configuration to apply with server-side
+// apply to reconcile the webhook to current, made of the fields the
+// reconciler manages only, so that the API server keeps those of the other
+// managers, and removes those the reconciler no longer sets.
+func (ac *reconciler) appliedConfiguration(current *admissionregistrationv1.MutatingWebhookConfiguration) *admissionregistrationv1.MutatingWebhookConfiguration {
+	annotations := kmap.Filter(current.Annotations, func(key string) bool {
+		_, managed := ac.annotations[key]
+		return !managed && key != webhook.CABundleOverlapUntilAnnotationKey &&
+			key != webhook.FailurePolicyFallbackAnnotationKey
+	})
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	applied := &admissionregistrationv1.MutatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "MutatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            current.Name,
+			Labels:          ac.labels,
+			Annotations:     annotations,
+			OwnerReferences: current.OwnerReferences,
+		},
+	}
+	for _, wh := range current.Webhooks {
+		if wh.Name != current.Name {
+			continue
+		}
+		managed := admissionregistrationv1.MutatingWebhook{
+			Name:                    wh.Name,
+			ClientConfig:            wh.ClientConfig,
+			Rules:                   wh.Rules,
+			NamespaceSelector:       wh.NamespaceSelector,
+			AdmissionReviewVersions: wh.AdmissionReviewVersions,
+		}
+		if ac.policy.FailurePolicy != nil || ac.endpointslister != nil {
+			managed.FailurePolicy = wh.FailurePolicy
+		}
+		if ac.policy.TimeoutSeconds != nil {
+			managed.TimeoutSeconds = wh.TimeoutSeconds
+		}
+		if ac.policy.SideEffects != nil {
+			managed.SideEffects = wh.SideEffects
+		}
+		applied.Webhooks = append(applied.Webhooks, managed)
+	}
+	return applied
+}
+
+// rules returns the rules of the webhook for the registered types, but
+// those in invalid.
+func (ac *reconciler) rules(invalid map[schema.GroupVersionKind]error) []admissionregistrationv1.RuleWithOperations {
+	handlers := ac.registeredHandlers()
+	gvks := make(map[schema.GroupVersionKind]struct{}, len(handlers)+len(ac.callbacks))
+	for gvk := range handlers {
 		gvks[gvk] = struct{}{}
 	}
 	for gvk := range ac.callbacks {
-		if _, ok := gvks[gvk]; !ok {
-			gvks[gvk] = struct{}{}
-		}
+		gvks[gvk] = struct{}{}
+	}
+	for gvk := range invalid {
+		delete(gvks, gvk)
 	}
 
+	rules := make([]admissionregistrationv1.RuleWithOperations, 0, len(gvks))
 	for gvk := range gvks {
 		plural := strings.ToLower(flect.Pluralize(gvk.Kind))
 
@@ -208,72 +744,192 @@ func (ac *reconciler) reconcileMutatingWebhook(ctx context.Context, caCert []byt
 		}
 		return lhs.Resources[0] < rhs.Resources[0]
 	})
+	return rules
+}
 
-	configuredWebhook, err := ac.mwhlister.Get(ac.key.Name)
-	if err != nil {
-		return fmt.Errorf("error retrieving webhook: %w", err)
+// configureWebhook sets the namespace selector, service reference, review
+// versions and policy of the managed webhook wh.
+func (ac *reconciler) configureWebhook(wh *admissionregistrationv1.MutatingWebhook) error {
+	if _, managed := ac.exclusions(); managed {
+		wh.NamespaceSelector = withoutExclusionRequirement(wh.NamespaceSelector)
 	}
+	wh.NamespaceSelector = webhook.UpdateLabelSelectorExpressions(
+		wh.NamespaceSelector, ac.namespaceSelector())
 
-	current := configuredWebhook.DeepCopy()
+	if ac.serviceName != "" && wh.ClientConfig.Service == nil {
+		wh.ClientConfig.Service = &admissionregistrationv1.ServiceReference{}
+	}
+	if svc := wh.ClientConfig.Service; svc != nil {
+		if ac.serviceName != "" {
+			svc.Name = ac.serviceName
+		}
+		// The service may live apart from the webhook, which still owns
+		// the configuration from system.Namespace().
+		if ac.serviceNamespace != "" {
+			svc.Namespace = ac.serviceNamespace
+		} else if ac.serviceName != "" {
+			svc.Namespace = system.Namespace()
+		}
+	}
+	if wh.ClientConfig.Service == nil {
+		return fmt.Errorf("missing service reference for webhook: %s", wh.Name)
+	}
+	wh.ClientConfig.Service.Path = ptr.String(ac.Path())
+	if ac.servicePort != 0 {
+		wh.ClientConfig.Service.Port = ptr.Int32(ac.servicePort)
+	}
+	if len(ac.reviewVersions) > 0 {
+		wh.AdmissionReviewVersions = append([]string(nil), ac.reviewVersions...)
+	}
+	if fp := ac.policy.FailurePolicy; fp != nil {
+		wh.FailurePolicy = failurePolicy(*fp)
+	}
+	if timeout := ac.policy.TimeoutSeconds; timeout != nil {
+		wh.TimeoutSeconds = ptr.Int32(*timeout)
+	}
+	if se := ac.policy.SideEffects; se != nil {
+		sideEffects := *se
+		wh.SideEffects = &sideEffects
+	}
+	return nil
+}
 
-	ns, err := ac.client.CoreV1().Namespaces().Get(ctx, system.Namespace(), metav1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to fetch namespace: %w", err)
+// reportFailure emits a warning event summarizing the failed reconciles, at
+// most once per failureMaxDelay, so that persistent failures don't flood the
+// API server with events while the reconciles are retried with backoff.
+func (ac *reconciler) reportFailure(err error) {
+	ac.failuresMu.Lock()
+	defer ac.failuresMu.Unlock()
+
+	ac.failures++
+	now := ac.clock.Now()
+	if !ac.failuresReportedAt.IsZero() && now.Sub(ac.failuresReportedAt) < failureMaxDelay {
+		return
+	}
+	mwh := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: ac.key.Name},
 	}
-	nsRef := *metav1.NewControllerRef(ns, corev1.SchemeGroupVersion.WithKind("Namespace"))
-	current.OwnerReferences = []metav1.OwnerReference{nsRef}
+	ac.recorder.Eventf(mwh, corev1.EventTypeWarning, webhook.ReasonReconcileFailed,
+		"Failed to reconcile webhook configuration %q %d time(s), last error: %v", ac.key.Name, ac.failures, err)
+	ac.failures = 0
+	ac.failuresReportedAt = now
+}
 
-	for i, wh := range current.Webhooks {
-		if wh.Name != current.Name {
-			continue
-		}
+// resetFailures forgets about the failed reconciles, so that the next
+// failure is reported right away.
+func (ac *reconciler) resetFailures() {
+	ac.failuresMu.Lock()
+	defer ac.failuresMu.Unlock()
+	ac.failures = 0
+	ac.failuresReportedAt = time.Time{}
+}
 
-		cur := &current.Webhooks[i]
-		cur.Rules = rules
+// caBundle returns the CA bundle to configure on a webhook of mwh that
+// currently trusts bundle. When a CA bundle overlap is configured and the
+// CA changes, the outgoing bundle keeps being trusted alongside caCert until
+// the deadline recorded on mwh passes.
+func (ac *reconciler) caBundle(mwh *admissionregistrationv1.MutatingWebhookConfiguration, bundle, caCert []byte) []byte {
+	const key = webhook.CABundleOverlapUntilAnnotationKey
+	if ac.caBundleOverlap <= 0 || len(bundle) == 0 || bytes.Equal(bundle, caCert) {
+		delete(mwh.Annotations, key)
+		return caCert
+	}
 
-		cur.NamespaceSelector = webhook.EnsureLabelSelectorExpressions(
-			cur.NamespaceSelector,
-			&metav1.LabelSelector{
-				MatchExpressions: []metav1.LabelSelectorRequirement{{
-					Key:      "webhooks.knative.dev/exclude",
-					Operator: metav1.LabelSelectorOpDoesNotExist,
-				}},
-			})
+	if !bytes.HasPrefix(bundle, caCert) {
+		// The CA was rotated, trust both the incoming and the outgoing CAs.
+		until := ac.clock.Now().Add(ac.caBundleOverlap).UTC()
+		mwh.Annotations = kmap.Union(mwh.Annotations, map[string]string{
+			key: until.Format(time.RFC3339),
+		})
+		ac.revisitIn(ac.caBundleOverlap)
 
-		cur.ClientConfig.CABundle = caCert
-		if cur.ClientConfig.Service == nil {
-			return fmt.Errorf("missing service reference for webhook: %s", wh.Name)
+		overlap := make([]byte, 0, len(caCert)+1+len(bundle))
+		overlap = append(overlap, caCert...)
+		if !bytes.HasSuffix(caCert, []byte("\n")) {
+			overlap = append(overlap, '\n')
 		}
-		cur.ClientConfig.Service.Path = ptr.String(ac.Path())
+		return append(overlap, bundle...)
 	}
 
-	if ok, err := kmp.SafeEqual(configuredWebhook, current); err != nil {
-		return fmt.Errorf("error diffing webhooks: %w", err)
-	} else if !ok {
-		logger.Info("Updating webhook")
-		mwhclient := ac.client.AdmissionregistrationV1().MutatingWebhookConfigurations()
-		if _, err := mwhclient.Update(ctx, current, metav1.UpdateOptions{}); err != nil {
-			return fmt.Errorf("failed to update webhook: %w", err)
+	if until, err := time.Parse(time.RFC3339, mwh.Annotations[key]); err == nil {
+		if remaining := until.Sub(ac.clock.Now()); remaining > 0 {
+			// Still within the overlap window.
+			ac.revisitIn(remaining)
+			return bundle
 		}
-	} else {
-		logger.Info("Webhook is valid")
 	}
-	return nil
+	delete(mwh.Annotations, key)
+	return caCert
+}
+
+// fallBackFailurePolicy relaxes the Fail policy of wh, a webhook of mwh, to
+// Ignore while its service has no ready endpoints, and restores it once it
+// does. Other policies are left as is.
+func (ac *reconciler) fallBackFailurePolicy(mwh *admissionregistrationv1.MutatingWebhookConfiguration, wh *admissionregistrationv1.MutatingWebhook) {
+	const key = webhook.FailurePolicyFallbackAnnotationKey
+	svc := wh.ClientConfig.Service
+	ready := false
+	if ep, err := ac.endpointslister.Endpoints(svc.Namespace).Get(svc.Name); err == nil {
+		for _, subset := range ep.Subsets {
+			if len(subset.Addresses) > 0 {
+				ready = true
+				break
+			}
+		}
+	}
+
+	switch _, fellBack := mwh.Annotations[key]; {
+	case !ready && !fellBack && wh.FailurePolicy != nil && *wh.FailurePolicy == admissionregistrationv1.Fail:
+		wh.FailurePolicy = failurePolicy(admissionregistrationv1.Ignore)
+		mwh.Annotations = kmap.Union(mwh.Annotations, map[string]string{key: "true"})
+	case !ready && fellBack:
+		// Keep the policy relaxed, e.g. over the one of the policy profile.
+		wh.FailurePolicy = failurePolicy(admissionregistrationv1.Ignore)
+	case ready && fellBack:
+		wh.FailurePolicy = failurePolicy(admissionregistrationv1.Fail)
+		delete(mwh.Annotations, key)
+	}
+}
+
+func failurePolicy(fp admissionregistrationv1.FailurePolicyType) *admissionregistrationv1.FailurePolicyType {
+	return &fp
+}
+
+// revisitIn enqueues the webhook to be reconciled again after d.
+func (ac *reconciler) revisitIn(d time.Duration) {
+	if ac.enqueueAfter != nil {
+		ac.enqueueAfter(ac.key, d)
+	}
+}
+
+// namespaceSelector returns the knative-managed expressions of the
+// webhook's namespace selector.
+func (ac *reconciler) namespaceSelector() *metav1.LabelSelector {
+	reqs := []metav1.LabelSelectorRequirement{{
+		Key:      "webhooks.knative.dev/exclude",
+		Operator: metav1.LabelSelectorOpDoesNotExist,
+	}}
+	if len(ac.canaryCohorts) > 0 {
+		// Only intercept requests in namespaces from the canary cohorts.
+		reqs = append(reqs, metav1.LabelSelectorRequirement{
+			Key:      webhook.CanaryLabelKey,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   ac.canaryCohorts,
+		})
+	}
+	if excluded, _ := ac.exclusions(); len(excluded) > 0 {
+		reqs = append(reqs, exclusionRequirement(excluded))
+	}
+	return &metav1.LabelSelector{MatchExpressions: reqs}
 }
 
 func (ac *reconciler) mutate(ctx context.Context, req *admissionv1.AdmissionRequest) ([]byte, error) {
-	kind := req.Kind
 	newBytes := req.Object.Raw
 	oldBytes := req.OldObject.Raw
-	// Why, oh why are these different types...
-	gvk := schema.GroupVersionKind{
-		Group:   kind.Group,
-		Version: kind.Version,
-		Kind:    kind.Kind,
-	}
+	gvk := resourcesemantics.RequestKind(req)
 
 	logger := logging.FromContext(ctx)
-	handler, ok := ac.handlers[gvk]
+	handler, ok := ac.registeredHandlers()[gvk]
 	if !ok {
 		if _, ok := ac.callbacks[gvk]; !ok {
 			logger.Error("Unhandled kind: ", gvk)
@@ -22,14 +22,22 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opencensus.io/trace"
+	"go.uber.org/atomic"
+	"golang.org/x/sync/errgroup"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	// Injection stuff
-	_ "knative.dev/pkg/client/injection/kube/client/fake"
+	kubeclient "knative.dev/pkg/client/injection/kube/client/fake"
 	_ "knative.dev/pkg/client/injection/kube/informers/admissionregistration/v1/mutatingwebhookconfiguration/fake"
 	_ "knative.dev/pkg/injection/clients/namespacedkube/informers/core/v1/secret/fake"
 	"knative.dev/pkg/ptr"
@@ -40,11 +48,21 @@ import (
 	authenticationv1 "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
 	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	admissionclient "k8s.io/client-go/kubernetes/typed/admissionregistration/v1"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 
 	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	pkgreconciler "knative.dev/pkg/reconciler"
 	"knative.dev/pkg/system"
 	"knative.dev/pkg/webhook"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
 
 	_ "knative.dev/pkg/system/testing"
 
@@ -166,8 +184,24 @@ func TestConnectAllowed(t *testing.T) {
 	}
 }
 
+func TestUnknownKindAllowedByDefault(t *testing.T) {
+	_, ac := newNonRunningTestResourceAdmissionController(t)
+
+	req := &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Kind: metav1.GroupVersionKind{
+			Group:   "pkg.knative.dev",
+			Version: "v1alpha1",
+			Kind:    "Garbage",
+		},
+	}
+
+	ExpectAllowed(t, ac.Admit(TestContextWithLogger(t), req))
+}
+
 func TestUnknownKindFails(t *testing.T) {
 	_, ac := newNonRunningTestResourceAdmissionController(t)
+	ac.(*reconciler).denyUnregisteredKinds = true
 
 	req := &admissionv1.AdmissionRequest{
 		Operation: admissionv1.Create,
@@ -183,6 +217,7 @@ func TestUnknownKindFails(t *testing.T) {
 
 func TestUnknownVersionFails(t *testing.T) {
 	_, ac := newNonRunningTestResourceAdmissionController(t)
+	ac.(*reconciler).denyUnregisteredKinds = true
 	req := &admissionv1.AdmissionRequest{
 		Operation: admissionv1.Create,
 		Kind: metav1.GroupVersionKind{
@@ -216,7 +251,7 @@ func TestUnknownFieldFails(t *testing.T) {
 	req.Object.Raw = marshaled
 
 	ExpectFailsWith(t, ac.Admit(TestContextWithLogger(t), req),
-		`mutation failed: cannot decode incoming new object: json: unknown field "foo"`)
+		`mutation failed: cannot decode incoming new object: json: unknown field "foo": must not set the field(s): spec.foo`)
 }
 
 func TestUnknownMetadataFieldSucceeds(t *testing.T) {
@@ -453,6 +488,36 @@ func TestAdmitCreates(t *testing.T) {
 	}
 }
 
+func TestNewAdmissionControllerStatsReporter(t *testing.T) {
+	stats, _ := webhook.NewStatsReporter()
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName:    "webhook-secret",
+		StatsReporter: stats,
+	})
+
+	ac := NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath,
+		handlers, func(ctx context.Context) context.Context {
+			return ctx
+		}, true).Reconciler.(*reconciler)
+	if ac.stats != stats {
+		t.Errorf("StatsReporter = %v, want the one of the options: %v", ac.stats, stats)
+	}
+
+	// Without one, the controller defaults its own, leaving the options be.
+	ctx = webhook.WithOptions(ctx, webhook.Options{SecretName: "webhook-secret"})
+	ac = NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath,
+		handlers, func(ctx context.Context) context.Context {
+			return ctx
+		}, true).Reconciler.(*reconciler)
+	if ac.stats == nil {
+		t.Error("StatsReporter = nil, want a default one")
+	}
+	if got := webhook.GetOptions(ctx).StatsReporter; got != nil {
+		t.Errorf("Options.StatsReporter = %v, want it left unset", got)
+	}
+}
+
 func createCreateResource(ctx context.Context, t *testing.T, r *Resource) *admissionv1.AdmissionRequest {
 	t.Helper()
 	req := &admissionv1.AdmissionRequest{
@@ -1015,3 +1080,656 @@ func podCallback(ctx context.Context, u *unstructured.Unstructured) error {
 
 	return nil
 }
+
+func TestReconcileFailuresBackOff(t *testing.T) {
+	ctx, cancel, informers := SetupFakeContextWithCancel(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName: "webhook-secret",
+	})
+
+	client := kubeclient.Get(ctx)
+	for _, obj := range []runtime.Object{
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: system.Namespace()},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "webhook-secret",
+				Namespace: system.Namespace(),
+			},
+			Data: map[string][]byte{
+				certresources.ServerKey:  []byte("present"),
+				certresources.ServerCert: []byte("present"),
+				certresources.CACert:     []byte("present"),
+			},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: testResourceValidationName},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{{
+				Name: testResourceValidationName,
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{
+						Namespace: system.Namespace(),
+						Name:      "webhook",
+					},
+				},
+			}},
+		},
+	} {
+		if err := client.Tracker().Add(obj); err != nil {
+			t.Fatal("Tracker.Add() =", err)
+		}
+	}
+
+	// Persistently fail the updates of the webhook configuration.
+	var attempts atomic.Int32
+	failure := InduceFailure("update", "mutatingwebhookconfigurations")
+	client.PrependReactor("update", "mutatingwebhookconfigurations",
+		func(action clientgotesting.Action) (bool, runtime.Object, error) {
+			attempts.Inc()
+			return failure(action)
+		})
+
+	c := NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath,
+		handlers, func(ctx context.Context) context.Context {
+			return ctx
+		}, true, callbacks)
+
+	waitInformers, err := RunAndSyncInformers(ctx, informers...)
+	if err != nil {
+		t.Fatal("RunAndSyncInformers() =", err)
+	}
+	eg := errgroup.Group{}
+	defer func() {
+		cancel()
+		eg.Wait()
+		waitInformers()
+	}()
+	eg.Go(func() error {
+		return c.RunContext(ctx, 1)
+	})
+
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return attempts.Load() > 0, nil
+	}); err != nil {
+		t.Fatal("The webhook configuration was never updated")
+	}
+
+	// The first retries happen after the base delay of the backoff, the
+	// next ones only after twice as long, while retrying immediately would
+	// make many more attempts in the meantime.
+	time.Sleep(failureBaseDelay + failureBaseDelay/2)
+	retried := attempts.Load()
+	if retried < 2 {
+		t.Errorf("Update attempts = %d, wanted the update to be retried", retried)
+	}
+	time.Sleep(failureBaseDelay)
+	if got := attempts.Load(); got != retried {
+		t.Errorf("Update attempts = %d, want: %d while backing off", got, retried)
+	}
+
+	// The failures are reported once per backoff window.
+	recorder := controller.GetEventRecorder(ctx).(*record.FakeRecorder)
+	if got := len(recorder.Events); got != 1 {
+		t.Errorf("Events = %d, want: 1", got)
+	}
+}
+
+func TestReconcileReportsInvalidRegistrationsOnce(t *testing.T) {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: system.Namespace()},
+	}
+	mwh := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: testResourceValidationName},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{{
+			Name: testResourceValidationName,
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Namespace: system.Namespace(),
+					Name:      "webhook",
+				},
+			},
+		}},
+	}
+	valid := schema.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Resource"}
+	// This registration is missing its version.
+	malformed := schema.GroupVersionKind{Group: "pkg.knative.dev", Kind: "Broken"}
+	broken := map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+		valid:     handlers[valid],
+		malformed: handlers[valid],
+	}
+
+	listers := NewListers([]runtime.Object{ns, mwh})
+	recorder := record.NewFakeRecorder(10)
+	r := &reconciler{
+		key:       types.NamespacedName{Name: testResourceValidationName},
+		path:      testResourceValidationPath,
+		client:    fakekubeclientset.NewSimpleClientset(ns, mwh),
+		mwhlister: listers.GetMutatingWebhookConfigurationLister(),
+		recorder:  recorder,
+		stats:     newTestStatsReporter(),
+	}
+	ctx := TestContextWithLogger(t)
+	invalidEvents := func() (n int) {
+		for len(recorder.Events) > 0 {
+			if strings.Contains(<-recorder.Events, webhook.ReasonInvalidRegistration) {
+				n++
+			}
+		}
+		return n
+	}
+
+	for i, tc := range []struct {
+		handlers map[schema.GroupVersionKind]resourcesemantics.GenericCRD
+		want     int
+	}{
+		{handlers: broken, want: 1},
+		// The periodic reconciles don't report it again.
+		{handlers: broken, want: 0},
+		{handlers: map[schema.GroupVersionKind]resourcesemantics.GenericCRD{valid: handlers[valid]}, want: 0},
+		// Registered broken anew, it is reported again.
+		{handlers: broken, want: 1},
+	} {
+		r.setHandlers(tc.handlers)
+		if err := r.reconcileMutatingWebhook(ctx, []byte("present")); err != nil {
+			t.Fatalf("#%d: reconcileMutatingWebhook() = %v", i, err)
+		}
+		if got := invalidEvents(); got != tc.want {
+			t.Errorf("#%d: InvalidRegistration events = %d, want: %d", i, got, tc.want)
+		}
+	}
+}
+
+func TestReconcileTracesUpdate(t *testing.T) {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: system.Namespace()},
+	}
+	mwh := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: testResourceValidationName},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{{
+			Name: testResourceValidationName,
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Namespace: system.Namespace(),
+					Name:      "webhook",
+				},
+			},
+		}},
+	}
+	listers := NewListers([]runtime.Object{ns, mwh})
+	client := &tracingClient{Interface: fakekubeclientset.NewSimpleClientset(ns, mwh)}
+	r := &reconciler{
+		key:       types.NamespacedName{Name: testResourceValidationName},
+		path:      testResourceValidationPath,
+		handlers:  handlers,
+		client:    client,
+		mwhlister: listers.GetMutatingWebhookConfigurationLister(),
+		recorder:  record.NewFakeRecorder(10),
+		stats:     newTestStatsReporter(),
+	}
+
+	exporter := &spanExporter{}
+	trace.RegisterExporter(exporter)
+	defer trace.UnregisterExporter(exporter)
+
+	// The span of the reconcile, as started by the controller.
+	ctx, span := trace.StartSpan(TestContextWithLogger(t), "reconcile", trace.WithSampler(trace.AlwaysSample()))
+	if err := r.reconcileMutatingWebhook(ctx, []byte("present")); err != nil {
+		t.Fatal("reconcileMutatingWebhook() =", err)
+	}
+	span.End()
+
+	if client.updateSpan == nil {
+		t.Fatal("The webhook configuration was not updated within a span")
+	}
+	update := exporter.find(client.updateSpan.SpanContext().SpanID)
+	if update == nil {
+		t.Fatal("The span of the update was not exported")
+	}
+	if got, want := update.ParentSpanID, span.SpanContext().SpanID; got != want {
+		t.Errorf("Update span parent = %v, wanted the reconcile span %v", got, want)
+	}
+	if got, want := update.TraceID, span.SpanContext().TraceID; got != want {
+		t.Errorf("Update trace = %v, wanted %v", got, want)
+	}
+}
+
+// tracingClient records the span of the context of the updates of the
+// MutatingWebhookConfigurations.
+type tracingClient struct {
+	kubernetes.Interface
+	updateSpan *trace.Span
+}
+
+func (c *tracingClient) AdmissionregistrationV1() admissionclient.AdmissionregistrationV1Interface {
+	return &tracingAdmissionClient{AdmissionregistrationV1Interface: c.Interface.AdmissionregistrationV1(), parent: c}
+}
+
+type tracingAdmissionClient struct {
+	admissionclient.AdmissionregistrationV1Interface
+	parent *tracingClient
+}
+
+func (c *tracingAdmissionClient) MutatingWebhookConfigurations() admissionclient.MutatingWebhookConfigurationInterface {
+	return &tracingMWHClient{MutatingWebhookConfigurationInterface: c.AdmissionregistrationV1Interface.MutatingWebhookConfigurations(), parent: c.parent}
+}
+
+type tracingMWHClient struct {
+	admissionclient.MutatingWebhookConfigurationInterface
+	parent *tracingClient
+}
+
+func (c *tracingMWHClient) Update(ctx context.Context, mwh *admissionregistrationv1.MutatingWebhookConfiguration, opts metav1.UpdateOptions) (*admissionregistrationv1.MutatingWebhookConfiguration, error) {
+	c.parent.updateSpan = trace.FromContext(ctx)
+	return c.MutatingWebhookConfigurationInterface.Update(ctx, mwh, opts)
+}
+
+// spanExporter keeps the exported spans.
+type spanExporter struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (e *spanExporter) ExportSpan(s *trace.SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, s)
+}
+
+func (e *spanExporter) find(id trace.SpanID) *trace.SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range e.spans {
+		if s.SpanID == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestReconcileWithConfigurationClient(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "webhook-secret",
+			Namespace: system.Namespace(),
+		},
+		Data: map[string][]byte{
+			certresources.ServerKey:  []byte("present"),
+			certresources.ServerCert: []byte("present"),
+			certresources.CACert:     []byte("present"),
+		},
+	}
+	mwh := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: testResourceValidationName},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{{
+			Name: testResourceValidationName,
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Namespace: system.Namespace(),
+					Name:      "webhook",
+				},
+			},
+		}},
+	}
+	// The secret is in the cluster the webhook runs in, the webhook
+	// configuration in the one it is managed on.
+	local := fakekubeclientset.NewSimpleClientset(secret)
+	remote := fakekubeclientset.NewSimpleClientset(mwh)
+	listers := NewListers([]runtime.Object{secret, mwh})
+	r := &reconciler{
+		key:                 types.NamespacedName{Name: testResourceValidationName},
+		path:                testResourceValidationPath,
+		handlers:            handlers,
+		client:              local,
+		configurationClient: remote,
+		mwhlister:           listers.GetMutatingWebhookConfigurationLister(),
+		secretlister:        listers.GetSecretLister(),
+		secretName:          secret.Name,
+		recorder:            record.NewFakeRecorder(10),
+		clock:               clock.RealClock{},
+		stats:               newTestStatsReporter(),
+	}
+	r.Promote(pkgreconciler.UniversalBucket(), func(pkgreconciler.Bucket, types.NamespacedName) {})
+
+	if err := r.Reconcile(TestContextWithLogger(t), system.Namespace()+"/does not matter"); err != nil {
+		t.Fatal("Reconcile() =", err)
+	}
+
+	if got := local.Actions(); len(got) != 0 {
+		t.Errorf("Actions on the local client = %v, wanted none", got)
+	}
+	var updated *admissionregistrationv1.MutatingWebhookConfiguration
+	for _, action := range remote.Actions() {
+		if update, ok := action.(clientgotesting.UpdateAction); ok {
+			updated = update.GetObject().(*admissionregistrationv1.MutatingWebhookConfiguration)
+		}
+	}
+	if updated == nil {
+		t.Fatalf("Actions on the configuration client = %v, wanted an update of the webhook", remote.Actions())
+	}
+	if got, want := string(updated.Webhooks[0].ClientConfig.CABundle), "present"; got != want {
+		t.Errorf("CABundle = %q, wanted %q", got, want)
+	}
+	// The system namespace is not in the cluster of the configuration.
+	if got := updated.OwnerReferences; len(got) != 0 {
+		t.Errorf("OwnerReferences = %v, wanted none", got)
+	}
+}
+
+func TestSetHandlers(t *testing.T) {
+	ctx, cancel, informers := SetupFakeContextWithCancel(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName: "webhook-secret",
+	})
+
+	client := kubeclient.Get(ctx)
+	for _, obj := range []runtime.Object{
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: system.Namespace()},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "webhook-secret",
+				Namespace: system.Namespace(),
+			},
+			Data: map[string][]byte{
+				certresources.ServerKey:  []byte("present"),
+				certresources.ServerCert: []byte("present"),
+				certresources.CACert:     []byte("present"),
+			},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: testResourceValidationName},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{{
+				Name: testResourceValidationName,
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{
+						Namespace: system.Namespace(),
+						Name:      "webhook",
+					},
+				},
+			}},
+		},
+	} {
+		if err := client.Tracker().Add(obj); err != nil {
+			t.Fatal("Tracker.Add() =", err)
+		}
+	}
+
+	alpha := schema.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Resource"}
+	beta := schema.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1beta1", Kind: "Resource"}
+
+	c := NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath,
+		map[schema.GroupVersionKind]resourcesemantics.GenericCRD{alpha: &Resource{}},
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true)
+
+	waitInformers, err := RunAndSyncInformers(ctx, informers...)
+	if err != nil {
+		t.Fatal("RunAndSyncInformers() =", err)
+	}
+	defer func() {
+		cancel()
+		waitInformers()
+	}()
+
+	ac := c.Reconciler.(*reconciler)
+	admitBeta := func() *admissionv1.AdmissionResponse {
+		ctx := apis.WithUserInfo(TestContextWithLogger(t), &authenticationv1.UserInfo{Username: user1})
+		req := createCreateResource(ctx, t, CreateResource("a name"))
+		req.Kind.Version = beta.Version
+		return ac.Admit(ctx, req)
+	}
+
+	// The kind isn't registered yet, so the request is let through as is.
+	if resp := admitBeta(); !resp.Allowed || resp.Patch != nil {
+		t.Fatalf("Admit() = %+v, wanted allowed without patch", resp)
+	}
+
+	SetHandlers(c, map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+		alpha: &Resource{},
+		beta:  &Resource{},
+	})
+
+	// The new kind is decoded and defaulted.
+	resp := admitBeta()
+	ExpectAllowed(t, resp)
+	if len(resp.Patch) == 0 || string(resp.Patch) == "[]" {
+		t.Errorf("Admit() patch = %s, wanted the defaults", resp.Patch)
+	}
+
+	// The rules of the webhook configuration cover the new kind.
+	ac.Promote(pkgreconciler.UniversalBucket(), func(pkgreconciler.Bucket, types.NamespacedName) {})
+	if err := ac.Reconcile(ctx, testResourceValidationName); err != nil {
+		t.Fatal("Reconcile() =", err)
+	}
+	mwh, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(
+		ctx, testResourceValidationName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Get() =", err)
+	}
+	var versions []string
+	for _, rule := range mwh.Webhooks[0].Rules {
+		versions = append(versions, rule.APIVersions...)
+	}
+	if want := []string{"v1alpha1", "v1beta1"}; !cmp.Equal(versions, want) {
+		t.Errorf("Rules cover versions %v, wanted %v", versions, want)
+	}
+
+	// The rules for a removed kind, e.g. of a removed CRD, are pruned on
+	// the next reconcile.
+	SetHandlers(c, map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+		beta: &Resource{},
+	})
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		mwh, err := ac.mwhlister.Get(testResourceValidationName)
+		return err == nil && len(mwh.Webhooks[0].Rules) == 2, err
+	}); err != nil {
+		t.Fatal("The lister never saw the updated webhook configuration:", err)
+	}
+	if err := ac.Reconcile(ctx, testResourceValidationName); err != nil {
+		t.Fatal("Reconcile() =", err)
+	}
+	mwh, err = client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(
+		ctx, testResourceValidationName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Get() =", err)
+	}
+	versions = nil
+	for _, rule := range mwh.Webhooks[0].Rules {
+		versions = append(versions, rule.APIVersions...)
+	}
+	if want := []string{"v1beta1"}; !cmp.Equal(versions, want) {
+		t.Errorf("Rules cover versions %v, wanted %v", versions, want)
+	}
+}
+
+func TestRegisteredGVKs(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{})
+
+	c := NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath, handlers,
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true /* disallow unknown field */)
+
+	want := []schema.GroupVersionKind{
+		{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "InnerDefaultResource"},
+		{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Resource"},
+		{Group: "pkg.knative.dev", Version: "v1beta1", Kind: "Resource"},
+		{Group: "pkg.knative.io", Version: "v1alpha1", Kind: "InnerDefaultResource"},
+	}
+	if got := RegisteredGVKs(c); !cmp.Equal(got, want) {
+		t.Errorf("RegisteredGVKs() = %v, wanted %v", got, want)
+	}
+}
+
+func TestPruneRulesOnUninstall(t *testing.T) {
+	bkt := pkgreconciler.UniversalBucket()
+	nopEnqueue := func(pkgreconciler.Bucket, types.NamespacedName) {}
+
+	t.Run("reconcile", func(t *testing.T) {
+		ctx, r := setupPruneRules(t, nil)
+
+		r.Promote(bkt, nopEnqueue)
+		if err := r.Reconcile(ctx, testResourceValidationName); err != nil {
+			t.Fatal("Reconcile() =", err)
+		}
+		if got := len(getWebhookRules(ctx, t)); got == 0 {
+			t.Fatal("Rules = 0, wanted some")
+		}
+
+		// Restarts of the webhook leave the rules be.
+		r.Demote(bkt)
+		if got := len(getWebhookRules(ctx, t)); got == 0 {
+			t.Error("Rules were pruned as the leader shut down")
+		}
+
+		r.Promote(bkt, nopEnqueue)
+		ns := getSystemNamespace(ctx, t)
+		ns.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		if _, err := kubeclient.Get(ctx).CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{}); err != nil {
+			t.Fatal("Update() =", err)
+		}
+		// The rules stay pruned as the namespace is being deleted.
+		for i := 0; i < 2; i++ {
+			if err := r.Reconcile(ctx, testResourceValidationName); err != nil {
+				t.Fatal("Reconcile() =", err)
+			}
+			if got := getWebhookRules(ctx, t); len(got) != 0 {
+				t.Errorf("Rules = %v, wanted none", got)
+			}
+		}
+		// No finalizer holds the deletion of the namespace.
+		if got := getSystemNamespace(ctx, t).Finalizers; len(got) != 0 {
+			t.Errorf("Finalizers = %v, wanted none", got)
+		}
+	})
+
+	t.Run("namespace deleted", func(t *testing.T) {
+		ctx, r := setupPruneRules(t, nil)
+		if err := kubeclient.Get(ctx).CoreV1().Namespaces().Delete(ctx, system.Namespace(), metav1.DeleteOptions{}); err != nil {
+			t.Fatal("Delete() =", err)
+		}
+
+		// The rules are pruned until the garbage collector deletes the
+		// webhook configuration.
+		r.Promote(bkt, nopEnqueue)
+		if err := r.Reconcile(ctx, testResourceValidationName); err != nil {
+			t.Fatal("Reconcile() =", err)
+		}
+		if got := getWebhookRules(ctx, t); len(got) != 0 {
+			t.Errorf("Rules = %v, wanted none", got)
+		}
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		ctx, r := setupPruneRules(t, &metav1.Time{Time: time.Now()})
+
+		// Only the leader prunes the rules.
+		r.Demote(bkt)
+		if got := len(getWebhookRules(ctx, t)); got == 0 {
+			t.Error("Rules were pruned by a replica that wasn't leading")
+		}
+
+		r.Promote(bkt, nopEnqueue)
+		r.Demote(bkt)
+		if got := getWebhookRules(ctx, t); len(got) != 0 {
+			t.Errorf("Rules = %v, wanted none", got)
+		}
+	})
+}
+
+// setupPruneRules returns the reconciler of an admission controller pruning
+// the rules of its webhook on uninstall, whose system namespace was deleted
+// at deleted, if set.
+func setupPruneRules(t *testing.T, deleted *metav1.Time) (context.Context, *reconciler) {
+	t.Helper()
+	ctx, cancel, informers := SetupFakeContextWithCancel(t)
+	t.Cleanup(cancel)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName:            "webhook-secret",
+		PruneRulesOnUninstall: true,
+	})
+
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: system.Namespace()},
+	}
+	if deleted != nil {
+		ns.DeletionTimestamp = deleted
+	}
+	client := kubeclient.Get(ctx)
+	for _, obj := range []runtime.Object{
+		ns,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "webhook-secret",
+				Namespace: system.Namespace(),
+			},
+			Data: map[string][]byte{
+				certresources.ServerKey:  []byte("present"),
+				certresources.ServerCert: []byte("present"),
+				certresources.CACert:     []byte("present"),
+			},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: testResourceValidationName},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{{
+				Name: testResourceValidationName,
+				Rules: []admissionregistrationv1.RuleWithOperations{{
+					Operations: []admissionregistrationv1.OperationType{"CREATE", "UPDATE"},
+					Rule: admissionregistrationv1.Rule{
+						APIGroups:   []string{"pkg.knative.dev"},
+						APIVersions: []string{"v1alpha1"},
+						Resources:   []string{"resources", "resources/status"},
+					},
+				}},
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{
+						Namespace: system.Namespace(),
+						Name:      "webhook",
+					},
+				},
+			}},
+		},
+	} {
+		if err := client.Tracker().Add(obj); err != nil {
+			t.Fatal("Tracker.Add() =", err)
+		}
+	}
+
+	c := NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath,
+		handlers, func(ctx context.Context) context.Context {
+			return ctx
+		}, true)
+
+	waitInformers, err := RunAndSyncInformers(ctx, informers...)
+	if err != nil {
+		t.Fatal("RunAndSyncInformers() =", err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		waitInformers()
+	})
+	return ctx, c.Reconciler.(*reconciler)
+}
+
+func getSystemNamespace(ctx context.Context, t *testing.T) *corev1.Namespace {
+	t.Helper()
+	ns, err := kubeclient.Get(ctx).CoreV1().Namespaces().Get(ctx, system.Namespace(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Get() =", err)
+	}
+	return ns
+}
+
+func getWebhookRules(ctx context.Context, t *testing.T) []admissionregistrationv1.RuleWithOperations {
+	t.Helper()
+	mwh, err := kubeclient.Get(ctx).AdmissionregistrationV1().MutatingWebhookConfigurations().Get(
+		ctx, testResourceValidationName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Get() =", err)
+	}
+	return mwh.Webhooks[0].Rules
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package defaulting
+
+import (
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/system"
+)
+
+// ExcludedNamespacesKey is the key of the ConfigMap watched through
+// WatchExcludedNamespaces holding the comma-separated list of namespaces
+// excluded from the webhook.
+const ExcludedNamespacesKey = "excluded-namespaces"
+
+// WatchExcludedNamespaces has the admission controller returned by
+// NewAdmissionController exclude the namespaces listed in the named
+// ConfigMap, in system.Namespace(), from the namespace selector of its
+// webhook configuration. The webhook configuration is reconciled again
+// whenever the ConfigMap changes. An absent ConfigMap excludes no namespace
+// when cmw is a configmap.DefaultingWatcher.
+func WatchExcludedNamespaces(impl *controller.Impl, cmw configmap.Watcher, name string) {
+	ac := impl.Reconciler.(*reconciler)
+	ac.watchExcludedNamespaces(cmw, name, func() { impl.EnqueueKey(ac.key) })
+}
+
+func (ac *reconciler) watchExcludedNamespaces(cmw configmap.Watcher, name string, enqueue func()) {
+	ac.exclusionsMu.Lock()
+	ac.watchesExclusions = true
+	ac.exclusionsMu.Unlock()
+
+	observer := func(cm *corev1.ConfigMap) {
+		excluded := parseExcludedNamespaces(cm.Data[ExcludedNamespacesKey])
+		ac.exclusionsMu.Lock()
+		ac.excludedNamespaces = excluded
+		ac.exclusionsMu.Unlock()
+		enqueue()
+	}
+	if dcmw, ok := cmw.(configmap.DefaultingWatcher); ok {
+		dcmw.WatchWithDefault(corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: system.Namespace(),
+			},
+		}, observer)
+	} else {
+		cmw.Watch(name, observer)
+	}
+}
+
+// exclusions returns the namespaces excluded from the webhook, and whether
+// they are managed through a ConfigMap at all.
+func (ac *reconciler) exclusions() ([]string, bool) {
+	ac.exclusionsMu.RLock()
+	defer ac.exclusionsMu.RUnlock()
+	return ac.excludedNamespaces, ac.watchesExclusions
+}
+
+// parseExcludedNamespaces parses a comma-separated list of namespaces into
+// a sorted list, ignoring blanks.
+func parseExcludedNamespaces(s string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(s, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// exclusionRequirement returns the selector requirement excluding the given
+// namespaces.
+func exclusionRequirement(namespaces []string) metav1.LabelSelectorRequirement {
+	return metav1.LabelSelectorRequirement{
+		Key:      corev1.LabelMetadataName,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   namespaces,
+	}
+}
+
+// withoutExclusionRequirement returns a copy of the selector without the
+// requirement excluding namespaces by name, so that it can be replaced with
+// the up to date one.
+func withoutExclusionRequirement(sel *metav1.LabelSelector) *metav1.LabelSelector {
+	if sel == nil {
+		return nil
+	}
+	out := sel.DeepCopy()
+	out.MatchExpressions = out.MatchExpressions[:0]
+	for _, r := range sel.MatchExpressions {
+		if r.Key != corev1.LabelMetadataName || r.Operator != metav1.LabelSelectorOpNotIn {
+			out.MatchExpressions = append(out.MatchExpressions, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package defaulting
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"knative.dev/pkg/configmap"
+	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+
+	. "knative.dev/pkg/logging/testing"
+	. "knative.dev/pkg/webhook/testing"
+)
+
+func TestReconcileExcludedNamespaces(t *testing.T) {
+	const (
+		name, path = "foo.bar.baz", "/blah"
+		secretName = "webhook-secret"
+		cmName     = "config-exclusions"
+	)
+
+	objs := []runtime.Object{
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: system.Namespace()},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: system.Namespace(),
+			},
+			Data: map[string][]byte{
+				certresources.ServerKey:  []byte("present"),
+				certresources.ServerCert: []byte("present"),
+				certresources.CACert:     []byte("present"),
+			},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{{
+				Name: name,
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{
+						Namespace: system.Namespace(),
+						Name:      "webhook",
+					},
+				},
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{
+						Key:      "foo",
+						Operator: metav1.LabelSelectorOpExists,
+					}},
+				},
+			}},
+		},
+	}
+	listers := NewListers(objs)
+	client := fakekubeclientset.NewSimpleClientset(objs...)
+
+	ac := &reconciler{
+		key:          types.NamespacedName{Name: name},
+		path:         path,
+		handlers:     handlers,
+		client:       client,
+		mwhlister:    listers.GetMutatingWebhookConfigurationLister(),
+		secretlister: listers.GetSecretLister(),
+		recorder:     record.NewFakeRecorder(10),
+		secretName:   secretName,
+		stats:        newTestStatsReporter(),
+	}
+	ac.Promote(pkgreconciler.UniversalBucket(), func(pkgreconciler.Bucket, types.NamespacedName) {})
+
+	enqueued := 0
+	cmw := &configmap.ManualWatcher{Namespace: system.Namespace()}
+	ac.watchExcludedNamespaces(cmw, cmName, func() { enqueued++ })
+
+	// reconcile reconciles the webhook configuration with the given
+	// exclusions config, and returns the resulting namespace selector.
+	reconcile := func(excluded string) *metav1.LabelSelector {
+		t.Helper()
+		cmw.OnChange(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cmName,
+				Namespace: system.Namespace(),
+			},
+			Data: map[string]string{ExcludedNamespacesKey: excluded},
+		})
+		if err := ac.Reconcile(TestContextWithLogger(t), system.Namespace()+"/does not matter"); err != nil {
+			t.Fatal("Reconcile() =", err)
+		}
+		mwh, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(
+			TestContextWithLogger(t), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal("Get() =", err)
+		}
+		// Feed the update back to the lister, as the informer would.
+		if err := listers.IndexerFor(mwh).Update(mwh); err != nil {
+			t.Fatal("Update() =", err)
+		}
+		return mwh.Webhooks[0].NamespaceSelector
+	}
+
+	exclude := func(namespaces ...string) *metav1.LabelSelector {
+		reqs := []metav1.LabelSelectorRequirement{{
+			Key:      "webhooks.knative.dev/exclude",
+			Operator: metav1.LabelSelectorOpDoesNotExist,
+		}}
+		if len(namespaces) > 0 {
+			reqs = append(reqs, metav1.LabelSelectorRequirement{
+				Key:      corev1.LabelMetadataName,
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   namespaces,
+			})
+		}
+		// The foreign requirement is preserved.
+		reqs = append(reqs, metav1.LabelSelectorRequirement{
+			Key:      "foo",
+			Operator: metav1.LabelSelectorOpExists,
+		})
+		return &metav1.LabelSelector{MatchExpressions: reqs}
+	}
+
+	if got, want := reconcile("kube-system"), exclude("kube-system"); !cmp.Equal(got, want) {
+		t.Error("NamespaceSelector (-want, +got):", cmp.Diff(want, got))
+	}
+
+	// Editing the config replaces the excluded namespaces.
+	if got, want := reconcile(" kube-public, kube-system ,"), exclude("kube-public", "kube-system"); !cmp.Equal(got, want) {
+		t.Error("NamespaceSelector (-want, +got):", cmp.Diff(want, got))
+	}
+
+	// Emptying the config drops the exclusions altogether.
+	if got, want := reconcile(""), exclude(); !cmp.Equal(got, want) {
+		t.Error("NamespaceSelector (-want, +got):", cmp.Diff(want, got))
+	}
+
+	if enqueued != 3 {
+		t.Errorf("Enqueued %d times, wanted 3", enqueued)
+	}
+}
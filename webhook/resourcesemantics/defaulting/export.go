@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package defaulting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	"knative.dev/pkg/kmap"
+	"knative.dev/pkg/webhook"
+	"knative.dev/pkg/webhook/resourcesemantics"
+)
+
+// MutatingWebhookConfiguration returns the MutatingWebhookConfiguration that
+// the admission controller returned by NewAdmissionController reconciles for
+// the handlers and callbacks, given the webhook.Options of ctx, trusting
+// caCert. This allows the configuration to be committed and applied by a
+// GitOps pipeline, rather than being written by the webhook.
+//
+// The webhook's service must be set through webhook.Options.ServiceName.
+// Unlike the reconciled configuration, the returned one isn't owned by
+// system.Namespace(), whose UID is unknown, only by the
+// webhook.Options.ConfigurationOwner if set.
+func MutatingWebhookConfiguration(
+	ctx context.Context,
+	name, path string,
+	handlers map[schema.GroupVersionKind]resourcesemantics.GenericCRD,
+	caCert []byte,
+	callbacks ...map[schema.GroupVersionKind]Callback,
+) (*admissionregistrationv1.MutatingWebhookConfiguration, error) {
+	options := webhook.GetOptions(ctx)
+	if options == nil || options.ServiceName == "" {
+		return nil, errors.New("the webhook's service name must be set")
+	}
+	if len(callbacks) > 1 {
+		return nil, errors.New("at most one callback map may be given")
+	}
+
+	name += options.ConfigurationNameSuffix
+	reviewVersions := options.AdmissionReviewVersions
+	if len(reviewVersions) == 0 {
+		reviewVersions = webhook.DefaultAdmissionReviewVersions
+	}
+	policy, err := options.WebhookPolicy()
+	if err != nil {
+		return nil, err
+	}
+	ac := &reconciler{
+		path:             path,
+		handlers:         handlers,
+		callbacks:        map[schema.GroupVersionKind]Callback{},
+		serviceName:      options.ServiceName,
+		serviceNamespace: options.ServiceNamespace,
+		servicePort:      options.ServicePort,
+		canaryCohorts:    options.CanaryCohorts,
+		reviewVersions:   reviewVersions,
+		policy:           policy,
+	}
+	if len(callbacks) == 1 {
+		ac.callbacks = callbacks[0]
+	}
+	if invalid := ac.invalidRegistrations(); len(invalid) > 0 {
+		// Fail rather than skip them, there is nowhere to surface it.
+		msgs := make([]string, 0, len(invalid))
+		for gvk, err := range invalid {
+			msgs = append(msgs, fmt.Sprintf("%s: %v", gvk, err))
+		}
+		sort.Strings(msgs)
+		return nil, fmt.Errorf("invalid registrations: %s", strings.Join(msgs, "; "))
+	}
+
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	mwh := &admissionregistrationv1.MutatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "MutatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{{
+			Name:          name,
+			Rules:         ac.rules(nil),
+			FailurePolicy: failurePolicy(admissionregistrationv1.Fail),
+			SideEffects:   &sideEffects,
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				CABundle: caCert,
+			},
+		}},
+	}
+	if len(options.ConfigurationLabels) > 0 {
+		mwh.Labels = kmap.Copy(options.ConfigurationLabels)
+	}
+	if len(options.ConfigurationAnnotations) > 0 {
+		mwh.Annotations = kmap.Copy(options.ConfigurationAnnotations)
+	}
+	if options.ConfigurationOwner != nil {
+		mwh.OwnerReferences = []metav1.OwnerReference{*options.ConfigurationOwner}
+	}
+	if err := ac.configureWebhook(&mwh.Webhooks[0]); err != nil {
+		return nil, err
+	}
+	return mwh, nil
+}
+
+// RenderMutatingWebhookConfiguration renders the configuration returned by
+// MutatingWebhookConfiguration as YAML.
+func RenderMutatingWebhookConfiguration(
+	ctx context.Context,
+	name, path string,
+	handlers map[schema.GroupVersionKind]resourcesemantics.GenericCRD,
+	caCert []byte,
+	callbacks ...map[schema.GroupVersionKind]Callback,
+) ([]byte, error) {
+	mwh, err := MutatingWebhookConfiguration(ctx, name, path, handlers, caCert, callbacks...)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(mwh)
+}
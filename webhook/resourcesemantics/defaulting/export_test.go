@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package defaulting
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	"knative.dev/pkg/ptr"
+	"knative.dev/pkg/system"
+	. "knative.dev/pkg/testing"
+	"knative.dev/pkg/webhook"
+	"knative.dev/pkg/webhook/resourcesemantics"
+)
+
+func TestRenderMutatingWebhookConfiguration(t *testing.T) {
+	owner := metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "ClusterRole",
+		Name:       "owner",
+		UID:        "owner-uid",
+	}
+	ctx := webhook.WithOptions(context.Background(), webhook.Options{
+		ServiceName:              "webhook",
+		ConfigurationNameSuffix:  ".canary",
+		ConfigurationLabels:      map[string]string{"app": "webhook"},
+		ConfigurationAnnotations: map[string]string{"note": "rendered"},
+		ConfigurationOwner:       &owner,
+	})
+	handlers := map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+		{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Resource"}: &Resource{},
+	}
+	callbacks := map[schema.GroupVersionKind]Callback{
+		{Group: "pkg.knative.dev", Version: "v1beta1", Kind: "Resource"}: NewCallback(resourceCallback, webhook.Create),
+	}
+
+	got, err := RenderMutatingWebhookConfiguration(ctx, testResourceValidationName, testResourceValidationPath,
+		handlers, []byte("ca-cert"), callbacks)
+	if err != nil {
+		t.Fatal("RenderMutatingWebhookConfiguration() =", err)
+	}
+	var mwh admissionregistrationv1.MutatingWebhookConfiguration
+	if err := yaml.UnmarshalStrict(got, &mwh); err != nil {
+		t.Fatalf("Failed to unmarshal the rendered YAML: %v\n%s", err, got)
+	}
+
+	const name = testResourceValidationName + ".canary"
+	ops := []admissionregistrationv1.OperationType{"CREATE", "UPDATE"}
+	want := admissionregistrationv1.MutatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admissionregistration.k8s.io/v1",
+			Kind:       "MutatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Labels:          map[string]string{"app": "webhook"},
+			Annotations:     map[string]string{"note": "rendered"},
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{{
+			Name: name,
+			Rules: []admissionregistrationv1.RuleWithOperations{{
+				Operations: ops,
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{"pkg.knative.dev"},
+					APIVersions: []string{"v1alpha1"},
+					Resources:   []string{"resources", "resources/status"},
+				},
+			}, {
+				Operations: ops,
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{"pkg.knative.dev"},
+					APIVersions: []string{"v1beta1"},
+					Resources:   []string{"resources", "resources/status"},
+				},
+			}},
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      "webhooks.knative.dev/exclude",
+					Operator: metav1.LabelSelectorOpDoesNotExist,
+				}},
+			},
+			FailurePolicy:           failurePolicy(admissionregistrationv1.Fail),
+			SideEffects:             sideEffects(admissionregistrationv1.SideEffectClassNone),
+			AdmissionReviewVersions: webhook.DefaultAdmissionReviewVersions,
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Name:      "webhook",
+					Namespace: system.Namespace(),
+					Path:      ptr.String(testResourceValidationPath),
+				},
+				CABundle: []byte("ca-cert"),
+			},
+		}},
+	}
+	if !cmp.Equal(mwh, want) {
+		t.Error("Rendered configuration (-got, +want):", cmp.Diff(mwh, want))
+	}
+}
+
+func TestRenderMutatingWebhookConfigurationErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     *webhook.Options
+		handlers map[schema.GroupVersionKind]resourcesemantics.GenericCRD
+	}{{
+		name:     "no options",
+		handlers: handlers,
+	}, {
+		name:     "no service",
+		opts:     &webhook.Options{},
+		handlers: handlers,
+	}, {
+		name: "invalid registration",
+		opts: &webhook.Options{ServiceName: "webhook"},
+		handlers: map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+			{Group: "pkg.knative.dev", Kind: "Resource"}: &Resource{},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.opts != nil {
+				ctx = webhook.WithOptions(ctx, *test.opts)
+			}
+			if _, err := RenderMutatingWebhookConfiguration(ctx, testResourceValidationName, testResourceValidationPath,
+				test.handlers, nil); err == nil {
+				t.Error("RenderMutatingWebhookConfiguration() = nil, wanted an error")
+			}
+		})
+	}
+}
+
+func sideEffects(se admissionregistrationv1.SideEffectClass) *admissionregistrationv1.SideEffectClass {
+	return &se
+}
@@ -18,24 +18,35 @@ package defaulting
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	kubeclient "knative.dev/pkg/client/injection/kube/client/fake"
 	_ "knative.dev/pkg/injection/clients/namespacedkube/informers/core/v1/secret/fake"
 	pkgreconciler "knative.dev/pkg/reconciler"
 
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	clientgotesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
 	"knative.dev/pkg/ptr"
 	"knative.dev/pkg/system"
 	"knative.dev/pkg/webhook"
@@ -49,6 +60,7 @@ import (
 func TestReconcile(t *testing.T) {
 	name, path := "foo.bar.baz", "/blah"
 	secretName := "webhook-secret"
+	now := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
 
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -68,6 +80,35 @@ func TestReconcile(t *testing.T) {
 	}
 	nsRef := *metav1.NewControllerRef(ns, corev1.SchemeGroupVersion.WithKind("Namespace"))
 	expectedOwnerReferences := []metav1.OwnerReference{nsRef}
+	// A webhook of the configuration not managed by the reconciler.
+	handManagedWebhook := admissionregistrationv1.MutatingWebhook{
+		Name: "hand.managed.webhook",
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			Service: &admissionregistrationv1.ServiceReference{
+				Namespace: "elsewhere",
+				Name:      "hand-managed",
+				Path:      ptr.String("/hand-managed"),
+			},
+			CABundle: []byte("hand-managed"),
+		},
+		Rules: []admissionregistrationv1.RuleWithOperations{{
+			Operations: []admissionregistrationv1.OperationType{"CREATE"},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{"example.com"},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"widgets"},
+			},
+		}},
+		SideEffects: sideEffects(admissionregistrationv1.SideEffectClassNone),
+	}
+	fail, ignore := admissionregistrationv1.Fail, admissionregistrationv1.Ignore
+	customOwnerReference := metav1.OwnerReference{
+		APIVersion: "platform.example.com/v1",
+		Kind:       "Platform",
+		Name:       "knative",
+		UID:        "9c0a7d0e-5e3f-4b0d-8a1b-2f4c7e6d5a3b",
+		Controller: ptr.Bool(true),
+	}
 
 	// This is the namespace selector setup
 	namespaceSelector := &metav1.LabelSelector{
@@ -133,10 +174,19 @@ func TestReconcile(t *testing.T) {
 	// namespace matters for namespace validation).
 	key := system.Namespace() + "/does not matter"
 
+	caCertFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caCertFile, []byte("from-file"), 0600); err != nil {
+		t.Fatal("WriteFile() =", err)
+	}
+
 	table := TableTest{{
 		Name:    "no secret",
 		Key:     key,
 		WantErr: true,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, webhook.ReasonReconcileFailed,
+				"Failed to reconcile webhook configuration %q 1 time(s), last error: secret %q not found", name, secretName),
+		},
 	}, {
 		Name: "secret missing CA Cert",
 		Key:  key,
@@ -152,11 +202,19 @@ func TestReconcile(t *testing.T) {
 			},
 		}},
 		WantErr: true,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, webhook.ReasonReconcileFailed,
+				"Failed to reconcile webhook configuration %q 1 time(s), last error: secret %q is missing %q key", name, secretName, certresources.CACert),
+		},
 	}, {
 		Name:    "secret exists, but MWH does not",
 		Key:     key,
 		Objects: []runtime.Object{secret},
 		WantErr: true,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, webhook.ReasonReconcileFailed,
+				"Failed to reconcile webhook configuration %q 1 time(s), last error: error retrieving webhook: mutatingwebhookconfiguration.admissionregistration.k8s.io %q not found", name, name),
+		},
 	}, {
 		Name: "secret and MWH exist, missing service reference",
 		Key:  key,
@@ -171,6 +229,10 @@ func TestReconcile(t *testing.T) {
 			},
 		},
 		WantErr: true,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, webhook.ReasonReconcileFailed,
+				"Failed to reconcile webhook configuration %q 1 time(s), last error: missing service reference for webhook: %s", name, name),
+		},
 	}, {
 		Name: "secret and MWH exist, missing other stuff",
 		Key:  key,
@@ -214,6 +276,12 @@ func TestReconcile(t *testing.T) {
 				}},
 			},
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			wantReconcileReasons(webhook.ReasonUpdated),
+		},
 	}, {
 		Name: "secret and MWH exist, added fields are incorrect",
 		Key:  key,
@@ -270,10 +338,122 @@ func TestReconcile(t *testing.T) {
 				}},
 			},
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+			Eventf(corev1.EventTypeWarning, webhook.ReasonDriftCorrected, "Reverted changes made by others to webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, foreign webhook is preserved",
+		Key:  key,
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{handManagedWebhook, {
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							// Incorrect
+							Path: ptr.String("incorrect"),
+						},
+					},
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				// The foreign webhook is left as is, in place.
+				Webhooks: []admissionregistrationv1.MutatingWebhook{handManagedWebhook, {
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							// Path is fixed.
+							Path: ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, new types registered are no drift",
+		Key:  key,
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						// Rotated since.
+						CABundle: []byte("outdated"),
+					},
+					// Set before the other types were registered.
+					Rules: []admissionregistrationv1.RuleWithOperations{{
+						Operations: []admissionregistrationv1.OperationType{"CREATE", "UPDATE"},
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{"pkg.knative.dev"},
+							APIVersions: []string{"v1alpha1"},
+							Resources:   []string{"innerdefaultresources", "innerdefaultresources/status"},
+						},
+					}},
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
 	}, {
 		Name:    "failure updating MWH",
 		Key:     key,
 		WantErr: true,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, webhook.ReasonReconcileFailed,
+				"Failed to reconcile webhook configuration %q 1 time(s), last error: failed to update webhook: inducing failure for update mutatingwebhookconfigurations", name),
+		},
 		WithReactors: []clientgotesting.ReactionFunc{
 			InduceFailure("update", "mutatingwebhookconfigurations"),
 		},
@@ -330,6 +510,78 @@ func TestReconcile(t *testing.T) {
 				}},
 			},
 		}},
+	}, {
+		Name:    "MWH exceeding the object size limit",
+		Key:     key,
+		WantErr: true,
+		WantEvents: []string{
+			// The error explains what to do about it.
+			Eventf(corev1.EventTypeWarning, webhook.ReasonReconcileFailed,
+				"Failed to reconcile webhook configuration %q 1 time(s), last error: failed to update webhook: "+
+					"its 7 rules exceed the API server's object size limit, split the registered types across several admission controllers: "+
+					"Request entity too large: limit is 3145728", name),
+		},
+		WithReactors: []clientgotesting.ReactionFunc{
+			func(action clientgotesting.Action) (bool, runtime.Object, error) {
+				if !action.Matches("update", "mutatingwebhookconfigurations") {
+					return false, nil, nil
+				}
+				return true, nil, apierrors.NewRequestEntityTooLargeError("limit is 3145728")
+			},
+		},
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							// Incorrect
+							Path: ptr.String("incorrect"),
+						},
+						// Incorrect
+						CABundle: []byte("incorrect"),
+					},
+					// Incorrect (really just incomplete)
+					Rules: []admissionregistrationv1.RuleWithOperations{{
+						Operations: []admissionregistrationv1.OperationType{"CREATE", "UPDATE"},
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{"pkg.knative.dev"},
+							APIVersions: []string{"v1alpha1"},
+							Resources:   []string{"innerdefaultresources", "innerdefaultresources/status"},
+						},
+					}},
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							// Path is fixed.
+							Path: ptr.String(path),
+						},
+						// CABundle is fixed.
+						CABundle: []byte("present"),
+					},
+					// Rules are fixed.
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
 	}, {
 		Name: ":fire: everything is fine :fire:",
 		Key:  key,
@@ -366,6 +618,9 @@ func TestReconcile(t *testing.T) {
 				}},
 			},
 		},
+		PostConditions: []func(*testing.T, *TableRow){
+			wantReconcileReasons(webhook.ReasonAlreadyReconciled),
+		},
 	}, {
 		Name: "secret and MWH exist, correcting namespaceSelector",
 		Key:  key,
@@ -432,57 +687,1519 @@ func TestReconcile(t *testing.T) {
 				}},
 			},
 		}},
-	}}
-
-	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
-		return &reconciler{
-			key: types.NamespacedName{
-				Name: name,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+			Eventf(corev1.EventTypeWarning, webhook.ReasonDriftCorrected, "Reverted changes made by others to webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, adding canary namespaceSelector",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			CanaryCohorts: []string{"alpha", "beta"},
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules: expectedRules,
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "webhooks.knative.dev/exclude",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}, {
+							Key:      "foo.bar/baz",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}},
+					},
+				}},
 			},
-			path: path,
-
-			handlers:  handlers,
-			callbacks: callbacks,
-
-			client:       kubeclient.Get(ctx),
-			mwhlister:    listers.GetMutatingWebhookConfigurationLister(),
-			secretlister: listers.GetSecretLister(),
-
-			secretName: secretName,
-		}
-	}))
-}
-
-func TestNew(t *testing.T) {
-	ctx, _ := SetupFakeContext(t)
-	ctx = webhook.WithOptions(ctx, webhook.Options{})
-
-	c := NewAdmissionController(ctx, "foo", "/bar",
-		map[schema.GroupVersionKind]resourcesemantics.GenericCRD{},
-		func(ctx context.Context) context.Context {
-			return ctx
-		}, true /* disallow unknown field */)
-	if c == nil {
-		t.Fatal("Expected NewController to return a non-nil value")
-	}
-
-	if want, got := 0, c.WorkQueue().Len(); want != got {
-		t.Errorf("WorkQueue.Len() = %d, wanted %d", got, want)
-	}
-
-	la, ok := c.Reconciler.(pkgreconciler.LeaderAware)
-	if !ok {
-		t.Fatalf("%T is not leader aware", c.Reconciler)
-	}
-
-	if err := la.Promote(pkgreconciler.UniversalBucket(), c.MaybeEnqueueBucketKey); err != nil {
-		t.Error("Promote() =", err)
-	}
-
-	// Queue has async moving parts so if we check at the wrong moment, this might still be 0.
-	if wait.PollImmediate(10*time.Millisecond, 250*time.Millisecond, func() (bool, error) {
-		return c.WorkQueue().Len() == 1, nil
-	}) != nil {
-		t.Error("Queue length was never 1")
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules: expectedRules,
+					NamespaceSelector: &metav1.LabelSelector{
+						// The canary cohort is selected alongside the exclusion,
+						// while the non-knative key is kept.
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "webhooks.knative.dev/exclude",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}, {
+							Key:      webhook.CanaryLabelKey,
+							Operator: metav1.LabelSelectorOpIn,
+							Values:   []string{"alpha", "beta"},
+						}, {
+							Key:      "foo.bar/baz",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}},
+					},
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+			Eventf(corev1.EventTypeWarning, webhook.ReasonDriftCorrected, "Reverted changes made by others to webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, applying the Lenient policy profile",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			PolicyProfile: webhook.PolicyProfileLenient,
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					FailurePolicy:     failurePolicy(admissionregistrationv1.Fail),
+					TimeoutSeconds:    ptr.Int32(10),
+					SideEffects:       sideEffects(admissionregistrationv1.SideEffectClassNone),
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					FailurePolicy:     failurePolicy(admissionregistrationv1.Ignore),
+					TimeoutSeconds:    ptr.Int32(30),
+					SideEffects:       sideEffects(admissionregistrationv1.SideEffectClassNone),
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, overriding the timeout of the Lenient policy profile",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			PolicyProfile: webhook.PolicyProfileLenient,
+			PolicyOverrides: webhook.WebhookPolicy{
+				TimeoutSeconds: ptr.Int32(15),
+			},
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					FailurePolicy:     failurePolicy(admissionregistrationv1.Fail),
+					TimeoutSeconds:    ptr.Int32(10),
+					SideEffects:       sideEffects(admissionregistrationv1.SideEffectClassNone),
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					FailurePolicy:     failurePolicy(admissionregistrationv1.Ignore),
+					TimeoutSeconds:    ptr.Int32(15),
+					SideEffects:       sideEffects(admissionregistrationv1.SideEffectClassNone),
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+	}, {
+		Name: "CA cert file and MWH exist, no secret",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			CACertFile: caCertFile,
+		}),
+		Objects: []runtime.Object{ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						// CABundle is read from the file.
+						CABundle: []byte("from-file"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, adding managed labels",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			ConfigurationLabels: map[string]string{"policy.example.com/owner": "knative"},
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+					Annotations:     map[string]string{"unmanaged": "annotation"},
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+					// The managed label is added.
+					Labels: map[string]string{"policy.example.com/owner": "knative"},
+					// The unmanaged annotation is preserved.
+					Annotations: map[string]string{"unmanaged": "annotation"},
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, applied with server-side apply",
+		Key:  key,
+		// The MWH is cluster-scoped.
+		SkipNamespaceValidation: true,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			ServerSideApply:     true,
+			ConfigurationLabels: map[string]string{"policy.example.com/owner": "knative"},
+		}),
+		WithReactors: []clientgotesting.ReactionFunc{
+			func(action clientgotesting.Action) (bool, runtime.Object, error) {
+				if patch, ok := action.(clientgotesting.PatchAction); ok && patch.GetPatchType() != types.ApplyPatchType {
+					return true, nil, fmt.Errorf("unexpected patch type %q", patch.GetPatchType())
+				}
+				// The fake clientset doesn't support server-side apply.
+				return action.GetVerb() == "patch", nil, nil
+			},
+		},
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+					Annotations:     map[string]string{"unmanaged": "annotation"},
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							// Incorrect
+							Path: ptr.String("incorrect"),
+						},
+					},
+					NamespaceSelector: namespaceSelector,
+					SideEffects:       sideEffects(admissionregistrationv1.SideEffectClassNone),
+				}, {
+					Name: "another.webhook",
+				}},
+			},
+		},
+		// Only the managed fields are applied, leaving those of the other
+		// managers, e.g. the other webhook and the unmanaged annotation.
+		WantPatches: []clientgotesting.PatchActionImpl{{
+			Name: name,
+			Patch: mustMarshal(t, &admissionregistrationv1.MutatingWebhookConfiguration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "admissionregistration.k8s.io/v1",
+					Kind:       "MutatingWebhookConfiguration",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+					Labels:          map[string]string{"policy.example.com/owner": "knative"},
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			}),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, owned by a custom object",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			ConfigurationOwner: &customOwnerReference,
+		}),
+		// The namespace isn't needed to build the owner reference.
+		Objects: []runtime.Object{secret,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+					// The custom owner replaces the system namespace.
+					OwnerReferences: []metav1.OwnerReference{customOwnerReference},
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+			Eventf(corev1.EventTypeWarning, webhook.ReasonDriftCorrected, "Reverted changes made by others to webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, with a name suffix",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			ConfigurationNameSuffix: "-tenant",
+		}),
+		Objects: []runtime.Object{secret, ns,
+			// The webhook of another install is left alone.
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						CABundle: []byte("other"),
+					},
+				}},
+			},
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name + "-tenant",
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name + "-tenant",
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("incorrect"),
+					},
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name + "-tenant",
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name + "-tenant",
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name+"-tenant"),
+			Eventf(corev1.EventTypeWarning, webhook.ReasonDriftCorrected, "Reverted changes made by others to webhook configuration %q", name+"-tenant"),
+		},
+	}, {
+		Name: "secret and MWH exist, correcting admission review versions",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			AdmissionReviewVersions: []string{"v1"},
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					// Drifted.
+					AdmissionReviewVersions: []string{"v1beta1", "v1"},
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					// Versions are fixed.
+					AdmissionReviewVersions: []string{"v1"},
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+			Eventf(corev1.EventTypeWarning, webhook.ReasonDriftCorrected, "Reverted changes made by others to webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, no ready endpoints relax the failure policy",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			FailurePolicyFallback: true,
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: system.Namespace(),
+					Name:      "webhook",
+				},
+				// The webhook pods are rolling out.
+				Subsets: []corev1.EndpointSubset{{
+					NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+				}},
+			},
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					FailurePolicy:     &fail,
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+					Annotations:     map[string]string{webhook.FailurePolicyFallbackAnnotationKey: "true"},
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					FailurePolicy:     &ignore,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, ready endpoints restore the failure policy",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			FailurePolicyFallback: true,
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: system.Namespace(),
+					Name:      "webhook",
+				},
+				Subsets: []corev1.EndpointSubset{{
+					Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+				}},
+			},
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+					Annotations:     map[string]string{webhook.FailurePolicyFallbackAnnotationKey: "true"},
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					FailurePolicy:     &ignore,
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					FailurePolicy:     &fail,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, no ready endpoints keep an Ignore policy",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			FailurePolicyFallback: true,
+		}),
+		// The service has no endpoints at all.
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					FailurePolicy:     &ignore,
+				}},
+			},
+		},
+	}, {
+		Name: "secret and MWH exist, referencing a non-default service",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			ServiceName:      "webhook-us-east",
+			ServiceNamespace: "webhooks-us-east",
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						// The configured service is referenced.
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: "webhooks-us-east",
+							Name:      "webhook-us-east",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+			Eventf(corev1.EventTypeWarning, webhook.ReasonDriftCorrected, "Reverted changes made by others to webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, referencing a custom service port",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			ServicePort: 8443,
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+							Port:      ptr.Int32(443),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						// The port of the service is reconciled.
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+							Port:      ptr.Int32(8443),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+			Eventf(corev1.EventTypeWarning, webhook.ReasonDriftCorrected, "Reverted changes made by others to webhook configuration %q", name),
+		},
+	}, {
+		Name: "secret and MWH exist, referencing a service in a non-system namespace",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			ServiceNamespace: "webhooks",
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+					// Still owned by the system namespace.
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						// The service keeps its name, in the configured namespace.
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: "webhooks",
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+			Eventf(corev1.EventTypeWarning, webhook.ReasonDriftCorrected, "Reverted changes made by others to webhook configuration %q", name),
+		},
+	}, {
+		Name: "CA rotated, outgoing CA is kept during the overlap",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			CABundleOverlap: time.Hour,
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("outgoing"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+					Annotations: map[string]string{
+						webhook.CABundleOverlapUntilAnnotationKey: now.Add(time.Hour).Format(time.RFC3339),
+					},
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						// Both the incoming and the outgoing CA are trusted.
+						CABundle: []byte("present\noutgoing"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+	}, {
+		Name: "CA bundle overlap in progress",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			CABundleOverlap: time.Hour,
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+					Annotations: map[string]string{
+						webhook.CABundleOverlapUntilAnnotationKey: now.Add(time.Minute).Format(time.RFC3339),
+					},
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present\noutgoing"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		},
+	}, {
+		Name: "CA bundle overlap elapsed",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			CABundleOverlap: time.Hour,
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+					Annotations: map[string]string{
+						webhook.CABundleOverlapUntilAnnotationKey: now.Add(-time.Minute).Format(time.RFC3339),
+					},
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present\noutgoing"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						// The outgoing CA is trimmed.
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := &reconciler{
+			key: types.NamespacedName{
+				Name: name,
+			},
+			path: path,
+
+			handlers:  handlers,
+			callbacks: callbacks,
+
+			client:       kubeclient.Get(ctx),
+			mwhlister:    listers.GetMutatingWebhookConfigurationLister(),
+			secretlister: listers.GetSecretLister(),
+			recorder:     controller.GetEventRecorder(ctx),
+
+			secretName: secretName,
+			clock:      clocktesting.NewFakeClock(now),
+			stats:      newTestStatsReporter(),
+		}
+		if opts := webhook.GetOptions(ctx); opts != nil {
+			r.canaryCohorts = opts.CanaryCohorts
+			r.caCertFile = opts.CACertFile
+			r.labels = opts.ConfigurationLabels
+			r.annotations = opts.ConfigurationAnnotations
+			r.owner = opts.ConfigurationOwner
+			r.key.Name += opts.ConfigurationNameSuffix
+			r.reviewVersions = opts.AdmissionReviewVersions
+			if opts.FailurePolicyFallback {
+				r.endpointslister = listers.GetEndpointsLister()
+			}
+			r.caBundleOverlap = opts.CABundleOverlap
+			r.serviceName = opts.ServiceName
+			r.serviceNamespace = opts.ServiceNamespace
+			r.servicePort = opts.ServicePort
+			r.serverSideApply = opts.ServerSideApply
+			r.policy, _ = opts.WebhookPolicy()
+		}
+		return r
+	}))
+}
+
+// mustMarshal returns the JSON encoding of obj.
+func mustMarshal(t *testing.T, obj interface{}) []byte {
+	t.Helper()
+	b, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal("json.Marshal() =", err)
+	}
+	return b
+}
+
+func TestReconcileSkipsInvalidRegistrations(t *testing.T) {
+	name, path := "foo.bar.baz", "/blah"
+	secretName := "webhook-secret"
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: system.Namespace(),
+		},
+		Data: map[string][]byte{
+			certresources.ServerKey:  []byte("present"),
+			certresources.ServerCert: []byte("present"),
+			certresources.CACert:     []byte("present"),
+		},
+	}
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: system.Namespace(),
+		},
+	}
+	nsRef := *metav1.NewControllerRef(ns, corev1.SchemeGroupVersion.WithKind("Namespace"))
+
+	valid := schema.GroupVersionKind{
+		Group:   "pkg.knative.dev",
+		Version: "v1alpha1",
+		Kind:    "Resource",
+	}
+	// This registration is missing its version.
+	malformed := schema.GroupVersionKind{
+		Group: "pkg.knative.dev",
+		Kind:  "Broken",
+	}
+	registered := map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+		valid:     handlers[valid],
+		malformed: handlers[valid],
+	}
+
+	key := system.Namespace() + "/does not matter"
+
+	table := TableTest{{
+		Name: "malformed registration is skipped",
+		Key:  key,
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+						},
+					},
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: []metav1.OwnerReference{nsRef},
+				},
+				Webhooks: []admissionregistrationv1.MutatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					// Only the valid registration produces rules.
+					Rules: []admissionregistrationv1.RuleWithOperations{{
+						Operations: []admissionregistrationv1.OperationType{"CREATE", "UPDATE"},
+						Rule: admissionregistrationv1.Rule{
+							APIGroups:   []string{"pkg.knative.dev"},
+							APIVersions: []string{"v1alpha1"},
+							Resources:   []string{"resources", "resources/status"},
+						},
+					}},
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "webhooks.knative.dev/exclude",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}},
+					},
+				}},
+			},
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeWarning, webhook.ReasonInvalidRegistration,
+				"Skipping invalid registration of %s: %v", malformed, "missing version"),
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+	}}
+
+	status := webhook.NewStatus()
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		return &reconciler{
+			key: types.NamespacedName{
+				Name: name,
+			},
+			path: path,
+
+			handlers: registered,
+
+			client:       kubeclient.Get(ctx),
+			mwhlister:    listers.GetMutatingWebhookConfigurationLister(),
+			secretlister: listers.GetSecretLister(),
+			recorder:     controller.GetEventRecorder(ctx),
+
+			secretName: secretName,
+			status:     status,
+			stats:      newTestStatsReporter(),
+		}
+	}))
+
+	// The webhook is configured, but reports the skipped registration.
+	snapshot := status.Snapshot()
+	cond := snapshot.GetCondition(webhook.WebhookConfigured)
+	if cond == nil || !cond.IsTrue() || cond.Reason != "InvalidRegistrations" {
+		t.Errorf("WebhookConfigured = %+v, wanted True with reason InvalidRegistrations", cond)
+	}
+}
+
+func TestReconcileBeforeSecretsSynced(t *testing.T) {
+	name, path := "foo.bar.baz", "/blah"
+	secretName := "webhook-secret"
+
+	var revisitedIn time.Duration
+	table := TableTest{{
+		Name: "secret not in the cache yet",
+		Key:  system.Namespace() + "/does not matter",
+		// No error nor event, just a requeue.
+		PostConditions: []func(*testing.T, *TableRow){
+			func(t *testing.T, _ *TableRow) {
+				if revisitedIn != informerSyncDelay {
+					t.Errorf("Revisited in %v, wanted %v", revisitedIn, informerSyncDelay)
+				}
+			},
+		},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		return &reconciler{
+			key: types.NamespacedName{
+				Name: name,
+			},
+			path: path,
+
+			handlers: handlers,
+
+			client:        kubeclient.Get(ctx),
+			mwhlister:     listers.GetMutatingWebhookConfigurationLister(),
+			secretlister:  listers.GetSecretLister(),
+			secretsSynced: func() bool { return false },
+			recorder:      controller.GetEventRecorder(ctx),
+			enqueueAfter: func(_ types.NamespacedName, d time.Duration) {
+				revisitedIn = d
+			},
+
+			secretName: secretName,
+			stats:      newTestStatsReporter(),
+		}
+	}))
+}
+
+func TestReconcileBeforeConfigurationSynced(t *testing.T) {
+	name, path := "foo.bar.baz", "/blah"
+	secretName := "webhook-secret"
+
+	var revisitedIn time.Duration
+	table := TableTest{{
+		Name: "configuration not in the cache of the configuration client yet",
+		Key:  system.Namespace() + "/does not matter",
+		// No error nor event, just a requeue.
+		PostConditions: []func(*testing.T, *TableRow){
+			func(t *testing.T, _ *TableRow) {
+				if revisitedIn != informerSyncDelay {
+					t.Errorf("Revisited in %v, wanted %v", revisitedIn, informerSyncDelay)
+				}
+			},
+		},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		return &reconciler{
+			key: types.NamespacedName{
+				Name: name,
+			},
+			path: path,
+
+			handlers: handlers,
+
+			client:       kubeclient.Get(ctx),
+			mwhlister:    listers.GetMutatingWebhookConfigurationLister(),
+			mwhSynced:    func() bool { return false },
+			secretlister: listers.GetSecretLister(),
+			recorder:     controller.GetEventRecorder(ctx),
+			enqueueAfter: func(_ types.NamespacedName, d time.Duration) {
+				revisitedIn = d
+			},
+
+			secretName: secretName,
+			stats:      newTestStatsReporter(),
+		}
+	}))
+}
+
+func TestReconcileLogsDiff(t *testing.T) {
+	name, path := "foo.bar.baz", "/blah"
+	secretName := "webhook-secret"
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: system.Namespace(),
+		},
+		Data: map[string][]byte{
+			certresources.ServerKey:  []byte("present"),
+			certresources.ServerCert: []byte("present"),
+			certresources.CACert:     []byte("present"),
+		},
+	}
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: system.Namespace(),
+		},
+	}
+	nsRef := *metav1.NewControllerRef(ns, corev1.SchemeGroupVersion.WithKind("Namespace"))
+
+	gvk := schema.GroupVersionKind{
+		Group:   "pkg.knative.dev",
+		Version: "v1alpha1",
+		Kind:    "Resource",
+	}
+	mwh := func(caBundle string) *admissionregistrationv1.MutatingWebhookConfiguration {
+		return &admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				OwnerReferences: []metav1.OwnerReference{nsRef},
+			},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{{
+				Name: name,
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{
+						Namespace: system.Namespace(),
+						Name:      "webhook",
+						Path:      ptr.String(path),
+					},
+					CABundle: []byte(caBundle),
+				},
+				Rules: []admissionregistrationv1.RuleWithOperations{{
+					Operations: []admissionregistrationv1.OperationType{"CREATE", "UPDATE"},
+					Rule: admissionregistrationv1.Rule{
+						APIGroups:   []string{"pkg.knative.dev"},
+						APIVersions: []string{"v1alpha1"},
+						Resources:   []string{"resources", "resources/status"},
+					},
+				}},
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{
+						Key:      "webhooks.knative.dev/exclude",
+						Operator: metav1.LabelSelectorOpDoesNotExist,
+					}},
+				},
+			}},
+		}
+	}
+
+	core, logs := observer.New(zap.InfoLevel)
+
+	table := TableTest{{
+		Name:    "correcting the CABundle logs the diff",
+		Key:     system.Namespace() + "/does not matter",
+		Objects: []runtime.Object{secret, ns, mwh("incorrect")},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: mwh("present"),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, webhook.ReasonUpdated, "Updated webhook configuration %q", name),
+		},
+		PostConditions: []func(*testing.T, *TableRow){
+			func(t *testing.T, _ *TableRow) {
+				entries := logs.FilterMessage("Updating webhook").All()
+				if len(entries) != 1 {
+					t.Fatalf("Got %d update log entries, wanted 1", len(entries))
+				}
+				diff, ok := entries[0].ContextMap()["diff"].(string)
+				if !ok || !strings.Contains(diff, "CABundle") {
+					t.Errorf("Logged diff = %q, wanted it to mention the CABundle", diff)
+				}
+			},
+		},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		return &observedReconciler{
+			logger: zap.New(core).Sugar(),
+			reconciler: &reconciler{
+				key: types.NamespacedName{
+					Name: name,
+				},
+				path: path,
+
+				handlers: map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+					gvk: handlers[gvk],
+				},
+
+				client:       kubeclient.Get(ctx),
+				mwhlister:    listers.GetMutatingWebhookConfigurationLister(),
+				secretlister: listers.GetSecretLister(),
+				recorder:     controller.GetEventRecorder(ctx),
+
+				secretName: secretName,
+				stats:      newTestStatsReporter(),
+			},
+		}
+	}))
+}
+
+// observedReconciler runs the wrapped reconciler with the given logger, so
+// that the tests can inspect what gets logged.
+type observedReconciler struct {
+	*reconciler
+	logger *zap.SugaredLogger
+}
+
+func (r *observedReconciler) Reconcile(ctx context.Context, key string) error {
+	return r.reconciler.Reconcile(logging.WithLogger(ctx, r.logger), key)
+}
+
+func TestNew(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{})
+
+	c := NewAdmissionController(ctx, "foo", "/bar",
+		map[schema.GroupVersionKind]resourcesemantics.GenericCRD{},
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true /* disallow unknown field */)
+	if c == nil {
+		t.Fatal("Expected NewController to return a non-nil value")
+	}
+
+	if want, got := 0, c.WorkQueue().Len(); want != got {
+		t.Errorf("WorkQueue.Len() = %d, wanted %d", got, want)
+	}
+
+	la, ok := c.Reconciler.(pkgreconciler.LeaderAware)
+	if !ok {
+		t.Fatalf("%T is not leader aware", c.Reconciler)
+	}
+
+	if err := la.Promote(pkgreconciler.UniversalBucket(), c.MaybeEnqueueBucketKey); err != nil {
+		t.Error("Promote() =", err)
+	}
+
+	// Queue has async moving parts so if we check at the wrong moment, this might still be 0.
+	if wait.PollImmediate(10*time.Millisecond, 250*time.Millisecond, func() (bool, error) {
+		return c.WorkQueue().Len() == 1, nil
+	}) != nil {
+		t.Error("Queue length was never 1")
+	}
+}
+
+func TestNewPeriodicReconcile(t *testing.T) {
+	ctx, cancel, _ := SetupFakeContextWithCancel(t)
+	defer cancel()
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		ReconcilePeriod: 50 * time.Millisecond,
+	})
+
+	c := NewAdmissionController(ctx, "foo", "/bar",
+		map[schema.GroupVersionKind]resourcesemantics.GenericCRD{},
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true /* disallow unknown field */)
+
+	if want, got := 0, c.WorkQueue().Len(); want != got {
+		t.Errorf("WorkQueue.Len() = %d, wanted %d", got, want)
+	}
+
+	// Without any events or promotion, the singleton should be enqueued
+	// once the period elapses.
+	if wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return c.WorkQueue().Len() == 1, nil
+	}) != nil {
+		t.Error("Queue length was never 1")
+	}
+}
+
+func TestNewWatchesCACertFile(t *testing.T) {
+	ctx, cancel, _ := SetupFakeContextWithCancel(t)
+	defer cancel()
+	caCertFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caCertFile, []byte("before"), 0600); err != nil {
+		t.Fatal("WriteFile() =", err)
+	}
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		CACertFile: caCertFile,
+	})
+
+	c := NewAdmissionController(ctx, "foo", "/bar",
+		map[schema.GroupVersionKind]resourcesemantics.GenericCRD{},
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true /* disallow unknown field */)
+
+	if want, got := 0, c.WorkQueue().Len(); want != got {
+		t.Errorf("WorkQueue.Len() = %d, wanted %d", got, want)
+	}
+
+	// Rewriting the CA cert, e.g. as it is rotated, enqueues the singleton.
+	if err := os.WriteFile(caCertFile, []byte("after"), 0600); err != nil {
+		t.Fatal("WriteFile() =", err)
+	}
+	if wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return c.WorkQueue().Len() == 1, nil
+	}) != nil {
+		t.Error("Queue length was never 1")
+	}
+}
+
+// newTestStatsReporter returns the StatsReporter of the reconcilers under
+// test, which records the reasons of their reconciles.
+func newTestStatsReporter() webhook.StatsReporter {
+	reporter, _ := webhook.NewStatsReporter()
+	return &reconcileRecorder{StatsReporter: reporter}
+}
+
+// reconcileRecorder is a webhook.StatsReporter recording the reasons of the
+// reconciles.
+type reconcileRecorder struct {
+	webhook.StatsReporter
+	reasons []string
+}
+
+func (r *reconcileRecorder) ReportReconcile(_, reason string) error {
+	r.reasons = append(r.reasons, reason)
+	return nil
+}
+
+// wantReconcileReasons asserts that the reconciler of the row reported the
+// given reconcile reasons.
+func wantReconcileReasons(want ...string) func(*testing.T, *TableRow) {
+	return func(t *testing.T, r *TableRow) {
+		got := r.Reconciler.(*reconciler).stats.(*reconcileRecorder).reasons
+		if !cmp.Equal(got, want) {
+			t.Errorf("Reported reconcile reasons = %v, want: %v", got, want)
+		}
 	}
 }
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcesemantics
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ValidateHandlers checks that the handlers of an admission controller can
+// admit the objects of their kinds, which the compiler doesn't guarantee.
+// The objects are decoded into a DeepCopyObject of the handler of their kind,
+// which must therefore be a non-nil pointer to a struct, and return an object
+// of the same type, which is then defaulted and validated. The returned
+// error names the kinds of all the offending handlers, if any.
+func ValidateHandlers(handlers map[schema.GroupVersionKind]GenericCRD) error {
+	var errs []string
+	for gvk, handler := range handlers {
+		if err := validateHandler(handler); err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", gvk, err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Strings(errs)
+	return fmt.Errorf("invalid handlers: %s", strings.Join(errs, "; "))
+}
+
+// SortGVKs sorts gvks by group, version and kind, the order of the rules of
+// the webhook configurations of the admission controllers.
+func SortGVKs(gvks []schema.GroupVersionKind) {
+	sort.Slice(gvks, func(i, j int) bool {
+		lhs, rhs := gvks[i], gvks[j]
+		if lhs.Group != rhs.Group {
+			return lhs.Group < rhs.Group
+		}
+		if lhs.Version != rhs.Version {
+			return lhs.Version < rhs.Version
+		}
+		return lhs.Kind < rhs.Kind
+	})
+}
+
+// validateHandler checks a single handler, see ValidateHandlers.
+func validateHandler(handler GenericCRD) error {
+	if handler == nil {
+		return fmt.Errorf("handler is nil")
+	}
+	v := reflect.ValueOf(handler)
+	if v.Kind() != reflect.Ptr || v.Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("handler is a %T, want a pointer to a struct", handler)
+	}
+	if v.IsNil() {
+		return fmt.Errorf("handler is a nil %T", handler)
+	}
+	obj := handler.DeepCopyObject()
+	if _, ok := obj.(GenericCRD); !ok {
+		return fmt.Errorf("DeepCopyObject of %T returns a %T, which doesn't implement GenericCRD", handler, obj)
+	}
+	if reflect.TypeOf(obj) != v.Type() {
+		return fmt.Errorf("DeepCopyObject of %T returns a %T", handler, obj)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcesemantics
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	pkgtesting "knative.dev/pkg/testing"
+)
+
+// embeddedResource inherits its methods from Resource, including the
+// DeepCopyObject returning a *Resource.
+type embeddedResource struct {
+	pkgtesting.Resource
+}
+
+func TestValidateHandlers(t *testing.T) {
+	gvk := pkgtesting.SchemeGroupVersion.WithKind("Resource")
+
+	tests := []struct {
+		name    string
+		handler GenericCRD
+		wantErr string
+	}{{
+		name:    "valid",
+		handler: &pkgtesting.Resource{},
+	}, {
+		name:    "nil",
+		wantErr: "handler is nil",
+	}, {
+		name:    "nil pointer",
+		handler: (*pkgtesting.Resource)(nil),
+		wantErr: "handler is a nil *testing.Resource",
+	}, {
+		name:    "DeepCopyObject of another type",
+		handler: &embeddedResource{},
+		wantErr: "DeepCopyObject of *resourcesemantics.embeddedResource returns a *testing.Resource",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateHandlers(map[schema.GroupVersionKind]GenericCRD{
+				gvk: test.handler,
+			})
+			if test.wantErr == "" {
+				if err != nil {
+					t.Error("ValidateHandlers() =", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("ValidateHandlers() = nil, wanted an error")
+			}
+			for _, want := range []string{gvk.String(), test.wantErr} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("ValidateHandlers() = %v, wanted it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
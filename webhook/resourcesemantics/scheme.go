@@ -0,0 +1,41 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcesemantics
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// HandlersFromScheme returns the handlers of the admission controllers for
+// the kinds registered with scheme whose Go types implement GenericCRD. This
+// lets consumers register their own API types, the way they do for clients,
+// and have the objects of those kinds decoded into the registered types.
+func HandlersFromScheme(scheme *runtime.Scheme) map[schema.GroupVersionKind]GenericCRD {
+	handlers := make(map[schema.GroupVersionKind]GenericCRD)
+	for gvk, t := range scheme.AllKnownTypes() {
+		if gvk.Version == runtime.APIVersionInternal {
+			continue
+		}
+		if crd, ok := reflect.New(t).Interface().(GenericCRD); ok {
+			handlers[gvk] = crd
+		}
+	}
+	return handlers
+}
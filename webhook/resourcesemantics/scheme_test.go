@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcesemantics
+
+import (
+	"encoding/json"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	pkgtesting "knative.dev/pkg/testing"
+)
+
+func TestHandlersFromScheme(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := pkgtesting.AddToScheme(scheme); err != nil {
+		t.Fatal("AddToScheme() =", err)
+	}
+
+	handlers := HandlersFromScheme(scheme)
+	gvk := pkgtesting.SchemeGroupVersion.WithKind("Resource")
+	if got, want := len(handlers), 1; got != want {
+		t.Fatalf("len(HandlersFromScheme()) = %d, want: %d (%v)", got, want, handlers)
+	}
+	handler, ok := handlers[gvk]
+	if !ok {
+		t.Fatalf("HandlersFromScheme() = %v, wanted a handler for %v", handlers, gvk)
+	}
+
+	// Decode an object of the custom kind the way the admission controllers do.
+	raw, err := json.Marshal(&pkgtesting.Resource{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: "a name"},
+		Spec:       pkgtesting.ResourceSpec{FieldWithDefault: "a value"},
+	})
+	if err != nil {
+		t.Fatal("Marshal() =", err)
+	}
+	obj := handler.DeepCopyObject().(GenericCRD)
+	if err := json.Unmarshal(raw, obj); err != nil {
+		t.Fatal("Unmarshal() =", err)
+	}
+	r, ok := obj.(*pkgtesting.Resource)
+	if !ok {
+		t.Fatalf("Decoded a %T, wanted a *testing.Resource", obj)
+	}
+	if got, want := r.Spec.FieldWithDefault, "a value"; got != want {
+		t.Errorf("FieldWithDefault = %q, want: %q", got, want)
+	}
+}
+
+func TestHandlersFromSchemeSkipsOtherTypes(t *testing.T) {
+	scheme := runtime.NewScheme()
+	metav1.AddToGroupVersion(scheme, schema.GroupVersion{Group: "pkg.knative.dev", Version: "v2"})
+	if got := HandlersFromScheme(scheme); len(got) != 0 {
+		t.Errorf("HandlersFromScheme() = %v, wanted no handlers", got)
+	}
+}
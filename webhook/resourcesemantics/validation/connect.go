@@ -0,0 +1,163 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/gobuffalo/flect"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/pkg/apis"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/webhook"
+	"knative.dev/pkg/webhook/json"
+)
+
+// ConnectRequest is a CONNECT request on a connect subresource, e.g. the
+// exec of a command in a pod through pods/exec.
+type ConnectRequest struct {
+	// Name and Namespace are those of the object connected to, e.g. the pod.
+	Name      string
+	Namespace string
+
+	// SubResource is the subresource connected to, e.g. "exec".
+	SubResource string
+
+	// Options are the options of the connection, e.g. a *corev1.PodExecOptions
+	// for pods/exec or a *corev1.PodAttachOptions for pods/attach. The options
+	// of the kinds not known to this package are *unstructured.Unstructured.
+	Options runtime.Object
+}
+
+// connectOptions are the options of the connect subresources known to this
+// package, by kind.
+var connectOptions = map[schema.GroupVersionKind]func() runtime.Object{
+	corev1.SchemeGroupVersion.WithKind("PodExecOptions"):        func() runtime.Object { return &corev1.PodExecOptions{} },
+	corev1.SchemeGroupVersion.WithKind("PodAttachOptions"):      func() runtime.Object { return &corev1.PodAttachOptions{} },
+	corev1.SchemeGroupVersion.WithKind("PodPortForwardOptions"): func() runtime.Object { return &corev1.PodPortForwardOptions{} },
+	corev1.SchemeGroupVersion.WithKind("PodProxyOptions"):       func() runtime.Object { return &corev1.PodProxyOptions{} },
+	corev1.SchemeGroupVersion.WithKind("ServiceProxyOptions"):   func() runtime.Object { return &corev1.ServiceProxyOptions{} },
+	corev1.SchemeGroupVersion.WithKind("NodeProxyOptions"):      func() runtime.Object { return &corev1.NodeProxyOptions{} },
+}
+
+// NewConnectCallback creates a new callback function to be invoked on the
+// CONNECT requests on the given connect subresources, e.g. "exec" and
+// "attach", of the kind it is registered for, e.g. Pod. The webhook
+// configuration then also intercepts these requests.
+func NewConnectCallback(function func(context.Context, *ConnectRequest) error, subresources ...string) Callback {
+	if len(subresources) == 0 {
+		panic("at least one subresource must be given")
+	}
+	s := sets.NewString()
+	for _, sub := range subresources {
+		if s.Has(sub) {
+			panic("duplicate subresources not allowed")
+		}
+		s.Insert(sub)
+	}
+	return Callback{connectFunction: function, connectSubresources: s}
+}
+
+// resourceName returns the name of the resource of kind.
+func resourceName(gvk schema.GroupVersionKind) string {
+	return strings.ToLower(flect.Pluralize(gvk.Kind))
+}
+
+// connectRules returns the rules of the webhook for the connect subresources
+// the callbacks are registered for.
+func (ac *reconciler) connectRules() []admissionregistrationv1.RuleWithOperations {
+	var rules []admissionregistrationv1.RuleWithOperations
+	for gvk, c := range ac.callbacks {
+		if c.connectFunction == nil {
+			continue
+		}
+		plural := resourceName(gvk)
+		resources := make([]string, 0, c.connectSubresources.Len())
+		for _, sub := range c.connectSubresources.List() {
+			resources = append(resources, plural+"/"+sub)
+		}
+		rules = append(rules, admissionregistrationv1.RuleWithOperations{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Connect},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{gvk.Group},
+				APIVersions: []string{gvk.Version},
+				Resources:   resources,
+			},
+		})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Resources[0] < rules[j].Resources[0]
+	})
+	return rules
+}
+
+// connectCallback returns the callback registered for the connect
+// subresource of the request, if any.
+func (ac *reconciler) connectCallback(req *admissionv1.AdmissionRequest) (Callback, bool) {
+	for gvk, c := range ac.callbacks {
+		if c.connectFunction != nil &&
+			gvk.Group == req.Resource.Group && gvk.Version == req.Resource.Version &&
+			resourceName(gvk) == req.Resource.Resource &&
+			c.connectSubresources.Has(req.SubResource) {
+			return c, true
+		}
+	}
+	return Callback{}, false
+}
+
+// admitConnect admits a CONNECT request on a connect subresource with its
+// callback.
+func (ac *reconciler) admitConnect(ctx context.Context, request *admissionv1.AdmissionRequest, c Callback) *admissionv1.AdmissionResponse {
+	var opts runtime.Object = &unstructured.Unstructured{}
+	if newOpts, ok := connectOptions[schema.GroupVersionKind(request.Kind)]; ok {
+		opts = newOpts()
+	}
+	if err := json.Unmarshal(request.Object.Raw, opts); err != nil {
+		ac.reportDenial(ctx, request, denialReasonDecode)
+		return webhook.MakeErrorStatus("decoding request failed: cannot decode connect options: %v", err)
+	}
+
+	ctx = apis.WithUserInfo(ctx, &request.UserInfo)
+	ctx = context.WithValue(ctx, kubeclient.Key{}, ac.client)
+	if request.DryRun != nil && *request.DryRun {
+		ctx = apis.WithDryRun(ctx)
+	}
+
+	if err := recoverInternalError(ctx, func() error {
+		return c.connectFunction(ctx, &ConnectRequest{
+			Name:        request.Name,
+			Namespace:   request.Namespace,
+			SubResource: request.SubResource,
+			Options:     opts,
+		})
+	}); err != nil {
+		if resp, ok := ac.internalErrorResponse(ctx, request, err); ok {
+			return resp
+		}
+		ac.reportDenial(ctx, request, denialReasonCallback)
+		return webhook.MakeErrorStatus("validation callback failed: %v", err)
+	}
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
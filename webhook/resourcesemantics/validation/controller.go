@@ -18,6 +18,7 @@ package validation
 
 import (
 	"context"
+	"time"
 
 	// Injection stuff
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
@@ -26,8 +27,15 @@ import (
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
 
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	admissionregistrationinformers "k8s.io/client-go/informers/admissionregistration/v1"
 	"k8s.io/client-go/tools/cache"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/system"
@@ -35,6 +43,10 @@ import (
 	"knative.dev/pkg/webhook/resourcesemantics"
 )
 
+// reconcileJitter is the maximum jitter factor applied to the period
+// with which the webhook configuration is reconciled.
+const reconcileJitter = 0.2
+
 // NewAdmissionController constructs a reconciler
 func NewAdmissionController(
 	ctx context.Context,
@@ -46,10 +58,15 @@ func NewAdmissionController(
 ) *controller.Impl {
 
 	client := kubeclient.Get(ctx)
-	vwhInformer := vwhinformer.Get(ctx)
 	secretInformer := secretinformer.Get(ctx)
 	options := webhook.GetOptions(ctx)
 
+	// Fail fast on the handlers that would fail the admission of every object
+	// of their kinds.
+	if err := resourcesemantics.ValidateHandlers(handlers); err != nil {
+		panic(err)
+	}
+
 	// This not ideal, we are using a variadic argument to effectively make callbacks optional
 	// This allows this addition to be non-breaking to consumers of /pkg
 	// TODO: once all sub-repos have adopted this, we might move this back to a traditional param.
@@ -63,6 +80,33 @@ func NewAdmissionController(
 		panic("NewAdmissionController may not be called with multiple callback maps")
 	}
 
+	// The options may not have a reporter yet, webhook.New only defaults it
+	// once the admission controllers are constructed.
+	stats := options.StatsReporter
+	if stats == nil {
+		reporter, err := webhook.NewStatsReporter()
+		if err != nil {
+			panic(err)
+		}
+		stats = reporter
+	}
+
+	var (
+		vwhInformer admissionregistrationinformers.ValidatingWebhookConfigurationInformer
+		vwhFactory  informers.SharedInformerFactory
+	)
+	if options.ConfigurationClient != nil {
+		// Watch the configuration where it is managed, rather than through
+		// the informers of the cluster the webhook runs in.
+		vwhFactory = informers.NewSharedInformerFactoryWithOptions(options.ConfigurationClient, 0,
+			informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+				opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+			}))
+		vwhInformer = vwhFactory.Admissionregistration().V1().ValidatingWebhookConfigurations()
+	} else {
+		vwhInformer = vwhinformer.Get(ctx)
+	}
+
 	wh := &reconciler{
 		LeaderAwareFuncs: pkgreconciler.LeaderAwareFuncs{
 			// Have this reconciler enqueue our singleton whenever it becomes leader.
@@ -80,17 +124,34 @@ func NewAdmissionController(
 		callbacks: unwrappedCallbacks,
 
 		withContext:           wc,
+		controllerContext:     ctx,
 		disallowUnknownFields: disallowUnknownFields,
 		secretName:            options.SecretName,
-
-		client:       client,
-		vwhlister:    vwhInformer.Lister(),
-		secretlister: secretInformer.Lister(),
+		caCertFile:            options.CACertFile,
+		bypassUsernames:       sets.NewString(options.ValidationBypassUsernames...),
+		bypassGroups:          sets.NewString(options.ValidationBypassGroups...),
+		denyUnregisteredKinds: options.DenyUnregisteredKinds,
+		internalErrorPolicy:   options.ValidationInternalErrorPolicy,
+		canaryCohorts:         options.CanaryCohorts,
+		objectSelector:        options.ValidationObjectSelector,
+
+		client:              client,
+		configurationClient: options.ConfigurationClient,
+		vwhlister:           vwhInformer.Lister(),
+		secretlister:        secretInformer.Lister(),
+
+		stats: stats,
+	}
+	if vwhFactory != nil {
+		// The informers of the configuration client are not started, nor
+		// waited for, along with the injected ones.
+		wh.vwhSynced = vwhInformer.Informer().HasSynced
 	}
 
 	logger := logging.FromContext(ctx)
 	const queueName = "ValidationWebhook"
 	c := controller.NewContext(ctx, wh, controller.ControllerOptions{WorkQueueName: queueName, Logger: logger.Named(queueName)})
+	wh.enqueueAfter = c.EnqueueKeyAfter
 
 	// Reconcile when the named ValidatingWebhookConfiguration changes.
 	vwhInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
@@ -99,6 +160,16 @@ func NewAdmissionController(
 		// the named VWH resource.
 		Handler: controller.HandleAll(c.Enqueue),
 	})
+	if vwhFactory != nil {
+		vwhFactory.Start(ctx.Done())
+	}
+
+	if options.CACertFile != "" {
+		// Reconcile when the CA cert file changes.
+		if err := webhook.WatchFiles(ctx, func() { c.EnqueueKey(wh.key) }, options.CACertFile); err != nil {
+			logger.Errorw("Failed to watch the CA cert file, its changes are only picked up periodically", zap.Error(err))
+		}
+	}
 
 	// Reconcile when the cert bundle changes.
 	secretInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
@@ -108,5 +179,42 @@ func NewAdmissionController(
 		Handler: controller.HandleAll(c.Enqueue),
 	})
 
+	period := options.ReconcilePeriod
+	if period == 0 {
+		period = webhook.DefaultReconcilePeriod
+	}
+	if period > 0 {
+		// Reconcile periodically, so that drift introduced by other actors
+		// is corrected even when no events are received.
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait.Jitter(period, reconcileJitter)):
+					c.EnqueueKey(wh.key)
+				}
+			}
+		}()
+	}
+
 	return c
 }
+
+// RegisteredGVKs returns the kinds the admission controller returned by
+// NewAdmissionController is registered for, i.e. those of its handlers and
+// callbacks, sorted by group, version and kind, e.g. for a debug endpoint.
+func RegisteredGVKs(impl *controller.Impl) []schema.GroupVersionKind {
+	ac := impl.Reconciler.(*reconciler)
+	gvks := make([]schema.GroupVersionKind, 0, len(ac.handlers)+len(ac.callbacks))
+	for gvk := range ac.handlers {
+		gvks = append(gvks, gvk)
+	}
+	for gvk := range ac.callbacks {
+		if _, ok := ac.handlers[gvk]; !ok {
+			gvks = append(gvks, gvk)
+		}
+	}
+	resourcesemantics.SortGVKs(gvks)
+	return gvks
+}
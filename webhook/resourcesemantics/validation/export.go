@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"context"
+	"errors"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	"knative.dev/pkg/ptr"
+	"knative.dev/pkg/system"
+	"knative.dev/pkg/webhook"
+	"knative.dev/pkg/webhook/resourcesemantics"
+)
+
+// ValidatingWebhookConfiguration returns the ValidatingWebhookConfiguration
+// that the admission controller returned by NewAdmissionController
+// reconciles for the handlers and callbacks, given the webhook.Options of
+// ctx, trusting caCert. This allows the configuration to be committed and applied by a
+// GitOps pipeline, rather than being written by the webhook.
+//
+// The webhook's service must be set through webhook.Options.ServiceName.
+// Unlike the reconciled configuration, the returned one isn't owned by
+// system.Namespace(), whose UID is unknown.
+func ValidatingWebhookConfiguration(
+	ctx context.Context,
+	name, path string,
+	handlers map[schema.GroupVersionKind]resourcesemantics.GenericCRD,
+	caCert []byte,
+	callbacks ...map[schema.GroupVersionKind]Callback,
+) (*admissionregistrationv1.ValidatingWebhookConfiguration, error) {
+	options := webhook.GetOptions(ctx)
+	if options == nil || options.ServiceName == "" {
+		return nil, errors.New("the webhook's service name must be set")
+	}
+	if len(callbacks) > 1 {
+		return nil, errors.New("at most one callback map may be given")
+	}
+	serviceNamespace := options.ServiceNamespace
+	if serviceNamespace == "" {
+		serviceNamespace = system.Namespace()
+	}
+	reviewVersions := options.AdmissionReviewVersions
+	if len(reviewVersions) == 0 {
+		reviewVersions = webhook.DefaultAdmissionReviewVersions
+	}
+
+	ac := &reconciler{
+		path:           path,
+		handlers:       handlers,
+		canaryCohorts:  options.CanaryCohorts,
+		objectSelector: options.ValidationObjectSelector,
+	}
+	if len(callbacks) == 1 {
+		ac.callbacks = callbacks[0]
+	}
+	failurePolicy := admissionregistrationv1.Fail
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	var port *int32
+	if options.ServicePort != 0 {
+		port = ptr.Int32(options.ServicePort)
+	}
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "ValidatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name:                    name,
+			Rules:                   ac.rules(),
+			NamespaceSelector:       ac.namespaceSelector(),
+			FailurePolicy:           &failurePolicy,
+			SideEffects:             &sideEffects,
+			AdmissionReviewVersions: append([]string(nil), reviewVersions...),
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Name:      options.ServiceName,
+					Namespace: serviceNamespace,
+					Path:      ptr.String(ac.Path()),
+					Port:      port,
+				},
+				CABundle: caCert,
+			},
+		}},
+	}, nil
+}
+
+// RenderValidatingWebhookConfiguration renders the configuration returned by
+// ValidatingWebhookConfiguration as YAML.
+func RenderValidatingWebhookConfiguration(
+	ctx context.Context,
+	name, path string,
+	handlers map[schema.GroupVersionKind]resourcesemantics.GenericCRD,
+	caCert []byte,
+	callbacks ...map[schema.GroupVersionKind]Callback,
+) ([]byte, error) {
+	vwh, err := ValidatingWebhookConfiguration(ctx, name, path, handlers, caCert, callbacks...)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(vwh)
+}
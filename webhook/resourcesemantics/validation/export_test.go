@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"knative.dev/pkg/ptr"
+	"knative.dev/pkg/webhook"
+)
+
+func TestRenderValidatingWebhookConfiguration(t *testing.T) {
+	const name, path = "foo.bar.baz", "/blah"
+	ctx := webhook.WithOptions(context.Background(), webhook.Options{
+		ServiceName:      "webhook",
+		ServiceNamespace: "webhook-ns",
+		CanaryCohorts:    []string{"canary"},
+	})
+
+	got, err := RenderValidatingWebhookConfiguration(ctx, name, path, handlers, []byte("ca-cert"))
+	if err != nil {
+		t.Fatal("RenderValidatingWebhookConfiguration() =", err)
+	}
+	var vwh admissionregistrationv1.ValidatingWebhookConfiguration
+	if err := yaml.UnmarshalStrict(got, &vwh); err != nil {
+		t.Fatalf("Failed to unmarshal the rendered YAML: %v\n%s", err, got)
+	}
+
+	ops := []admissionregistrationv1.OperationType{"CREATE", "UPDATE", "DELETE"}
+	want := admissionregistrationv1.ValidatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "admissionregistration.k8s.io/v1",
+			Kind:       "ValidatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name: name,
+			Rules: []admissionregistrationv1.RuleWithOperations{{
+				Operations: ops,
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{"pkg.knative.dev"},
+					APIVersions: []string{"v1alpha1"},
+					Resources:   []string{"innerdefaultresources", "innerdefaultresources/status"},
+				},
+			}, {
+				Operations: ops,
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{"pkg.knative.dev"},
+					APIVersions: []string{"v1alpha1"},
+					Resources:   []string{"resources", "resources/status"},
+				},
+			}, {
+				Operations: ops,
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{"pkg.knative.dev"},
+					APIVersions: []string{"v1beta1"},
+					Resources:   []string{"resources", "resources/status"},
+				},
+			}, {
+				Operations: ops,
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{"pkg.knative.io"},
+					APIVersions: []string{"v1alpha1"},
+					Resources:   []string{"innerdefaultresources", "innerdefaultresources/status"},
+				},
+			}},
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      "webhooks.knative.dev/exclude",
+					Operator: metav1.LabelSelectorOpDoesNotExist,
+				}, {
+					Key:      webhook.CanaryLabelKey,
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   []string{"canary"},
+				}},
+			},
+			FailurePolicy:           failurePolicyPtr(admissionregistrationv1.Fail),
+			SideEffects:             sideEffectsPtr(admissionregistrationv1.SideEffectClassNone),
+			AdmissionReviewVersions: []string{"v1"},
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Name:      "webhook",
+					Namespace: "webhook-ns",
+					Path:      ptr.String(path),
+				},
+				CABundle: []byte("ca-cert"),
+			},
+		}},
+	}
+	if !cmp.Equal(vwh, want) {
+		t.Error("Rendered configuration (-got, +want):", cmp.Diff(vwh, want))
+	}
+}
+
+func TestRenderValidatingWebhookConfigurationWithoutService(t *testing.T) {
+	if _, err := RenderValidatingWebhookConfiguration(context.Background(), "foo.bar.baz", "/blah", handlers, nil); err == nil {
+		t.Error("RenderValidatingWebhookConfiguration() = nil, wanted an error without a service name")
+	}
+}
+
+func failurePolicyPtr(fp admissionregistrationv1.FailurePolicyType) *admissionregistrationv1.FailurePolicyType {
+	return &fp
+}
+
+func sideEffectsPtr(se admissionregistrationv1.SideEffectClass) *admissionregistrationv1.SideEffectClass {
+	return &se
+}
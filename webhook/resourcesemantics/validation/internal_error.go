@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/webhook"
+)
+
+// internalErrorWarning is the warning the requests allowed despite an
+// internal error are admitted with.
+const internalErrorWarning = "The request was not validated because of an internal error of the webhook"
+
+// internalError is an error of the webhook itself, rather than of the
+// request being validated.
+type internalError struct {
+	err error
+}
+
+// NewInternalError returns an error a callback may fail with when it can't
+// tell whether the request is valid, e.g. because it failed to look up
+// another resource, rather than because the request is invalid. Those
+// requests are admitted according to
+// webhook.Options.ValidationInternalErrorPolicy.
+func NewInternalError(err error) error {
+	return &internalError{err: err}
+}
+
+// Error implements error
+func (e *internalError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *internalError) Unwrap() error {
+	return e.err
+}
+
+// recoverInternalError runs fn, returning a panic of fn as an internal error.
+func recoverInternalError(ctx context.Context, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.FromContext(ctx).Errorw("Validation panicked",
+				zap.Any("panic", r), zap.ByteString("stack", debug.Stack()))
+			err = NewInternalError(fmt.Errorf("validation panicked: %v", r))
+		}
+	}()
+	return fn()
+}
+
+// internalErrorResponse returns the response to the request whose validation
+// failed with err, and true, if err is an internal error.
+func (ac *reconciler) internalErrorResponse(ctx context.Context, request *admissionv1.AdmissionRequest, err error) (*admissionv1.AdmissionResponse, bool) {
+	var ie *internalError
+	if !errors.As(err, &ie) {
+		return nil, false
+	}
+	if ac.internalErrorPolicy == admissionregistrationv1.Ignore {
+		logging.FromContext(ctx).Warnw("Allowing the request despite an internal error", zap.Error(err))
+		return &admissionv1.AdmissionResponse{
+			Allowed:  true,
+			Warnings: []string{internalErrorWarning},
+		}, true
+	}
+	ac.reportDenial(ctx, request, denialReasonInternal)
+	return webhook.MakeErrorStatus("validation failed because of an internal error: %v", err), true
+}
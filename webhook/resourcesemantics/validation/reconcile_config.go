@@ -19,17 +19,20 @@ package validation
 import (
 	"context"
 	"fmt"
+	"os"
 	"sort"
-	"strings"
+	"time"
 
-	"github.com/gobuffalo/flect"
+	"go.opencensus.io/trace"
 	"go.uber.org/zap"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
+	admissionclient "k8s.io/client-go/kubernetes/typed/admissionregistration/v1"
 	admissionlisters "k8s.io/client-go/listers/admissionregistration/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"knative.dev/pkg/controller"
@@ -43,6 +46,10 @@ import (
 	"knative.dev/pkg/webhook/resourcesemantics"
 )
 
+// informerSyncDelay is how long to wait before reconciling the webhook
+// configuration again when an informer it is read from has not synced yet.
+const informerSyncDelay = time.Second
+
 // reconciler implements the AdmissionController for resources
 type reconciler struct {
 	webhook.StatelessAdmissionImpl
@@ -54,13 +61,51 @@ type reconciler struct {
 	callbacks map[schema.GroupVersionKind]Callback
 
 	withContext func(context.Context) context.Context
+	// controllerContext is the context the admission controller was created
+	// with. Its values, e.g. the injected clients and informers, are made
+	// available to the validation of the resources.
+	controllerContext context.Context
 
 	client       kubernetes.Interface
 	vwhlister    admissionlisters.ValidatingWebhookConfigurationLister
 	secretlister corelisters.SecretLister
 
+	// configurationClient, when set, is the client the webhook configuration
+	// is managed with, in lieu of client, see
+	// webhook.Options.ConfigurationClient. vwhlister then lists its
+	// configurations.
+	configurationClient kubernetes.Interface
+	// vwhSynced, when set, reports whether vwhlister is synced.
+	vwhSynced func() bool
+	// enqueueAfter, when set, is used to revisit the webhook once vwhlister
+	// is synced.
+	enqueueAfter func(types.NamespacedName, time.Duration)
+
 	disallowUnknownFields bool
+	denyUnregisteredKinds bool
 	secretName            string
+	// caCertFile, when set, is read the CA cert from, in lieu of the secret.
+	caCertFile string
+
+	// canaryCohorts, when non-empty, restricts the webhook to the namespaces
+	// of these canary cohorts.
+	canaryCohorts []string
+
+	// objectSelector, if non-nil, holds the requirements of the object
+	// selector of the webhook.
+	objectSelector *metav1.LabelSelector
+
+	// bypassUsernames and bypassGroups are the users, and groups of users,
+	// whose requests are allowed without validation.
+	bypassUsernames sets.String
+	bypassGroups    sets.String
+
+	// internalErrorPolicy is how the requests whose validation fails
+	// because of an internal error are admitted, Fail if unset.
+	internalErrorPolicy admissionregistrationv1.FailurePolicyType
+
+	// stats reports the metrics of the admissions, e.g. their denials.
+	stats webhook.StatsReporter
 }
 
 var _ controller.Reconciler = (*reconciler)(nil)
@@ -81,53 +126,56 @@ func (ac *reconciler) Reconcile(ctx context.Context, key string) error {
 		return controller.NewSkipKey(key)
 	}
 
-	// Look up the webhook secret, and fetch the CA cert bundle.
-	secret, err := ac.secretlister.Secrets(system.Namespace()).Get(ac.secretName)
+	if ac.vwhSynced != nil && !ac.vwhSynced() {
+		// Don't report the configuration as missing before the cache had a
+		// chance to catch up, just try again later.
+		logger.Debug("Webhook configuration informer not synced yet, retrying later")
+		if ac.enqueueAfter != nil {
+			ac.enqueueAfter(ac.key, informerSyncDelay)
+		}
+		return nil
+	}
+
+	caCert, err := ac.fetchCACert(ctx)
 	if err != nil {
-		logger.Errorw("Error fetching secret", zap.Error(err))
 		return err
 	}
-	caCert, ok := secret.Data[certresources.CACert]
-	if !ok {
-		return fmt.Errorf("secret %q is missing %q key", ac.secretName, certresources.CACert)
-	}
 
 	// Reconcile the webhook configuration.
 	return ac.reconcileValidatingWebhook(ctx, caCert)
 }
 
-func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []byte) error {
+// fetchCACert returns the CA cert bundle of the webhook, read from the CA
+// cert file if set, or the webhook secret.
+func (ac *reconciler) fetchCACert(ctx context.Context) ([]byte, error) {
 	logger := logging.FromContext(ctx)
 
-	rules := make([]admissionregistrationv1.RuleWithOperations, 0, len(ac.handlers))
-	for gvk := range ac.handlers {
-		plural := strings.ToLower(flect.Pluralize(gvk.Kind))
+	if ac.caCertFile != "" {
+		caCert, err := os.ReadFile(ac.caCertFile)
+		if err != nil {
+			logger.Errorw("Error reading CA cert file", zap.Error(err))
+			return nil, err
+		}
+		return caCert, nil
+	}
 
-		rules = append(rules, admissionregistrationv1.RuleWithOperations{
-			Operations: []admissionregistrationv1.OperationType{
-				admissionregistrationv1.Create,
-				admissionregistrationv1.Update,
-				admissionregistrationv1.Delete,
-			},
-			Rule: admissionregistrationv1.Rule{
-				APIGroups:   []string{gvk.Group},
-				APIVersions: []string{gvk.Version},
-				Resources:   []string{plural, plural + "/status"},
-			},
-		})
+	// Look up the webhook secret, and fetch the CA cert bundle.
+	secret, err := ac.secretlister.Secrets(system.Namespace()).Get(ac.secretName)
+	if err != nil {
+		logger.Errorw("Error fetching secret", zap.Error(err))
+		return nil, err
+	}
+	caCert, ok := secret.Data[certresources.CACert]
+	if !ok {
+		return nil, fmt.Errorf("secret %q is missing %q key", ac.secretName, certresources.CACert)
 	}
+	return caCert, nil
+}
 
-	// Sort the rules by Group, Version, Kind so that things are deterministically ordered.
-	sort.Slice(rules, func(i, j int) bool {
-		lhs, rhs := rules[i], rules[j]
-		if lhs.APIGroups[0] != rhs.APIGroups[0] {
-			return lhs.APIGroups[0] < rhs.APIGroups[0]
-		}
-		if lhs.APIVersions[0] != rhs.APIVersions[0] {
-			return lhs.APIVersions[0] < rhs.APIVersions[0]
-		}
-		return lhs.Resources[0] < rhs.Resources[0]
-	})
+func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []byte) error {
+	logger := logging.FromContext(ctx)
+
+	rules := ac.rules()
 
 	configuredWebhook, err := ac.vwhlister.Get(ac.key.Name)
 	if err != nil {
@@ -136,13 +184,16 @@ func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []b
 
 	current := configuredWebhook.DeepCopy()
 
-	// Set the owner to namespace.
-	ns, err := ac.client.CoreV1().Namespaces().Get(ctx, system.Namespace(), metav1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to fetch namespace: %w", err)
+	// Set the owner to namespace, unless the configuration is managed in
+	// another cluster.
+	if ac.configurationClient == nil {
+		ns, err := ac.client.CoreV1().Namespaces().Get(ctx, system.Namespace(), metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to fetch namespace: %w", err)
+		}
+		nsRef := *metav1.NewControllerRef(ns, corev1.SchemeGroupVersion.WithKind("Namespace"))
+		current.OwnerReferences = []metav1.OwnerReference{nsRef}
 	}
-	nsRef := *metav1.NewControllerRef(ns, corev1.SchemeGroupVersion.WithKind("Namespace"))
-	current.OwnerReferences = []metav1.OwnerReference{nsRef}
 
 	for i, wh := range current.Webhooks {
 		if wh.Name != current.Name {
@@ -151,14 +202,10 @@ func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []b
 		cur := &current.Webhooks[i]
 		cur.Rules = rules
 
-		cur.NamespaceSelector = webhook.EnsureLabelSelectorExpressions(
-			cur.NamespaceSelector,
-			&metav1.LabelSelector{
-				MatchExpressions: []metav1.LabelSelectorRequirement{{
-					Key:      "webhooks.knative.dev/exclude",
-					Operator: metav1.LabelSelectorOpDoesNotExist,
-				}},
-			})
+		cur.NamespaceSelector = webhook.UpdateLabelSelectorExpressions(
+			cur.NamespaceSelector, ac.namespaceSelector())
+		cur.ObjectSelector = webhook.UpdateLabelSelectorExpressions(
+			cur.ObjectSelector, ac.objectSelector)
 
 		cur.ClientConfig.CABundle = caCert
 		if cur.ClientConfig.Service == nil {
@@ -172,7 +219,10 @@ func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []b
 	} else if !ok {
 		logger.Info("Updating webhook")
 		vwhclient := ac.client.AdmissionregistrationV1().ValidatingWebhookConfigurations()
-		if _, err := vwhclient.Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+		if ac.configurationClient != nil {
+			vwhclient = ac.configurationClient.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+		}
+		if err := updateWebhook(ctx, vwhclient, current); err != nil {
 			return fmt.Errorf("failed to update webhook: %w", err)
 		}
 	} else {
@@ -180,3 +230,70 @@ func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []b
 	}
 	return nil
 }
+
+// rules returns the rules of the webhook for the registered types, followed
+// by those for the connect subresources the callbacks are registered for.
+func (ac *reconciler) rules() []admissionregistrationv1.RuleWithOperations {
+	rules := make([]admissionregistrationv1.RuleWithOperations, 0, len(ac.handlers))
+	for gvk := range ac.handlers {
+		plural := resourceName(gvk)
+
+		rules = append(rules, admissionregistrationv1.RuleWithOperations{
+			Operations: []admissionregistrationv1.OperationType{
+				admissionregistrationv1.Create,
+				admissionregistrationv1.Update,
+				admissionregistrationv1.Delete,
+			},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{gvk.Group},
+				APIVersions: []string{gvk.Version},
+				Resources:   []string{plural, plural + "/status"},
+			},
+		})
+	}
+
+	// Sort the rules by Group, Version, Kind so that things are deterministically ordered.
+	sort.Slice(rules, func(i, j int) bool {
+		lhs, rhs := rules[i], rules[j]
+		if lhs.APIGroups[0] != rhs.APIGroups[0] {
+			return lhs.APIGroups[0] < rhs.APIGroups[0]
+		}
+		if lhs.APIVersions[0] != rhs.APIVersions[0] {
+			return lhs.APIVersions[0] < rhs.APIVersions[0]
+		}
+		return lhs.Resources[0] < rhs.Resources[0]
+	})
+	return append(rules, ac.connectRules()...)
+}
+
+// updateWebhook updates the webhook configuration within a span, a child of
+// the reconcile's span carried by ctx.
+func updateWebhook(ctx context.Context, client admissionclient.ValidatingWebhookConfigurationInterface, wh *admissionregistrationv1.ValidatingWebhookConfiguration) error {
+	ctx, span := trace.StartSpan(ctx, "UpdateValidatingWebhookConfiguration")
+	defer span.End()
+	span.AddAttributes(trace.StringAttribute("name", wh.Name))
+	_, err := client.Update(ctx, wh, metav1.UpdateOptions{})
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+	return err
+}
+
+// namespaceSelector returns the knative requirements of the namespace
+// selector of the webhook, which are reconciled alongside the foreign ones
+// like those of the MutatingWebhookConfiguration.
+func (ac *reconciler) namespaceSelector() *metav1.LabelSelector {
+	reqs := []metav1.LabelSelectorRequirement{{
+		Key:      "webhooks.knative.dev/exclude",
+		Operator: metav1.LabelSelectorOpDoesNotExist,
+	}}
+	if len(ac.canaryCohorts) > 0 {
+		// Only intercept requests in namespaces from the canary cohorts.
+		reqs = append(reqs, metav1.LabelSelectorRequirement{
+			Key:      webhook.CanaryLabelKey,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   ac.canaryCohorts,
+		})
+	}
+	return &metav1.LabelSelector{MatchExpressions: reqs}
+}
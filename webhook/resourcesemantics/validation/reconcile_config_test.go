@@ -18,6 +18,8 @@ package validation
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -108,6 +110,11 @@ func TestReconcile(t *testing.T) {
 		},
 	}}
 
+	caCertFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caCertFile, []byte("from-file"), 0600); err != nil {
+		t.Fatal("WriteFile() =", err)
+	}
+
 	// The key to use, which for this singleton reconciler doesn't matter (although the
 	// namespace matters for namespace validation).
 	key := system.Namespace() + "/does not matter"
@@ -193,6 +200,50 @@ func TestReconcile(t *testing.T) {
 				}},
 			},
 		}},
+	}, {
+		Name: "CA cert file and VWH exist, no secret",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			CACertFile: caCertFile,
+		}),
+		Objects: []runtime.Object{ns,
+			&admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+				},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+						},
+					},
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						// CABundle is read from the file.
+						CABundle: []byte("from-file"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+				}},
+			},
+		}},
 	}, {
 		Name: "secret and VWH exist, added fields are incorrect",
 		Key:  key,
@@ -411,6 +462,235 @@ func TestReconcile(t *testing.T) {
 				}},
 			},
 		}},
+	}, {
+		Name: "secret and VWH exist, adding canary namespaceSelector",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			CanaryCohorts: []string{"alpha", "beta"},
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules: expectedRules,
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "webhooks.knative.dev/exclude",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}, {
+							Key:      "foo.bar/baz",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}},
+					},
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules: expectedRules,
+					NamespaceSelector: &metav1.LabelSelector{
+						// The canary cohort is selected alongside the exclusion,
+						// while the non-knative key is kept.
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "webhooks.knative.dev/exclude",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}, {
+							Key:      webhook.CanaryLabelKey,
+							Operator: metav1.LabelSelectorOpIn,
+							Values:   []string{"alpha", "beta"},
+						}, {
+							Key:      "foo.bar/baz",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}},
+					},
+				}},
+			},
+		}},
+	}, {
+		Name: "secret and VWH exist, correcting a drifted objectSelector",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			ValidationObjectSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      "webhooks.knative.dev/skip-validation",
+					Operator: metav1.LabelSelectorOpDoesNotExist,
+				}},
+			},
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					ObjectSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "foo.bar/baz",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}, {
+							Key:      "webhooks.knative.dev/skip-validation",
+							Operator: metav1.LabelSelectorOpExists,
+						}, {
+							Key:      "webhooks.knative.dev/stale",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}},
+					},
+				}},
+			},
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: &admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					ObjectSelector: &metav1.LabelSelector{
+						// The drifted knative key is corrected in place, the
+						// stale one is removed and the non-knative key is kept.
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "foo.bar/baz",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}, {
+							Key:      "webhooks.knative.dev/skip-validation",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}},
+					},
+				}},
+			},
+		}},
+	}, {
+		Name: "secret and VWH exist, objectSelector up to date",
+		Key:  key,
+		Ctx: webhook.WithOptions(context.Background(), webhook.Options{
+			ValidationObjectSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      "webhooks.knative.dev/skip-validation",
+					Operator: metav1.LabelSelectorOpDoesNotExist,
+				}},
+			},
+		}),
+		Objects: []runtime.Object{secret, ns,
+			&admissionregistrationv1.ValidatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					OwnerReferences: expectedOwnerReferences,
+				},
+				Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+					Name: name,
+					ClientConfig: admissionregistrationv1.WebhookClientConfig{
+						Service: &admissionregistrationv1.ServiceReference{
+							Namespace: system.Namespace(),
+							Name:      "webhook",
+							Path:      ptr.String(path),
+						},
+						CABundle: []byte("present"),
+					},
+					Rules:             expectedRules,
+					NamespaceSelector: namespaceSelector,
+					ObjectSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "foo.bar/baz",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}, {
+							Key:      "webhooks.knative.dev/skip-validation",
+							Operator: metav1.LabelSelectorOpDoesNotExist,
+						}},
+					},
+				}},
+			},
+		},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := &reconciler{
+			key: types.NamespacedName{
+				Name: name,
+			},
+			path: path,
+
+			handlers: handlers,
+
+			client:       kubeclient.Get(ctx),
+			vwhlister:    listers.GetValidatingWebhookConfigurationLister(),
+			secretlister: listers.GetSecretLister(),
+
+			secretName: secretName,
+		}
+		if opts := webhook.GetOptions(ctx); opts != nil {
+			r.canaryCohorts = opts.CanaryCohorts
+			r.caCertFile = opts.CACertFile
+			r.objectSelector = opts.ValidationObjectSelector
+		}
+		return r
+	}))
+}
+
+func TestReconcileBeforeConfigurationSynced(t *testing.T) {
+	name, path := "foo.bar.baz", "/blah"
+	secretName := "webhook-secret"
+
+	var revisitedIn time.Duration
+	table := TableTest{{
+		Name: "configuration not in the cache of the configuration client yet",
+		Key:  system.Namespace() + "/does not matter",
+		// No error, just a requeue.
+		PostConditions: []func(*testing.T, *TableRow){
+			func(t *testing.T, _ *TableRow) {
+				if revisitedIn != informerSyncDelay {
+					t.Errorf("Revisited in %v, wanted %v", revisitedIn, informerSyncDelay)
+				}
+			},
+		},
 	}}
 
 	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
@@ -424,7 +704,11 @@ func TestReconcile(t *testing.T) {
 
 			client:       kubeclient.Get(ctx),
 			vwhlister:    listers.GetValidatingWebhookConfigurationLister(),
+			vwhSynced:    func() bool { return false },
 			secretlister: listers.GetSecretLister(),
+			enqueueAfter: func(_ types.NamespacedName, d time.Duration) {
+				revisitedIn = d
+			},
 
 			secretName: secretName,
 		}
@@ -466,3 +750,61 @@ func TestNew(t *testing.T) {
 	}
 
 }
+
+func TestNewPeriodicReconcile(t *testing.T) {
+	ctx, cancel, _ := SetupFakeContextWithCancel(t)
+	defer cancel()
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		ReconcilePeriod: 50 * time.Millisecond,
+	})
+
+	c := NewAdmissionController(ctx, "foo", "/bar",
+		map[schema.GroupVersionKind]resourcesemantics.GenericCRD{},
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true /* disallow unknown field */)
+
+	if want, got := 0, c.WorkQueue().Len(); want != got {
+		t.Errorf("WorkQueue.Len() = %d, wanted %d", got, want)
+	}
+
+	// Without any events or promotion, the singleton should be enqueued
+	// once the period elapses.
+	if wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return c.WorkQueue().Len() == 1, nil
+	}) != nil {
+		t.Error("Queue length was never 1")
+	}
+}
+
+func TestNewWatchesCACertFile(t *testing.T) {
+	ctx, cancel, _ := SetupFakeContextWithCancel(t)
+	defer cancel()
+	caCertFile := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caCertFile, []byte("before"), 0600); err != nil {
+		t.Fatal("WriteFile() =", err)
+	}
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		CACertFile: caCertFile,
+	})
+
+	c := NewAdmissionController(ctx, "foo", "/bar",
+		map[schema.GroupVersionKind]resourcesemantics.GenericCRD{},
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true /* disallow unknown field */)
+
+	if want, got := 0, c.WorkQueue().Len(); want != got {
+		t.Errorf("WorkQueue.Len() = %d, wanted %d", got, want)
+	}
+
+	// Rewriting the CA cert, e.g. as it is rotated, enqueues the singleton.
+	if err := os.WriteFile(caCertFile, []byte("after"), 0600); err != nil {
+		t.Fatal("WriteFile() =", err)
+	}
+	if wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return c.WorkQueue().Len() == 1, nil
+	}) != nil {
+		t.Error("Queue length was never 1")
+	}
+}
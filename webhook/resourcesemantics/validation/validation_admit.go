@@ -20,11 +20,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"go.uber.org/zap"
 	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"knative.dev/pkg/apis"
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/logging"
@@ -35,6 +38,17 @@ import (
 
 var errMissingNewObject = errors.New("the new object may not be nil")
 
+// The reasons the denials are reported with, besides the top-level fields
+// that failed validation, see validationDenialReason.
+const (
+	denialReasonUnregisteredKind = "unregistered_kind"
+	denialReasonDecode           = "decode"
+	denialReasonInvalid          = "invalid"
+	denialReasonCanceled         = "canceled"
+	denialReasonCallback         = "callback"
+	denialReasonInternal         = "internal"
+)
+
 // Callback is a generic function to be called by a consumer of validation
 type Callback struct {
 	// function is the callback to be invoked
@@ -43,6 +57,11 @@ type Callback struct {
 	// supportedVerbs are the verbs supported for the callback.
 	// The function will only be called on these actions.
 	supportedVerbs map[webhook.Operation]struct{}
+
+	// connectFunction, if set, is invoked on the CONNECT requests on the
+	// connectSubresources instead, see NewConnectCallback.
+	connectFunction     func(ctx context.Context, req *ConnectRequest) error
+	connectSubresources sets.String
 }
 
 // NewCallback creates a new callback function to be invoked on supported verbs.
@@ -61,33 +80,104 @@ var _ webhook.AdmissionController = (*reconciler)(nil)
 
 // Admit implements AdmissionController
 func (ac *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if ac.bypassesValidation(request) {
+		logging.FromContext(ctx).Debugf("Allowing request of %q without validation", request.UserInfo.Username)
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	if ac.controllerContext != nil {
+		// Allow validations that check other resources, e.g. that referenced
+		// namespaces exist, to use the injected listers and clients.
+		ctx = withValuesFrom(ctx, ac.controllerContext)
+	}
 	if ac.withContext != nil {
 		ctx = ac.withContext(ctx)
 	}
 
-	kind := request.Kind
-	gvk := schema.GroupVersionKind{
-		Group:   kind.Group,
-		Version: kind.Version,
-		Kind:    kind.Kind,
+	if request.Operation == admissionv1.Connect {
+		if c, ok := ac.connectCallback(request); ok {
+			return ac.admitConnect(ctx, request, c)
+		}
+	}
+
+	gvk := resourcesemantics.RequestKind(request)
+	if _, ok := ac.handlers[gvk]; !ok {
+		resp := resourcesemantics.UnregisteredKindResponse(ctx, gvk, ac.denyUnregisteredKinds)
+		if !resp.Allowed {
+			ac.reportDenial(ctx, request, denialReasonUnregisteredKind)
+		}
+		return resp
 	}
 
 	ctx, resource, err := ac.decodeRequestAndPrepareContext(ctx, request, gvk)
 	if err != nil {
+		ac.reportDenial(ctx, request, denialReasonDecode)
 		return webhook.MakeErrorStatus("decoding request failed: %v", err)
 	}
 
-	if err := validate(ctx, resource, request); err != nil {
+	if err := recoverInternalError(ctx, func() error {
+		return validate(ctx, resource, request)
+	}); err != nil {
+		if resp, ok := ac.internalErrorResponse(ctx, request, err); ok {
+			return resp
+		}
+		ac.reportDenial(ctx, request, validationDenialReason(err))
 		return webhook.MakeErrorStatus("validation failed: %v", err)
 	}
 
-	if err := ac.callback(ctx, request, gvk); err != nil {
+	// Don't run the callbacks for requests the API server gave up on.
+	if err := ctx.Err(); err != nil {
+		ac.reportDenial(ctx, request, denialReasonCanceled)
+		return webhook.MakeErrorStatus("validation canceled: %v", err)
+	}
+
+	if err := recoverInternalError(ctx, func() error {
+		return ac.callback(ctx, request, gvk)
+	}); err != nil {
+		if resp, ok := ac.internalErrorResponse(ctx, request, err); ok {
+			return resp
+		}
+		ac.reportDenial(ctx, request, denialReasonCallback)
 		return webhook.MakeErrorStatus("validation callback failed: %v", err)
 	}
 
 	return &admissionv1.AdmissionResponse{Allowed: true}
 }
 
+// validationDenialReason returns the reason to report a request failing
+// validation with err: the top-level fields of the fields in error, e.g.
+// "metadata,spec", which are bounded by the schema of the resource, or
+// denialReasonInvalid if err doesn't point at any.
+func validationDenialReason(err error) string {
+	var fe *apis.FieldError
+	if !errors.As(err, &fe) {
+		return denialReasonInvalid
+	}
+	fields := make([]string, 0, 1)
+	for _, field := range fe.TopLevelFields() {
+		if field != apis.CurrentField {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return denialReasonInvalid
+	}
+	return strings.Join(fields, ",")
+}
+
+// reportDenial reports that the request was denied for reason.
+func (ac *reconciler) reportDenial(ctx context.Context, req *admissionv1.AdmissionRequest, reason string) {
+	if err := ac.stats.ReportDenial(req, reason); err != nil {
+		logging.FromContext(ctx).Warnw("Failed to report the denial", zap.Error(err))
+	}
+}
+
+// bypassesValidation returns whether the request was made by a user, or a
+// member of a group, that is allowed without validation.
+func (ac *reconciler) bypassesValidation(req *admissionv1.AdmissionRequest) bool {
+	return ac.bypassUsernames.Has(req.UserInfo.Username) ||
+		ac.bypassGroups.HasAny(req.UserInfo.Groups...)
+}
+
 // decodeRequestAndPrepareContext deserializes the old and new GenericCrds from the incoming request and sets up the context.
 // nil oldObj or newObj denote absence of `old` (create) or `new` (delete) objects.
 func (ac *reconciler) decodeRequestAndPrepareContext(
@@ -141,6 +231,13 @@ func (ac *reconciler) decodeRequestAndPrepareContext(
 		ctx = apis.WithinCreate(ctx)
 	case admissionv1.Delete:
 		ctx = apis.WithinDelete(ctx)
+		if len(req.Options.Raw) != 0 {
+			opts := &metav1.DeleteOptions{}
+			if err := json.Decode(req.Options.Raw, opts, false); err != nil {
+				return ctx, nil, fmt.Errorf("cannot decode delete options: %w", err)
+			}
+			ctx = apis.WithDeleteOptions(ctx, opts)
+		}
 		return ctx, oldObj, nil
 	}
 
@@ -176,6 +273,28 @@ func validate(ctx context.Context, resource resourcesemantics.GenericCRD, req *a
 	return nil
 }
 
+// valuesContext is a context whose values not carried by the embedded
+// context are looked up in fallback instead.
+type valuesContext struct {
+	context.Context
+	fallback context.Context
+}
+
+// withValuesFrom returns a context carrying the deadline, cancellation and
+// values of ctx, which also provides the values of fallback that ctx doesn't
+// carry itself.
+func withValuesFrom(ctx, fallback context.Context) context.Context {
+	return valuesContext{Context: ctx, fallback: fallback}
+}
+
+// Value implements context.Context
+func (c valuesContext) Value(key interface{}) interface{} {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.fallback.Value(key)
+}
+
 // callback runs optional callbacks on admission
 func (ac *reconciler) callback(ctx context.Context, req *admissionv1.AdmissionRequest, gvk schema.GroupVersionKind) error {
 	var toDecode []byte
@@ -20,18 +20,24 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	// Injection stuff
 	_ "knative.dev/pkg/client/injection/kube/client/fake"
 	_ "knative.dev/pkg/client/injection/kube/informers/admissionregistration/v1/validatingwebhookconfiguration/fake"
+	namespaceinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/namespace"
+	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/namespace/fake"
 	_ "knative.dev/pkg/injection/clients/namespacedkube/informers/core/v1/secret/fake"
 	pkgreconciler "knative.dev/pkg/reconciler"
 
+	"github.com/google/go-cmp/cmp"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1beta1"
 	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -39,9 +45,11 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
 	"knative.dev/pkg/apis"
+	"knative.dev/pkg/metrics/metricstest"
 	"knative.dev/pkg/system"
 	"knative.dev/pkg/webhook"
 
+	_ "knative.dev/pkg/metrics/testing"
 	_ "knative.dev/pkg/system/testing"
 
 	. "knative.dev/pkg/logging/testing"
@@ -160,8 +168,112 @@ func TestConnectAllowed(t *testing.T) {
 	}
 }
 
+func TestConnectCallback(t *testing.T) {
+	_, ac := newNonRunningTestResourceAdmissionController(t)
+	ac.(*reconciler).callbacks = map[schema.GroupVersionKind]Callback{
+		corev1.SchemeGroupVersion.WithKind("Pod"): NewConnectCallback(func(ctx context.Context, req *ConnectRequest) error {
+			if req.Name != "protected" {
+				return nil
+			}
+			switch opts := req.Options.(type) {
+			case *corev1.PodExecOptions:
+				return fmt.Errorf("cannot exec %v into a protected pod", opts.Command)
+			case *corev1.PodAttachOptions:
+				return fmt.Errorf("cannot attach to container %q of a protected pod", opts.Container)
+			}
+			return fmt.Errorf("unexpected options %T", req.Options)
+		}, "exec", "attach"),
+	}
+
+	// The webhook intercepts the CONNECT requests on these subresources.
+	rules := ac.(*reconciler).rules()
+	rule := rules[len(rules)-1]
+	if got, want := rule.Resources, []string{"pods/attach", "pods/exec"}; !cmp.Equal(got, want) {
+		t.Errorf("Connect rule resources = %v, want: %v", got, want)
+	}
+	if got := rule.Operations; len(got) != 1 || got[0] != "CONNECT" {
+		t.Errorf("Connect rule operations = %v, want: [CONNECT]", got)
+	}
+
+	tests := []struct {
+		name        string
+		pod         string
+		subresource string
+		kind        string
+		options     runtime.Object
+		rejection   string
+	}{{
+		name:        "exec into a protected pod",
+		pod:         "protected",
+		subresource: "exec",
+		kind:        "PodExecOptions",
+		options:     &corev1.PodExecOptions{Container: "user-container", Command: []string{"sh"}},
+		rejection:   "validation callback failed: cannot exec [sh] into a protected pod",
+	}, {
+		name:        "attach to a protected pod",
+		pod:         "protected",
+		subresource: "attach",
+		kind:        "PodAttachOptions",
+		options:     &corev1.PodAttachOptions{Container: "user-container"},
+		rejection:   `validation callback failed: cannot attach to container "user-container" of a protected pod`,
+	}, {
+		name:        "exec into another pod",
+		pod:         "unprotected",
+		subresource: "exec",
+		kind:        "PodExecOptions",
+		options:     &corev1.PodExecOptions{Container: "user-container", Command: []string{"sh"}},
+	}, {
+		name:        "unregistered subresource",
+		pod:         "protected",
+		subresource: "portforward",
+		kind:        "PodPortForwardOptions",
+		options:     &corev1.PodPortForwardOptions{Ports: []int32{8080}},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := json.Marshal(tc.options)
+			if err != nil {
+				t.Fatal("Marshal() =", err)
+			}
+			req := &admissionv1.AdmissionRequest{
+				Operation:   admissionv1.Connect,
+				Kind:        metav1.GroupVersionKind{Version: "v1", Kind: tc.kind},
+				Resource:    metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+				SubResource: tc.subresource,
+				Name:        tc.pod,
+				Namespace:   "default",
+				Object:      runtime.RawExtension{Raw: raw},
+			}
+
+			resp := ac.Admit(TestContextWithLogger(t), req)
+			if tc.rejection == "" {
+				ExpectAllowed(t, resp)
+			} else {
+				ExpectFailsWith(t, resp, tc.rejection)
+			}
+		})
+	}
+}
+
+func TestUnknownKindAllowedByDefault(t *testing.T) {
+	_, ac := newNonRunningTestResourceAdmissionController(t)
+
+	req := &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Kind: metav1.GroupVersionKind{
+			Group:   "pkg.knative.dev",
+			Version: "v1alpha1",
+			Kind:    "Garbage",
+		},
+	}
+
+	ExpectAllowed(t, ac.Admit(TestContextWithLogger(t), req))
+}
+
 func TestUnknownKindFails(t *testing.T) {
 	_, ac := newNonRunningTestResourceAdmissionController(t)
+	ac.(*reconciler).denyUnregisteredKinds = true
 
 	req := &admissionv1.AdmissionRequest{
 		Operation: admissionv1.Create,
@@ -177,6 +289,7 @@ func TestUnknownKindFails(t *testing.T) {
 
 func TestUnknownVersionFails(t *testing.T) {
 	_, ac := newNonRunningTestResourceAdmissionController(t)
+	ac.(*reconciler).denyUnregisteredKinds = true
 	req := &admissionv1.AdmissionRequest{
 		Operation: admissionv1.Create,
 		Kind: metav1.GroupVersionKind{
@@ -210,7 +323,7 @@ func TestUnknownFieldFails(t *testing.T) {
 	req.Object.Raw = marshaled
 
 	ExpectFailsWith(t, ac.Admit(TestContextWithLogger(t), req),
-		`decoding request failed: cannot decode incoming new object: json: unknown field "foo"`)
+		`decoding request failed: cannot decode incoming new object: json: unknown field "foo": must not set the field(s): spec.foo`)
 }
 
 func TestUnknownMetadataFieldSucceeds(t *testing.T) {
@@ -298,6 +411,241 @@ func TestAdmitCreates(t *testing.T) {
 	}
 }
 
+func TestAdmitReportsDenials(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    func(context.Context, *testing.T) *admissionv1.AdmissionRequest
+		reason string
+	}{{
+		name: "invalid field",
+		req: func(ctx context.Context, t *testing.T) *admissionv1.AdmissionRequest {
+			r := CreateResource("a name")
+			r.Spec.FieldWithValidation = "not what's expected"
+			return createCreateResource(ctx, t, r)
+		},
+		reason: "spec",
+	}, {
+		name: "undecodable object",
+		req: func(ctx context.Context, t *testing.T) *admissionv1.AdmissionRequest {
+			req := createCreateResource(ctx, t, CreateResource("a name"))
+			req.Object.Raw = []byte(`{"spec":{"foo":"bar"}}`)
+			return req
+		},
+		reason: "decode",
+	}, {
+		name: "unregistered kind",
+		req: func(ctx context.Context, t *testing.T) *admissionv1.AdmissionRequest {
+			req := createCreateResource(ctx, t, CreateResource("a name"))
+			req.Kind.Version = "v1beta2"
+			return req
+		},
+		reason: "unregistered_kind",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			metricstest.Unregister("request_count", "request_latencies", "denial_count")
+			webhook.RegisterMetrics()
+
+			ctx := apis.WithUserInfo(TestContextWithLogger(t), &authenticationv1.UserInfo{Username: user1})
+			_, ac := newNonRunningTestResourceAdmissionController(t)
+			ac.(*reconciler).denyUnregisteredKinds = true
+
+			req := tc.req(ctx, t)
+			if resp := ac.Admit(ctx, req); resp.Allowed {
+				t.Fatal("Admit() allowed the request")
+			}
+
+			metricstest.CheckCountData(t, "denial_count", map[string]string{
+				"request_operation": string(admissionv1.Create),
+				"kind_group":        req.Kind.Group,
+				"kind_version":      req.Kind.Version,
+				"kind_kind":         req.Kind.Kind,
+				"denial_reason":     tc.reason,
+			}, 1)
+		})
+	}
+}
+
+// denialRecorder is a webhook.StatsReporter recording the denial reasons.
+type denialRecorder struct {
+	webhook.StatsReporter
+	reasons []string
+}
+
+func (r *denialRecorder) ReportDenial(_ *admissionv1.AdmissionRequest, reason string) error {
+	r.reasons = append(r.reasons, reason)
+	return nil
+}
+
+func TestAdmitReportsDenialsToOptionsReporter(t *testing.T) {
+	stats := &denialRecorder{}
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName:    "webhook-secret",
+		StatsReporter: stats,
+	})
+	ac := NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath,
+		handlers,
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true, callbacks).Reconciler.(*reconciler)
+
+	r := CreateResource("a name")
+	r.Spec.FieldWithValidation = "not what's expected"
+	ctx = apis.WithUserInfo(TestContextWithLogger(t), &authenticationv1.UserInfo{Username: user1})
+	if resp := ac.Admit(ctx, createCreateResource(ctx, t, r)); resp.Allowed {
+		t.Fatal("Admit() allowed the request")
+	}
+	if got, want := stats.reasons, []string{"spec"}; !cmp.Equal(got, want) {
+		t.Errorf("Reported denials = %v, want: %v", got, want)
+	}
+}
+
+func TestAdmitBypassesValidation(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName:                "webhook-secret",
+		ValidationBypassUsernames: []string{user1},
+		ValidationBypassGroups:    []string{"system:nodes"},
+	})
+	ac := NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath,
+		handlers,
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true, callbacks).Reconciler.(*reconciler)
+
+	tests := []struct {
+		name      string
+		userInfo  authenticationv1.UserInfo
+		rejection string
+	}{{
+		name:     "listed user",
+		userInfo: authenticationv1.UserInfo{Username: user1},
+	}, {
+		name: "member of a listed group",
+		userInfo: authenticationv1.UserInfo{
+			Username: user2,
+			Groups:   []string{"system:authenticated", "system:nodes"},
+		},
+	}, {
+		name: "other user",
+		userInfo: authenticationv1.UserInfo{
+			Username: user2,
+			Groups:   []string{"system:authenticated"},
+		},
+		rejection: "invalid value",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := apis.WithinCreate(apis.WithUserInfo(TestContextWithLogger(t), &tc.userInfo))
+			r := CreateResource("a name")
+			// Put a bad value in.
+			r.Spec.FieldWithValidation = "not what's expected"
+
+			resp := ac.Admit(ctx, createCreateResource(ctx, t, r))
+			if tc.rejection == "" {
+				ExpectAllowed(t, resp)
+			} else {
+				ExpectFailsWith(t, resp, tc.rejection)
+			}
+		})
+	}
+}
+
+// panickingResource is a Resource whose validation panics.
+type panickingResource struct {
+	Resource
+}
+
+func (r *panickingResource) Validate(context.Context) *apis.FieldError {
+	panic("validation bug")
+}
+
+func (r *panickingResource) DeepCopyObject() runtime.Object {
+	return &panickingResource{Resource: *r.Resource.DeepCopy()}
+}
+
+func TestAdmitInternalErrors(t *testing.T) {
+	panicking := schema.GroupVersionKind{
+		Group:   "pkg.knative.dev",
+		Version: "v1alpha1",
+		Kind:    "Panicking",
+	}
+	failingLookup := schema.GroupVersionKind{
+		Group:   "pkg.knative.dev",
+		Version: "v1alpha1",
+		Kind:    "Resource",
+	}
+	handlers := map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+		panicking:     &panickingResource{},
+		failingLookup: &Resource{},
+	}
+	callbacks := map[schema.GroupVersionKind]Callback{
+		failingLookup: NewCallback(func(context.Context, *unstructured.Unstructured) error {
+			return NewInternalError(errors.New("lookup failed"))
+		}, webhook.Create),
+	}
+
+	tests := []struct {
+		name      string
+		options   webhook.Options
+		kind      schema.GroupVersionKind
+		rejection string
+	}{{
+		name:      "panic, fail closed by default",
+		kind:      panicking,
+		rejection: "validation failed because of an internal error: validation panicked: validation bug",
+	}, {
+		name:      "panic, fail closed",
+		options:   webhook.Options{ValidationInternalErrorPolicy: "Fail"},
+		kind:      panicking,
+		rejection: "validation failed because of an internal error: validation panicked: validation bug",
+	}, {
+		name:    "panic, fail open",
+		options: webhook.Options{ValidationInternalErrorPolicy: "Ignore"},
+		kind:    panicking,
+	}, {
+		name:      "internal callback error, fail closed",
+		options:   webhook.Options{ValidationInternalErrorPolicy: "Fail"},
+		kind:      failingLookup,
+		rejection: "validation failed because of an internal error: lookup failed",
+	}, {
+		name:    "internal callback error, fail open",
+		options: webhook.Options{ValidationInternalErrorPolicy: "Ignore"},
+		kind:    failingLookup,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, _ := SetupFakeContext(t)
+			options := tc.options
+			options.SecretName = "webhook-secret"
+			ctx = webhook.WithOptions(ctx, options)
+			ac := NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath,
+				handlers,
+				func(ctx context.Context) context.Context {
+					return ctx
+				}, true, callbacks).Reconciler.(*reconciler)
+
+			ctx = apis.WithinCreate(apis.WithUserInfo(TestContextWithLogger(t), &authenticationv1.UserInfo{Username: user1}))
+			req := createCreateResource(ctx, t, CreateResource("a name"))
+			req.Kind.Kind = tc.kind.Kind
+
+			resp := ac.Admit(ctx, req)
+			if tc.rejection != "" {
+				ExpectFailsWith(t, resp, tc.rejection)
+				return
+			}
+			ExpectAllowed(t, resp)
+			if got, want := resp.Warnings, []string{internalErrorWarning}; !cmp.Equal(got, want) {
+				t.Errorf("Warnings = %v, want: %v", got, want)
+			}
+		})
+	}
+}
+
 func resourceCallback(ctx context.Context, uns *unstructured.Unstructured) error {
 	var resource Resource
 	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(uns.UnstructuredContent(), &resource); err != nil {
@@ -308,6 +656,10 @@ func resourceCallback(ctx context.Context, uns *unstructured.Unstructured) error
 		if resource.Spec.FieldForCallbackValidation != "magic delete" {
 			return errors.New("no magic delete")
 		}
+		if opts := apis.GetDeleteOptions(ctx); opts != nil && opts.PropagationPolicy != nil &&
+			*opts.PropagationPolicy != metav1.DeletePropagationForeground {
+			return fmt.Errorf("%s propagation not allowed", *opts.PropagationPolicy)
+		}
 		return nil
 	}
 
@@ -426,6 +778,47 @@ func TestValidationDeleteCallback(t *testing.T) {
 	}
 }
 
+func TestValidationDeleteCallbackOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    metav1.DeletionPropagation
+		rejection string
+	}{{
+		name:   "foreground propagation",
+		policy: metav1.DeletePropagationForeground,
+	}, {
+		name:      "background propagation",
+		policy:    metav1.DeletePropagationBackground,
+		rejection: "validation callback failed: Background propagation not allowed",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := CreateResource("a name")
+			r.Spec.FieldForCallbackValidation = "magic delete"
+			ctx := apis.WithUserInfo(
+				TestContextWithLogger(t),
+				&authenticationv1.UserInfo{Username: user1})
+
+			_, ac := newNonRunningTestResourceAdmissionController(t)
+			req := createDeleteResource(ctx, t, r)
+			opts, err := json.Marshal(&metav1.DeleteOptions{PropagationPolicy: &tc.policy})
+			if err != nil {
+				t.Fatal("Failed to marshal delete options:", err)
+			}
+			req.Options.Raw = opts
+
+			resp := ac.Admit(ctx, req)
+
+			if tc.rejection == "" {
+				ExpectAllowed(t, resp)
+			} else {
+				ExpectFailsWith(t, resp, tc.rejection)
+			}
+		})
+	}
+}
+
 func createDeleteResource(ctx context.Context, t *testing.T, old *Resource) *admissionv1.AdmissionRequest {
 	t.Helper()
 	req := &admissionv1.AdmissionRequest{
@@ -660,6 +1053,46 @@ func TestNewResourceAdmissionControllerDuplicateVerb(t *testing.T) {
 		call)
 }
 
+// embeddedResource doesn't conform, as the DeepCopyObject it inherits from
+// Resource returns a *Resource.
+type embeddedResource struct {
+	Resource
+}
+
+func TestNewResourceAdmissionControllerInvalidHandler(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("Expected a non-conforming handler to panic")
+		}
+		if got, want := fmt.Sprint(r), "pkg.knative.dev/v1, Kind=Embedded"; !strings.Contains(got, want) {
+			t.Errorf("Panic = %s, wanted it to name %s", got, want)
+		}
+	}()
+
+	invalid := map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
+		{
+			Group:   "pkg.knative.dev",
+			Version: "v1alpha1",
+			Kind:    "Resource",
+		}: &Resource{},
+		{
+			Group:   "pkg.knative.dev",
+			Version: "v1",
+			Kind:    "Embedded",
+		}: &embeddedResource{},
+	}
+
+	NewAdmissionController(
+		ctx, testResourceValidationName, testResourceValidationPath,
+		invalid,
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true)
+}
+
 func newTestResourceAdmissionController(t *testing.T) webhook.AdmissionController {
 	ctx, _ := SetupFakeContext(t)
 	ctx = webhook.WithOptions(ctx, webhook.Options{
@@ -698,3 +1131,94 @@ func newTestResourceAdmissionController(t *testing.T) webhook.AdmissionControlle
 
 	return c.Reconciler.(*reconciler)
 }
+
+func TestRegisteredGVKs(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{})
+
+	c := NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath, handlers,
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true /* disallow unknown field */)
+
+	want := []schema.GroupVersionKind{
+		{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "InnerDefaultResource"},
+		{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Resource"},
+		{Group: "pkg.knative.dev", Version: "v1beta1", Kind: "Resource"},
+		{Group: "pkg.knative.io", Version: "v1alpha1", Kind: "InnerDefaultResource"},
+	}
+	if got := RegisteredGVKs(c); !cmp.Equal(got, want) {
+		t.Errorf("RegisteredGVKs() = %v, wanted %v", got, want)
+	}
+}
+
+func TestValidationWithInjectedListers(t *testing.T) {
+	// A cluster-scoped resource referencing a namespace through an annotation.
+	const namespaceAnnotation = "pkg.knative.dev/namespace"
+	gvk := schema.GroupVersionKind{Group: "pkg.knative.dev", Version: "v1alpha1", Kind: "Resource"}
+	namespaceExists := func(ctx context.Context, uns *unstructured.Unstructured) error {
+		ns := uns.GetAnnotations()[namespaceAnnotation]
+		if _, err := namespaceinformer.Get(ctx).Lister().Get(ns); err != nil {
+			return fmt.Errorf("referenced namespace %q: %w", ns, err)
+		}
+		return nil
+	}
+
+	ctx, _ := SetupFakeContext(t)
+	ctx = webhook.WithOptions(ctx, webhook.Options{
+		SecretName: "webhook-secret",
+	})
+	namespaceinformer.Get(ctx).Informer().GetIndexer().Add(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "exists"},
+	})
+	c := NewAdmissionController(ctx, testResourceValidationName, testResourceValidationPath,
+		handlers,
+		func(ctx context.Context) context.Context {
+			return ctx
+		}, true,
+		map[schema.GroupVersionKind]Callback{
+			gvk: NewCallback(namespaceExists, webhook.Create, webhook.Update),
+		})
+	ac := c.Reconciler.(*reconciler)
+
+	tests := []struct {
+		name      string
+		namespace string
+		want      string
+	}{{
+		name:      "existing namespace",
+		namespace: "exists",
+	}, {
+		name:      "missing namespace",
+		namespace: "missing",
+		want:      `validation callback failed: referenced namespace "missing": namespace "missing" not found`,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &Resource{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: gvk.GroupVersion().String(),
+					Kind:       gvk.Kind,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "a name",
+					Annotations: map[string]string{namespaceAnnotation: test.namespace},
+				},
+				Spec: ResourceSpec{
+					FieldWithValidation: "magic value",
+				},
+			}
+			r.SetDefaults(context.Background())
+			req := NewAdmissionRequest().WithObject(r).WithKind(gvk).Request()
+
+			// The request context doesn't carry the informers, Admit provides them.
+			resp := ac.Admit(TestContextWithLogger(t), req)
+			if test.want == "" {
+				ExpectAllowed(t, resp)
+			} else {
+				ExpectFailsWith(t, resp, test.want)
+			}
+		})
+	}
+}
@@ -31,6 +31,10 @@ import (
 const (
 	requestCountName     = "request_count"
 	requestLatenciesName = "request_latencies"
+	denialCountName      = "denial_count"
+
+	driftCorrectionCountName = "drift_correction_count"
+	reconcileCountName       = "reconcile_count"
 )
 
 var (
@@ -42,6 +46,18 @@ var (
 		requestLatenciesName,
 		"The response time in milliseconds",
 		stats.UnitMilliseconds)
+	denialCountM = stats.Int64(
+		denialCountName,
+		"The number of requests denied by the webhook, by reason",
+		stats.UnitDimensionless)
+	driftCorrectionCountM = stats.Int64(
+		driftCorrectionCountName,
+		"The number of times the webhook reverted changes made by others to its configuration",
+		stats.UnitDimensionless)
+	reconcileCountM = stats.Int64(
+		reconcileCountName,
+		"The number of reconciliations of the webhook configuration, by result",
+		stats.UnitDimensionless)
 
 	// Create the tag keys that will be used to add tags to our measurements.
 	// Tag keys must conform to the restrictions described in
@@ -57,11 +73,29 @@ var (
 	resourceResourceKey  = tag.MustNewKey("resource_resource")
 	resourceNamespaceKey = tag.MustNewKey("resource_namespace")
 	admissionAllowedKey  = tag.MustNewKey("admission_allowed")
+	denialReasonKey      = tag.MustNewKey("denial_reason")
+	configurationNameKey = tag.MustNewKey("configuration_name")
+	reconcileReasonKey   = tag.MustNewKey("reconcile_reason")
 )
 
 // StatsReporter reports webhook metrics
 type StatsReporter interface {
 	ReportRequest(request *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse, d time.Duration) error
+
+	// ReportDenial records that the webhook denied the request for reason,
+	// which must come from a bounded set of values, e.g. reason codes or the
+	// top-level fields that failed validation, to keep the cardinality of the
+	// metric bounded.
+	ReportDenial(request *admissionv1.AdmissionRequest, reason string) error
+
+	// ReportDriftCorrection records that the webhook reverted the changes
+	// made by another actor to the webhook configuration with the given name.
+	ReportDriftCorrection(name string) error
+
+	// ReportReconcile records the result of a reconciliation of the webhook
+	// configuration with the given name, by its reason, e.g.
+	// ReasonAlreadyReconciled or ReasonUpdated.
+	ReportReconcile(name, reason string) error
 }
 
 // reporter implements StatsReporter interface
@@ -105,6 +139,53 @@ func (r *reporter) ReportRequest(req *admissionv1.AdmissionRequest, resp *admiss
 	return nil
 }
 
+// Captures denial count metric
+func (r *reporter) ReportDenial(req *admissionv1.AdmissionRequest, reason string) error {
+	ctx, err := tag.New(
+		r.ctx,
+		tag.Insert(requestOperationKey, string(req.Operation)),
+		tag.Insert(kindGroupKey, req.Kind.Group),
+		tag.Insert(kindVersionKey, req.Kind.Version),
+		tag.Insert(kindKindKey, req.Kind.Kind),
+		tag.Insert(denialReasonKey, reason),
+	)
+	if err != nil {
+		return err
+	}
+
+	metrics.Record(ctx, denialCountM.M(1))
+	return nil
+}
+
+// Captures drift correction count metric
+func (r *reporter) ReportDriftCorrection(name string) error {
+	ctx, err := tag.New(
+		r.ctx,
+		tag.Insert(configurationNameKey, name),
+	)
+	if err != nil {
+		return err
+	}
+
+	metrics.Record(ctx, driftCorrectionCountM.M(1))
+	return nil
+}
+
+// Captures reconcile count metric
+func (r *reporter) ReportReconcile(name, reason string) error {
+	ctx, err := tag.New(
+		r.ctx,
+		tag.Insert(configurationNameKey, name),
+		tag.Insert(reconcileReasonKey, reason),
+	)
+	if err != nil {
+		return err
+	}
+
+	metrics.Record(ctx, reconcileCountM.M(1))
+	return nil
+}
+
 func RegisterMetrics() {
 	tagKeys := []tag.Key{
 		requestOperationKey,
@@ -130,6 +211,30 @@ func RegisterMetrics() {
 			Aggregation: view.Distribution(metrics.Buckets125(1, 100000)...), // [1 2 5 10 20 50 100 200 500 1000 2000 5000 10000 20000 50000 100000]ms
 			TagKeys:     tagKeys,
 		},
+		&view.View{
+			Description: denialCountM.Description(),
+			Measure:     denialCountM,
+			Aggregation: view.Count(),
+			TagKeys: []tag.Key{
+				requestOperationKey,
+				kindGroupKey,
+				kindVersionKey,
+				kindKindKey,
+				denialReasonKey,
+			},
+		},
+		&view.View{
+			Description: driftCorrectionCountM.Description(),
+			Measure:     driftCorrectionCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{configurationNameKey},
+		},
+		&view.View{
+			Description: reconcileCountM.Description(),
+			Measure:     reconcileCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{configurationNameKey, reconcileReasonKey},
+		},
 	); err != nil {
 		panic(err)
 	}
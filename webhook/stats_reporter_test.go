@@ -65,12 +65,37 @@ func TestWebhookStatsReporter(t *testing.T) {
 	metricstest.CheckDistributionData(t, requestLatenciesName, expectedTags, 2, shortTime, longTime)
 }
 
+func TestReportDriftCorrection(t *testing.T) {
+	setup()
+
+	r, _ := NewStatsReporter()
+	r.ReportDriftCorrection("defaulting.webhook.knative.dev")
+	r.ReportDriftCorrection("defaulting.webhook.knative.dev")
+
+	metricstest.CheckCountData(t, driftCorrectionCountName, map[string]string{
+		configurationNameKey.Name(): "defaulting.webhook.knative.dev",
+	}, 2)
+}
+
+func TestReportReconcile(t *testing.T) {
+	setup()
+
+	r, _ := NewStatsReporter()
+	r.ReportReconcile("defaulting.webhook.knative.dev", ReasonAlreadyReconciled)
+	r.ReportReconcile("defaulting.webhook.knative.dev", ReasonAlreadyReconciled)
+
+	metricstest.CheckCountData(t, reconcileCountName, map[string]string{
+		configurationNameKey.Name(): "defaulting.webhook.knative.dev",
+		reconcileReasonKey.Name():   ReasonAlreadyReconciled,
+	}, 2)
+}
+
 func setup() {
 	resetMetrics()
 }
 
 // opencensus metrics carry global state that need to be reset between unit tests
 func resetMetrics() {
-	metricstest.Unregister(requestCountName, requestLatenciesName)
+	metricstest.Unregister(requestCountName, requestLatenciesName, denialCountName, driftCorrectionCountName, reconcileCountName)
 	RegisterMetrics()
 }
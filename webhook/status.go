@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"sync"
+
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+const (
+	// CertificatesReady is set when the webhook's serving certificates
+	// are present and valid.
+	CertificatesReady apis.ConditionType = "CertificatesReady"
+
+	// WebhookConfigured is set when the webhook configuration has been
+	// reconciled.
+	WebhookConfigured apis.ConditionType = "WebhookConfigured"
+)
+
+var statusCondSet = apis.NewLivingConditionSet(CertificatesReady, WebhookConfigured)
+
+// Status tracks the health of the webhook as a set of conditions, so that
+// it can be surfaced on the status of the resource configuring the webhook.
+// Ready is true only when all of CertificatesReady and WebhookConfigured are.
+// A Status is safe for concurrent use and a nil *Status ignores all updates.
+type Status struct {
+	mu     sync.RWMutex
+	status duckv1.Status
+}
+
+// NewStatus returns a Status with all of its conditions Unknown.
+func NewStatus() *Status {
+	s := &Status{}
+	statusCondSet.Manage(&s.status).InitializeConditions()
+	return s
+}
+
+// MarkCertificatesReady marks the CertificatesReady condition True.
+func (s *Status) MarkCertificatesReady() {
+	s.update(func(cm apis.ConditionManager) {
+		cm.MarkTrue(CertificatesReady)
+	})
+}
+
+// MarkCertificatesNotReady marks the CertificatesReady condition False with
+// the given reason and message.
+func (s *Status) MarkCertificatesNotReady(reason, messageFormat string, messageA ...interface{}) {
+	s.update(func(cm apis.ConditionManager) {
+		cm.MarkFalse(CertificatesReady, reason, messageFormat, messageA...)
+	})
+}
+
+// MarkWebhookConfigured marks the WebhookConfigured condition True.
+func (s *Status) MarkWebhookConfigured() {
+	s.update(func(cm apis.ConditionManager) {
+		cm.MarkTrue(WebhookConfigured)
+	})
+}
+
+// MarkWebhookConfiguredWithReason marks the WebhookConfigured condition
+// True with the given reason and message, e.g. to report that it was
+// configured in a degraded fashion.
+func (s *Status) MarkWebhookConfiguredWithReason(reason, messageFormat string, messageA ...interface{}) {
+	s.update(func(cm apis.ConditionManager) {
+		cm.MarkTrueWithReason(WebhookConfigured, reason, messageFormat, messageA...)
+	})
+}
+
+// MarkWebhookNotConfigured marks the WebhookConfigured condition False with
+// the given reason and message.
+func (s *Status) MarkWebhookNotConfigured(reason, messageFormat string, messageA ...interface{}) {
+	s.update(func(cm apis.ConditionManager) {
+		cm.MarkFalse(WebhookConfigured, reason, messageFormat, messageA...)
+	})
+}
+
+// IsReady returns whether the webhook is healthy.
+func (s *Status) IsReady() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return statusCondSet.Manage(&s.status).IsHappy()
+}
+
+// Snapshot returns a copy of the current status.
+func (s *Status) Snapshot() duckv1.Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return *s.status.DeepCopy()
+}
+
+func (s *Status) update(f func(apis.ConditionManager)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(statusCondSet.Manage(&s.status))
+}
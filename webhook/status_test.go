@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/apis"
+)
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		mark func(*Status)
+		want corev1.ConditionStatus
+	}{{
+		name: "initialized",
+		mark: func(*Status) {},
+		want: corev1.ConditionUnknown,
+	}, {
+		name: "only certificates ready",
+		mark: func(s *Status) {
+			s.MarkCertificatesReady()
+		},
+		want: corev1.ConditionUnknown,
+	}, {
+		name: "only webhook configured",
+		mark: func(s *Status) {
+			s.MarkWebhookConfigured()
+		},
+		want: corev1.ConditionUnknown,
+	}, {
+		name: "both ready",
+		mark: func(s *Status) {
+			s.MarkCertificatesReady()
+			s.MarkWebhookConfigured()
+		},
+		want: corev1.ConditionTrue,
+	}, {
+		name: "certificates not ready",
+		mark: func(s *Status) {
+			s.MarkWebhookConfigured()
+			s.MarkCertificatesNotReady("Expired", "the certificate expired")
+		},
+		want: corev1.ConditionFalse,
+	}, {
+		name: "webhook no longer configured",
+		mark: func(s *Status) {
+			s.MarkCertificatesReady()
+			s.MarkWebhookConfigured()
+			s.MarkWebhookNotConfigured("UpdateFailed", "failed to update webhook")
+		},
+		want: corev1.ConditionFalse,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewStatus()
+			test.mark(s)
+
+			status := s.Snapshot()
+			ready := status.GetCondition(apis.ConditionReady)
+			if ready == nil {
+				t.Fatal("Ready condition is missing")
+			}
+			if got := ready.Status; got != test.want {
+				t.Errorf("Ready = %v, wanted %v", got, test.want)
+			}
+			if got, want := s.IsReady(), test.want == corev1.ConditionTrue; got != want {
+				t.Errorf("IsReady() = %v, wanted %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNilStatus(t *testing.T) {
+	var s *Status
+	// Updates on a nil Status are ignored.
+	s.MarkCertificatesReady()
+	s.MarkCertificatesNotReady("reason", "message")
+	s.MarkWebhookConfigured()
+	s.MarkWebhookNotConfigured("reason", "message")
+}
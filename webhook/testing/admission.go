@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"encoding/json"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/ptr"
+)
+
+// AdmissionRequestBuilder assembles admission requests and reviews for tests.
+// The builder panics when the objects it is given cannot be serialized.
+type AdmissionRequestBuilder struct {
+	req admissionv1.AdmissionRequest
+}
+
+// NewAdmissionRequest returns a builder for a Create request with a unique UID.
+func NewAdmissionRequest() *AdmissionRequestBuilder {
+	return &AdmissionRequestBuilder{
+		req: admissionv1.AdmissionRequest{
+			UID:       uuid.NewUUID(),
+			Operation: admissionv1.Create,
+		},
+	}
+}
+
+// WithOperation sets the operation of the request.
+func (b *AdmissionRequestBuilder) WithOperation(op admissionv1.Operation) *AdmissionRequestBuilder {
+	b.req.Operation = op
+	return b
+}
+
+// WithObject sets the new object of the request. Unless overridden with
+// WithKind, the kind, resource, name and namespace of the request are
+// derived from the object.
+func (b *AdmissionRequestBuilder) WithObject(obj runtime.Object) *AdmissionRequestBuilder {
+	b.req.Object = rawExtension(obj)
+	b.inferFrom(obj)
+	return b
+}
+
+// WithOldObject sets the old object of the request, as found with updates.
+func (b *AdmissionRequestBuilder) WithOldObject(obj runtime.Object) *AdmissionRequestBuilder {
+	b.req.OldObject = rawExtension(obj)
+	if b.req.Kind.Kind == "" {
+		b.inferFrom(obj)
+	}
+	return b
+}
+
+// WithKind sets the kind of the request and the resource derived from it.
+func (b *AdmissionRequestBuilder) WithKind(gvk schema.GroupVersionKind) *AdmissionRequestBuilder {
+	b.req.Kind = metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind}
+	gvr := apis.KindToResource(gvk)
+	b.req.Resource = metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource}
+	return b
+}
+
+// WithSubResource sets the subresource the request is for, e.g. "status".
+func (b *AdmissionRequestBuilder) WithSubResource(sr string) *AdmissionRequestBuilder {
+	b.req.SubResource = sr
+	return b
+}
+
+// WithUser sets the user making the request.
+func (b *AdmissionRequestBuilder) WithUser(username string, groups ...string) *AdmissionRequestBuilder {
+	b.req.UserInfo = authenticationv1.UserInfo{
+		Username: username,
+		Groups:   groups,
+	}
+	return b
+}
+
+// WithDryRun marks the request as a dry run.
+func (b *AdmissionRequestBuilder) WithDryRun() *AdmissionRequestBuilder {
+	b.req.DryRun = ptr.Bool(true)
+	return b
+}
+
+// Request returns a copy of the built admission request.
+func (b *AdmissionRequestBuilder) Request() *admissionv1.AdmissionRequest {
+	return b.req.DeepCopy()
+}
+
+// Review returns an AdmissionReview wrapping a copy of the built request,
+// ready to be sent to the admission handler.
+func (b *AdmissionRequestBuilder) Review() *admissionv1.AdmissionReview {
+	return &admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionv1.SchemeGroupVersion.String(),
+			Kind:       "AdmissionReview",
+		},
+		Request: b.Request(),
+	}
+}
+
+// inferFrom populates the kind, name and namespace of the request from obj.
+func (b *AdmissionRequestBuilder) inferFrom(obj runtime.Object) {
+	if obj == nil {
+		return
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if g, ok := obj.(interface{ GetGroupVersionKind() schema.GroupVersionKind }); ok {
+		gvk = g.GetGroupVersionKind()
+	}
+	if !gvk.Empty() {
+		b.WithKind(gvk)
+	}
+	if acc, err := meta.Accessor(obj); err == nil {
+		b.req.Name = acc.GetName()
+		b.req.Namespace = acc.GetNamespace()
+	}
+}
+
+func rawExtension(obj runtime.Object) runtime.RawExtension {
+	if obj == nil {
+		return runtime.RawExtension{}
+	}
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal %T: %v", obj, err))
+	}
+	return runtime.RawExtension{Raw: raw}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/system"
+	pkgtest "knative.dev/pkg/testing"
+)
+
+func TestAdmissionRequestBuilderCreate(t *testing.T) {
+	obj := CreateResource("a-resource")
+	review := NewAdmissionRequest().
+		WithObject(obj).
+		WithUser("jane", "devs").
+		Review()
+
+	if got, want := review.APIVersion, "admission.k8s.io/v1"; got != want {
+		t.Errorf("APIVersion = %q, wanted %q", got, want)
+	}
+	req := review.Request
+	if req.UID == "" {
+		t.Error("Expected a UID to be set")
+	}
+	if got, want := req.Operation, admissionv1.Create; got != want {
+		t.Errorf("Operation = %v, wanted %v", got, want)
+	}
+	wantKind := metav1.GroupVersionKind{Group: "pkg.knative.dev", Version: "v2", Kind: "Resource"}
+	if diff := cmp.Diff(wantKind, req.Kind); diff != "" {
+		t.Error("Unexpected kind (-want, +got):", diff)
+	}
+	wantResource := metav1.GroupVersionResource{Group: "pkg.knative.dev", Version: "v2", Resource: "resources"}
+	if diff := cmp.Diff(wantResource, req.Resource); diff != "" {
+		t.Error("Unexpected resource (-want, +got):", diff)
+	}
+	if req.Name != "a-resource" || req.Namespace != system.Namespace() {
+		t.Errorf("Name, Namespace = %q, %q, wanted %q, %q", req.Name, req.Namespace, "a-resource", system.Namespace())
+	}
+	wantUser := authenticationv1.UserInfo{Username: "jane", Groups: []string{"devs"}}
+	if diff := cmp.Diff(wantUser, req.UserInfo); diff != "" {
+		t.Error("Unexpected user (-want, +got):", diff)
+	}
+	if req.OldObject.Raw != nil {
+		t.Errorf("OldObject = %s, wanted none", req.OldObject.Raw)
+	}
+
+	got := &pkgtest.Resource{}
+	if err := json.Unmarshal(req.Object.Raw, got); err != nil {
+		t.Fatal("Failed to unmarshal object:", err)
+	}
+	if diff := cmp.Diff(obj, got); diff != "" {
+		t.Error("Unexpected object (-want, +got):", diff)
+	}
+}
+
+func TestAdmissionRequestBuilderUpdate(t *testing.T) {
+	older := CreateResource("a-resource")
+	newer := older.DeepCopy()
+	newer.Spec.FieldWithDefault = "changed"
+
+	builder := NewAdmissionRequest().
+		WithOperation(admissionv1.Update).
+		WithObject(newer).
+		WithOldObject(older).
+		WithSubResource("status").
+		WithDryRun()
+	req := builder.Request()
+
+	if got, want := req.Operation, admissionv1.Update; got != want {
+		t.Errorf("Operation = %v, wanted %v", got, want)
+	}
+	if got, want := req.SubResource, "status"; got != want {
+		t.Errorf("SubResource = %q, wanted %q", got, want)
+	}
+	if req.DryRun == nil || !*req.DryRun {
+		t.Error("Expected a dry run request")
+	}
+
+	gotNew, gotOld := &pkgtest.Resource{}, &pkgtest.Resource{}
+	if err := json.Unmarshal(req.Object.Raw, gotNew); err != nil {
+		t.Fatal("Failed to unmarshal object:", err)
+	}
+	if err := json.Unmarshal(req.OldObject.Raw, gotOld); err != nil {
+		t.Fatal("Failed to unmarshal old object:", err)
+	}
+	if diff := cmp.Diff(newer, gotNew); diff != "" {
+		t.Error("Unexpected object (-want, +got):", diff)
+	}
+	if diff := cmp.Diff(older, gotOld); diff != "" {
+		t.Error("Unexpected old object (-want, +got):", diff)
+	}
+
+	// Each build produces an independent request.
+	if builder.Request() == req {
+		t.Error("Expected Request() to return a copy")
+	}
+}
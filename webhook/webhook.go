@@ -19,6 +19,7 @@ package webhook
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"html"
@@ -33,6 +34,11 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/system"
 	certresources "knative.dev/pkg/webhook/certificates/resources"
@@ -40,9 +46,28 @@ import (
 
 // Options contains the configuration for the webhook
 type Options struct {
-	// ServiceName is the service name of the webhook.
+	// ServiceName is the service name of the webhook. When set, the
+	// defaulting reconciler points the MutatingWebhookConfiguration it
+	// manages at this service, in ServiceNamespace, so that several webhook
+	// deployments can each reference their own service.
 	ServiceName string
 
+	// ServiceNamespace is the namespace of the webhook's service, which
+	// the defaulting reconciler references and the certificates reconciler
+	// issues certificates for, even if ServiceName is unset. Defaults to
+	// system.Namespace() if unset. The configurations and the secret remain
+	// in, or owned by, system.Namespace() regardless.
+	ServiceNamespace string
+
+	// ServicePort is the port of the webhook's service that the defaulting
+	// reconciler has the MutatingWebhookConfiguration it manages reference,
+	// as do the exported configurations, for services that don't expose the
+	// webhook on 443. The API server
+	// only supports port numbers, so this is the number of the service port,
+	// e.g. of the one named https-webhook. The port set on the configuration
+	// is left as is if unset.
+	ServicePort int32
+
 	// SecretName is the name of k8s secret that contains the webhook
 	// server key/cert and corresponding CA cert that signed them. The
 	// server key/cert are used to serve the webhook and the CA cert
@@ -51,11 +76,64 @@ type Options struct {
 	// If no SecretName is provided, then the webhook serves without TLS.
 	SecretName string
 
+	// CertFile and KeyFile are the paths to the PEM encoded server cert and
+	// key used to serve the webhook, for deployments where these are mounted
+	// by an external agent. When both are set, they take precedence over
+	// SecretName, and they are reloaded whenever they change.
+	CertFile string
+	KeyFile  string
+
+	// CACertFile is the path to the PEM encoded CA cert that signed the
+	// server cert. When set, it is provided to the k8s apiserver during
+	// admission controller registration instead of the CA cert in SecretName,
+	// and the webhook configurations are reconciled whenever it changes.
+	CACertFile string
+
+	// ClientCAs, when set, has the webhook require its clients, i.e. the
+	// API server, to present a certificate signed by one of these CAs, for
+	// mutual TLS. It only applies when the webhook serves with TLS.
+	ClientCAs *x509.CertPool
+
+	// ClientAuth is the policy the webhook follows for the certificates of
+	// its clients. Defaults to tls.RequireAndVerifyClientCert when ClientCAs
+	// is set.
+	ClientAuth tls.ClientAuthType
+
+	// OCSPResponse, when set, returns the DER encoded OCSP response for the
+	// serving certificate, which the webhook staples to its TLS handshakes,
+	// e.g. OCSPResponseFromFile. It is called again once the response nears
+	// its NextUpdate, or when the certificate changes. It only applies when
+	// the webhook serves with TLS.
+	OCSPResponse func(cert *tls.Certificate) ([]byte, error)
+
 	// Port where the webhook is served. Per k8s admission
 	// registration requirements this should be 443 unless there is
 	// only a single port for the service.
 	Port int
 
+	// MaxInFlightAdmissions bounds the number of admission requests the
+	// webhook handles at any given time, across its admission controllers.
+	// Requests beyond the limit are failed fast with 429 Too Many Requests,
+	// so that the API server backs off rather than piling them up.
+	// Defaults to DefaultMaxInFlightAdmissions if unset, a negative value
+	// disables the limit.
+	MaxInFlightAdmissions int
+
+	// AdmissionTimeout, when positive, is the deadline of the processing of
+	// each admission request by its admission controller, past which the
+	// webhook gives up on it and denies the request with a timeout, rather
+	// than holding the connection until the API server times out. It must
+	// not exceed the timeout of the webhook, i.e. the TimeoutSeconds of the
+	// webhook policy when set, or of the webhook configurations.
+	AdmissionTimeout time.Duration
+
+	// AuditLogging, when true, has the webhook record every admission
+	// decision, i.e. the user, kind, operation, whether the request was
+	// allowed and why not, and a summary of the patch, with the logger named
+	// AuditLoggerName, for compliance. The objects and the patches themselves
+	// are left out of the records.
+	AuditLogging bool
+
 	// StatsReporter reports metrics about the webhook.
 	// This will be automatically initialized by the constructor if left uninitialized.
 	StatsReporter StatsReporter
@@ -63,8 +141,187 @@ type Options struct {
 	// GracePeriod is how long to wait after failing readiness probes
 	// before shutting down.
 	GracePeriod time.Duration
+
+	// ReconcilePeriod is how often the webhook configuration is reconciled
+	// in the absence of other events, so that drift introduced by other
+	// actors is eventually corrected. The period is jittered.
+	// Defaults to DefaultReconcilePeriod if unset, a negative value disables
+	// the periodic reconciliation.
+	ReconcilePeriod time.Duration
+
+	// InformerResyncPeriod, when positive, is the resync period of the
+	// event handlers the certificates controller registers on its Secret
+	// informer, independent of the informer factory's default. This allows
+	// a tighter resync of the certificate secret for timely rotation.
+	InformerResyncPeriod time.Duration
+
+	// Clock, when set, is the clock the certificates controller tells time
+	// with, i.e. whether the certificates are due for rotation and when the
+	// new ones are valid from, e.g. a fake clock in tests. Defaults to the
+	// real clock.
+	Clock clock.Clock
+
+	// CanaryCohorts, when non-empty, restricts the webhook, i.e. both its
+	// mutating and validating configurations, to namespaces labeled with
+	// CanaryLabelKey set to one of the listed cohorts. This allows the
+	// webhook to be rolled out gradually, by labeling more namespaces or
+	// listing more cohorts, before it is fully enabled.
+	CanaryCohorts []string
+
+	// ConfigurationLabels and ConfigurationAnnotations are labels and
+	// annotations that the defaulting reconciler ensures are set on the
+	// MutatingWebhookConfiguration it manages. Other labels and annotations
+	// are preserved.
+	ConfigurationLabels      map[string]string
+	ConfigurationAnnotations map[string]string
+
+	// ConfigurationOwner, when set, is the owner reference the defaulting
+	// reconciler sets on the MutatingWebhookConfiguration it manages, in
+	// lieu of system.Namespace(). This allows the configuration to be
+	// garbage collected along with another, cluster-scoped, object.
+	ConfigurationOwner *metav1.OwnerReference
+
+	// ConfigurationClient, when set, is the client of the API server the
+	// defaulting and validation reconcilers manage their webhook
+	// configurations on, e.g. of another cluster in hybrid setups, rather
+	// than the one of the cluster the webhook runs in, which still serves the
+	// secrets and the other informers. As system.Namespace() is not in that
+	// cluster, the configurations are then only owned by ConfigurationOwner,
+	// if set.
+	ConfigurationClient kubernetes.Interface
+
+	// ConfigurationNameSuffix, when set, is appended to the name of the
+	// MutatingWebhookConfiguration the defaulting reconciler manages, and
+	// to the name of the webhook within it. This allows several installs
+	// of the same webhook to coexist in a cluster.
+	ConfigurationNameSuffix string
+
+	// AdmissionReviewVersions are the AdmissionReview versions the defaulting
+	// reconciler declares the webhook supports, in order of preference, in
+	// the MutatingWebhookConfiguration it manages. The webhook serves both
+	// v1 and v1beta1 AdmissionReviews, responding in the version of each.
+	// Defaults to DefaultAdmissionReviewVersions if unset.
+	AdmissionReviewVersions []string
+
+	// CABundleOverlap, when positive, is how long the defaulting reconciler
+	// keeps trusting the outgoing CA bundle alongside the incoming one when
+	// the CA changes, so that the API server does not reject the webhook's
+	// serving certificate mid-rotation. After the window the CABundle is
+	// trimmed to just the new CA.
+	CABundleOverlap time.Duration
+
+	// FailurePolicyFallback, when true, has the defaulting reconciler relax
+	// the FailurePolicy of the MutatingWebhookConfiguration it manages from
+	// Fail to Ignore while the webhook's service has no ready endpoints, e.g.
+	// mid-rollout, so that cluster operations don't stall. The Fail policy
+	// is restored once the service has ready endpoints again.
+	FailurePolicyFallback bool
+
+	// PruneRulesOnUninstall, when true, has the defaulting admission
+	// controller remove the rules of the webhook of the
+	// MutatingWebhookConfiguration it manages once the system namespace is
+	// being deleted, as the leader reconciles or shuts down, so that the
+	// configuration doesn't block the requests for its types until the
+	// garbage collector deletes it along with the namespace, its owner.
+	// This is best effort: no finalizer holds the deletion of the
+	// namespace, so an uninstall never hangs on the webhook. Restarts and
+	// rollouts of the webhook leave the rules be. The webhook must be
+	// allowed to get, list and watch the system namespace.
+	PruneRulesOnUninstall bool
+
+	// ServerSideApply, when true, has the defaulting reconciler apply the
+	// fields it manages of the MutatingWebhookConfiguration it manages with
+	// server-side apply, as FieldManager, rather than update the whole
+	// configuration, so that it doesn't clobber the fields managed by other
+	// actors, e.g. the other webhooks of the configuration.
+	ServerSideApply bool
+
+	// PolicyProfile, when set, selects the failure policy, timeout and side
+	// effects that the defaulting reconciler sets together on the webhook
+	// of the MutatingWebhookConfiguration it manages, e.g.
+	// PolicyProfileLenient. The non-nil fields of PolicyOverrides take
+	// precedence over the profile, and may also be set on their own.
+	PolicyProfile   PolicyProfile
+	PolicyOverrides WebhookPolicy
+
+	// ValidationBypassUsernames and ValidationBypassGroups list the users,
+	// and the groups of users, whose requests the validating admission
+	// controllers allow without running any validation. This is meant for
+	// system components, e.g. the garbage collector, whose operations must
+	// not be blocked by the webhook.
+	ValidationBypassUsernames []string
+	ValidationBypassGroups    []string
+
+	// ValidationObjectSelector, when set, has the validation reconcilers
+	// ensure the MatchExpressions of the ObjectSelector of the webhook of
+	// the ValidatingWebhookConfiguration they manage, so that it only
+	// intercepts the requests for the objects it selects. The expressions
+	// that other actors set on the object selector for other keys, which are
+	// not knative keys, are preserved.
+	ValidationObjectSelector *metav1.LabelSelector
+
+	// ValidationInternalErrorPolicy is how the validating admission
+	// controllers respond to the requests whose validation fails because of
+	// an internal error, i.e. a panic of the validation of the resource or
+	// of a callback, or a callback error made with
+	// validation.NewInternalError, rather than because they are invalid:
+	// Fail denies them, Ignore allows them with a warning, so that the bugs
+	// of the webhook don't block legitimate operations.
+	// Defaults to Fail if unset.
+	ValidationInternalErrorPolicy admissionregistrationv1.FailurePolicyType
+
+	// DenyUnregisteredKinds, when true, has the defaulting and validation
+	// admission controllers deny the requests for kinds they have no
+	// handler for, e.g. because of misconfigured webhook rules. By default
+	// those requests are allowed, so as not to block them.
+	DenyUnregisteredKinds bool
+
+	// Status, when set, is updated by the certificates and defaulting
+	// reconcilers to reflect the health of the webhook.
+	Status *Status
 }
 
+// CanaryLabelKey is the namespace label used to select the canary cohort
+// of namespaces a webhook applies to (see Options.CanaryCohorts).
+const CanaryLabelKey = "webhooks.knative.dev/canary"
+
+// CABundleOverlapUntilAnnotationKey is the annotation recording, on a webhook
+// configuration, until when its CABundle trusts both the outgoing and the
+// incoming CA (see Options.CABundleOverlap).
+const CABundleOverlapUntilAnnotationKey = "webhooks.knative.dev/ca-bundle-overlap-until"
+
+// FailurePolicyFallbackAnnotationKey is the annotation marking a webhook
+// configuration whose FailurePolicy was relaxed to Ignore while the webhook
+// was unavailable (see Options.FailurePolicyFallback).
+const FailurePolicyFallbackAnnotationKey = "webhooks.knative.dev/failure-policy-fallback"
+
+// ForceRotateAnnotationKey is the annotation on the certificate secret that
+// forces the rotation of its certificates, e.g. after a suspected key
+// compromise, whenever its value changes. Any value, e.g. a timestamp, may
+// be used.
+const ForceRotateAnnotationKey = "webhooks.knative.dev/force-rotate"
+
+// ForceRotatedAnnotationKey is the annotation recording, on the certificate
+// secret, the value of ForceRotateAnnotationKey its certificates were last
+// rotated for.
+const ForceRotatedAnnotationKey = "webhooks.knative.dev/force-rotated"
+
+// FieldManager is the field manager the defaulting reconciler applies the
+// fields it manages of the MutatingWebhookConfiguration as (see
+// Options.ServerSideApply).
+const FieldManager = "knative-webhook"
+
+// DefaultMaxInFlightAdmissions is the default value of
+// Options.MaxInFlightAdmissions.
+const DefaultMaxInFlightAdmissions = 1000
+
+// DefaultReconcilePeriod is the default value of Options.ReconcilePeriod.
+const DefaultReconcilePeriod = 5 * time.Minute
+
+// DefaultAdmissionReviewVersions is the default value of
+// Options.AdmissionReviewVersions.
+var DefaultAdmissionReviewVersions = []string{"v1"}
+
 // Operation is the verb being operated on
 // it is aliased in Validation from the k8s admission package
 type Operation = admissionv1.Operation
@@ -90,6 +347,10 @@ type Webhook struct {
 
 	// The TLS configuration to use for serving (or nil for non-TLS)
 	tlsConfig *tls.Config
+
+	// admissionPaths are the paths of the admission controllers, which
+	// also accept protobuf-encoded requests.
+	admissionPaths sets.String
 }
 
 // New constructs a Webhook
@@ -109,6 +370,16 @@ func New(
 	if opts == nil {
 		return nil, errors.New("context must have Options specified")
 	}
+	// Fail fast with a clear message, rather than panic on the first use of
+	// the system namespace, e.g. to look up the certificate secret.
+	if _, err := system.NamespaceE(); err != nil {
+		return nil, fmt.Errorf("error creating webhook: %w", err)
+	}
+	if policy, err := opts.WebhookPolicy(); err == nil && policy.TimeoutSeconds != nil &&
+		opts.AdmissionTimeout > time.Duration(*policy.TimeoutSeconds)*time.Second {
+		return nil, fmt.Errorf("admission timeout %v exceeds the webhook's timeout of %ds",
+			opts.AdmissionTimeout, *policy.TimeoutSeconds)
+	}
 	logger := logging.FromContext(ctx)
 
 	if opts.StatsReporter == nil {
@@ -125,9 +396,20 @@ func New(
 		Options: *opts,
 		Logger:  logger,
 		synced:  cancel,
+
+		admissionPaths: sets.NewString(),
 	}
 
-	if opts.SecretName != "" {
+	if opts.CertFile != "" && opts.KeyFile != "" {
+		reloader, err := newCertFileReloader(ctx, opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error watching the server cert and key: %w", err)
+		}
+		webhook.tlsConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	} else if opts.SecretName != "" {
 		// Injection is too aggressive for this case because by simply linking this
 		// library we force consumers to have secret access.  If we require that one
 		// of the admission controllers' informers *also* require the secret
@@ -168,15 +450,40 @@ func New(
 		}
 	}
 
+	if webhook.tlsConfig != nil && opts.OCSPResponse != nil {
+		stapler := &ocspStapler{fetch: opts.OCSPResponse, logger: logger}
+		webhook.tlsConfig.GetCertificate = stapler.wrap(webhook.tlsConfig.GetCertificate)
+	}
+
+	if webhook.tlsConfig != nil && opts.ClientCAs != nil {
+		webhook.tlsConfig.ClientCAs = opts.ClientCAs
+		webhook.tlsConfig.ClientAuth = opts.ClientAuth
+		if webhook.tlsConfig.ClientAuth == tls.NoClientCert {
+			webhook.tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
 	webhook.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprint("no controller registered for: ", html.EscapeString(r.URL.Path)), http.StatusBadRequest)
 	})
 
+	maxInFlight := opts.MaxInFlightAdmissions
+	if maxInFlight == 0 {
+		maxInFlight = DefaultMaxInFlightAdmissions
+	}
+	limit := newInFlightLimiter(maxInFlight)
+	timeout := newAdmissionTimeout(opts.AdmissionTimeout)
+	var audit *zap.SugaredLogger
+	if opts.AuditLogging {
+		audit = logger.Named(AuditLoggerName)
+	}
+
 	for _, controller := range controllers {
 		switch c := controller.(type) {
 		case AdmissionController:
-			handler := admissionHandler(logger, opts.StatsReporter, c, syncCtx.Done())
-			webhook.mux.Handle(c.Path(), handler)
+			handler := admissionHandler(logger, opts.StatsReporter, audit, c, syncCtx.Done())
+			webhook.mux.Handle(c.Path(), limit(timeout(handler)))
+			webhook.admissionPaths.Insert(c.Path())
 
 		case ConversionController:
 			handler := conversionHandler(logger, opts.StatsReporter, c)
@@ -253,8 +560,9 @@ func (wh *Webhook) Run(stop <-chan struct{}) error {
 
 func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Verify the content type is accurate.
+	// The admission controllers also accept protobuf-encoded requests.
 	contentType := r.Header.Get("Content-Type")
-	if contentType != "application/json" {
+	if contentType != "application/json" && !(isProtobuf(contentType) && wh.admissionPaths.Has(r.URL.Path)) {
 		http.Error(w, "invalid Content-Type, want `application/json`", http.StatusUnsupportedMediaType)
 		return
 	}
@@ -18,12 +18,25 @@ package webhook
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
 	"fmt"
+	"math/big"
 	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"golang.org/x/sync/errgroup"
+	"knative.dev/pkg/system"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
 
 	// Make system.Namespace() work in tests.
 	_ "knative.dev/pkg/system/testing"
@@ -93,3 +106,125 @@ func TestRegistrationStopChanFire(t *testing.T) {
 		t.Error("Unexpected success to dial to port", opts.Port)
 	}
 }
+
+func TestNewWithoutSystemNamespace(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	ctx = WithOptions(ctx, newDefaultOptions())
+	t.Setenv(system.NamespaceEnvKey, "")
+
+	if _, err := New(ctx, nil); !errors.Is(err, system.ErrNamespaceNotSet) {
+		t.Errorf("New() = %v, want: %v", err, system.ErrNamespaceNotSet)
+	}
+}
+
+func TestNewAdmissionTimeoutExceedingWebhookTimeout(t *testing.T) {
+	ctx, _ := SetupFakeContext(t)
+	opts := newDefaultOptions()
+	opts.PolicyProfile = PolicyProfileStrict
+	opts.AdmissionTimeout = 15 * time.Second
+
+	if _, err := New(WithOptions(ctx, opts), nil); err == nil {
+		t.Error("New() = nil, wanted an error")
+	}
+
+	// Up to the webhook's timeout is fine.
+	opts.AdmissionTimeout = 10 * time.Second
+	if _, err := New(WithOptions(ctx, opts), nil); err != nil {
+		t.Error("New() =", err)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverKey, serverCert, caCert, err := certresources.CreateCerts(context.Background(), "webhook", "ns", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal("CreateCerts() =", err)
+	}
+	opts := newDefaultOptions()
+	opts.CertFile, opts.KeyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	for name, data := range map[string][]byte{opts.CertFile: serverCert, opts.KeyFile: serverKey} {
+		if err := os.WriteFile(name, data, 0600); err != nil {
+			t.Fatal("WriteFile() =", err)
+		}
+	}
+	trusted, untrusted := newClientCert(t), newClientCert(t)
+	opts.ClientCAs = x509.NewCertPool()
+	opts.ClientCAs.AddCert(trusted.Leaf)
+
+	_, wh, cancel := newNonRunningTestWebhook(t, opts)
+	defer cancel()
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", wh.tlsConfig)
+	if err != nil {
+		t.Fatal("Listen() =", err)
+	}
+	server := &http.Server{Handler: wh}
+	go server.Serve(l)
+	defer server.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AppendCertsFromPEM(caCert)
+	tests := []struct {
+		name    string
+		certs   []tls.Certificate
+		wantErr bool
+	}{{
+		name:  "trusted client certificate",
+		certs: []tls.Certificate{trusted},
+	}, {
+		name:    "untrusted client certificate",
+		certs:   []tls.Certificate{untrusted},
+		wantErr: true,
+	}, {
+		name:    "no client certificate",
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:      rootCAs,
+					ServerName:   "webhook.ns.svc",
+					Certificates: test.certs,
+					MinVersion:   tls.VersionTLS12,
+				},
+			}}
+			resp, err := client.Get("https://" + l.Addr().String())
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != test.wantErr {
+				t.Errorf("Get() = %v, wantErr: %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+// newClientCert returns a self-signed certificate for TLS clients.
+func newClientCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey() =", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kube-apiserver"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal("CreateCertificate() =", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal("ParseCertificate() =", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}